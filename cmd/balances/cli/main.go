@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/juaguz/storid/internal/accounts/balances"
+	"github.com/juaguz/storid/internal/platform/config"
+	"github.com/juaguz/storid/internal/platform/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type BalancesHandler struct {
+	incremental *balances.IncrementalBalances
+	refresher   *balances.BalanceRefresher
+}
+
+func NewBalancesHandler(incremental *balances.IncrementalBalances, refresher *balances.BalanceRefresher) *BalancesHandler {
+	return &BalancesHandler{
+		incremental: incremental,
+		refresher:   refresher,
+	}
+}
+
+func (h *BalancesHandler) Run(ctx context.Context, action string) {
+	switch action {
+	case "rebuild":
+		if err := h.incremental.Rebuild(ctx); err != nil {
+			log.Fatalf("Error rebuilding balance totals: %v", err)
+		}
+		h.check(ctx)
+	case "check":
+		h.check(ctx)
+	case "refresh":
+		if err := h.refresher.Refresh(ctx); err != nil {
+			log.Fatalf("Error refreshing views: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown action: %s", action)
+	}
+	fmt.Println("Done")
+}
+
+func (h *BalancesHandler) check(ctx context.Context) {
+	mismatches, err := h.incremental.Check(ctx)
+	if err != nil {
+		log.Fatalf("Error checking balance totals: %v", err)
+	}
+
+	for _, m := range mismatches {
+		period := "month " + m.Month
+		if m.Day != "" {
+			period = "day " + m.Day
+		}
+		fmt.Printf("account %d %s: credits %d/%d debits %d/%d count %d/%d (expected/actual)\n",
+			m.AccountID, period,
+			m.ExpectedCreditSum, m.ActualCreditSum,
+			m.ExpectedDebitSum, m.ActualDebitSum,
+			m.ExpectedCount, m.ActualCount)
+	}
+
+	if len(mismatches) > 0 {
+		log.Fatalf("Found %d inconsistent periods, run with --action=rebuild to repair them", len(mismatches))
+	}
+	fmt.Println("Balance totals are consistent")
+}
+
+func main() {
+	action := flag.String("action", "check", "Choose action: rebuild, check or refresh")
+	flag.Parse()
+
+	app := fx.New(
+		fx.Provide(
+			zap.NewProduction,
+			config.LoadConfig,
+			func(cfg *config.Config) *db.Config {
+				return &db.Config{
+					Host:     cfg.DBConfig.Host,
+					Port:     cfg.DBConfig.Port,
+					User:     cfg.DBConfig.User,
+					Password: cfg.DBConfig.Password,
+					Database: cfg.DBConfig.Database,
+				}
+			},
+			db.NewDB,
+			balances.NewIncrementalBalances,
+			balances.NewBalanceRefresher,
+			NewBalancesHandler,
+		),
+		fx.Invoke(func(handler *BalancesHandler) {
+			handler.Run(context.Background(), *action)
+		}),
+	)
+
+	if err := app.Start(context.Background()); err != nil {
+		log.Fatalf("Error starting application: %v", err)
+	}
+
+	defer app.Stop(context.Background())
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/juaguz/storid/cmd/importer/internal"
 	"github.com/juaguz/storid/internal/accounts/transactions/importer"
 	"github.com/juaguz/storid/internal/platform/config"
-	"github.com/juaguz/storid/internal/platform/dispatcher"
 	"github.com/juaguz/storid/internal/platform/filereaders"
 	"go.uber.org/fx"
 )
@@ -81,11 +80,6 @@ func main() {
 			func() bool {
 				return false
 			},
-			fx.Annotate(
-				dispatcher.NewSimpleEventDispatcher,
-				fx.As(new(importer.EventDispatcher)),
-				fx.As(new(dispatcher.EventDispatcher)),
-			),
 		),
 		fx.Provide(func(importer *importer.FileImporter) *ImportHandler {
 			return NewImportHandler(importer, *filePath)
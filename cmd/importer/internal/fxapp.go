@@ -3,7 +3,11 @@ package internal
 import (
 	"context"
 
+	"github.com/juaguz/storid/cmd/internal/providers"
+	"github.com/juaguz/storid/internal/accounts/analysis"
+	analysisrepositories "github.com/juaguz/storid/internal/accounts/analysis/repositories"
 	"github.com/juaguz/storid/internal/accounts/balances"
+	"github.com/juaguz/storid/internal/accounts/balances/summary"
 	"github.com/juaguz/storid/internal/accounts/transactions/importer"
 	"github.com/juaguz/storid/internal/accounts/transactions/repositories"
 	"github.com/juaguz/storid/internal/platform/config"
@@ -11,6 +15,7 @@ import (
 	"github.com/juaguz/storid/internal/platform/dispatcher"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func NewApp() fx.Option {
@@ -27,21 +32,105 @@ func NewApp() fx.Option {
 					Database: cfg.DBConfig.Database,
 				}
 			},
+			func(cfg *config.Config) map[string]analysis.Thresholds {
+				thresholds := make(map[string]analysis.Thresholds)
+				for tier, t := range cfg.AlertConfig.Thresholds {
+					thresholds[tier] = analysis.Thresholds{
+						ZScore:     t.ZScore,
+						LargeDebit: t.LargeDebit,
+						DailyBurst: t.DailyBurst,
+						MinHistory: t.MinHistory,
+					}
+				}
+				return thresholds
+			},
 			db.NewDB,
 			fx.Annotate(
-				balances.NewBalanceRefresher,
-				fx.As(new(dispatcher.EventHandler))),
+				balanceHandlers,
+				fx.ResultTags(`group:"handlers,flatten"`),
+			),
+			fx.Annotate(
+				analysis.NewSubscriptionDetector,
+				fx.As(new(dispatcher.EventHandler)),
+				fx.ResultTags(`group:"handlers"`),
+			),
+			fx.Annotate(
+				analysis.NewAnomalyAlerter,
+				fx.ParamTags(``, ``, `group:"notifiers"`),
+				fx.As(new(dispatcher.EventHandler)),
+				fx.ResultTags(`group:"handlers"`),
+			),
+			fx.Annotate(
+				providers.NewAccountRepository,
+				fx.As(new(analysis.AccountRepository)),
+				fx.As(new(summary.AccountRepository)),
+			),
+			fx.Annotate(
+				providers.NewSMTPService,
+				fx.As(new(summary.EmailService)),
+			),
+			fx.Annotate(
+				providers.NewUnsubscribeTokens,
+				fx.As(new(summary.UnsubscribeLinks)),
+			),
+			fx.Annotate(
+				summary.NewEmailSender,
+				fx.ParamTags(``, ``, `group:"attachments"`, ``),
+				fx.As(new(summary.Notifier)),
+				fx.ResultTags(`group:"notifiers"`),
+			),
 			fx.Annotate(
-				repositories.NewTransactionRepository,
+				newTransactionRepository,
 				fx.As(new(importer.TransactionRepository)),
+				fx.As(new(analysis.TransactionRepository)),
+			),
+			fx.Annotate(
+				analysisrepositories.NewSubscriptionRepository,
+				fx.As(new(analysis.SubscriptionRepository)),
+			),
+			fx.Annotate(
+				newDispatcher,
+				fx.As(new(importer.EventDispatcher)),
+				fx.As(new(dispatcher.EventDispatcher)),
 			),
-			repositories.NewTransactionRepository,
 			importer.NewFileImporter,
 		),
-		fx.Invoke(registerHandlers),
+		fx.Invoke(
+			fx.Annotate(
+				registerHandlers,
+				fx.ParamTags(``, `group:"handlers"`),
+			),
+		),
 	)
 }
 
-func registerHandlers(d dispatcher.EventDispatcher, handler dispatcher.EventHandler) {
-	d.Register(context.Background(), importer.EventImported, handler)
+// balanceHandlers in incremental mode the balances are updated by the transaction repository,
+// nothing needs to be refreshed after the import.
+func balanceHandlers(cfg *config.Config, db *gorm.DB, log *zap.Logger) []dispatcher.EventHandler {
+	if cfg.BalanceConfig.Mode == balances.ModeIncremental {
+		return nil
+	}
+	return []dispatcher.EventHandler{balances.NewBalanceRefresher(db, log)}
+}
+
+func newTransactionRepository(cfg *config.Config, db *gorm.DB, log *zap.Logger) *repositories.TransactionDBRepository {
+	if cfg.BalanceConfig.Mode == balances.ModeIncremental {
+		return repositories.NewIncrementalTransactionRepository(db, balances.NewIncrementalBalances(db, log))
+	}
+	return repositories.NewTransactionRepository(db)
+}
+
+// newDispatcher the errors of the async handlers are logged, the import returned before they ran.
+func newDispatcher(async bool, log *zap.Logger) *dispatcher.Dispatcher {
+	d := dispatcher.NewSimpleEventDispatcher(async)
+	d.OnError = func(event string, err error) {
+		log.Error("error handling event", zap.String("event", event), zap.Error(err))
+	}
+	return d
+}
+
+func registerHandlers(d dispatcher.EventDispatcher, handlers []dispatcher.EventHandler) {
+	for _, handler := range handlers {
+		d.Register(context.Background(), importer.EventImported, handler)
+	}
 }
@@ -13,7 +13,6 @@ import (
 	"github.com/juaguz/storid/cmd/importer/internal"
 	"github.com/juaguz/storid/internal/accounts/transactions/importer"
 	"github.com/juaguz/storid/internal/platform/config"
-	"github.com/juaguz/storid/internal/platform/dispatcher"
 	"github.com/juaguz/storid/internal/platform/filereaders"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -82,11 +81,6 @@ func main() {
 			func() bool {
 				return false
 			},
-			fx.Annotate(
-				dispatcher.NewSimpleEventDispatcher,
-				fx.As(new(importer.EventDispatcher)),
-				fx.As(new(dispatcher.EventDispatcher)),
-			),
 		),
 		fx.Invoke(StartLambdaHandler),
 	)
@@ -0,0 +1,61 @@
+// Package providers the fx constructors shared by the apps of the commands.
+package providers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/juaguz/storid/internal/accounts/balances"
+	"github.com/juaguz/storid/internal/accounts/balances/repositories"
+	accountnotifications "github.com/juaguz/storid/internal/accounts/notifications"
+	accountrepositories "github.com/juaguz/storid/internal/accounts/repositories"
+	"github.com/juaguz/storid/internal/platform/config"
+	"github.com/juaguz/storid/internal/platform/notifications"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewSMTPService the connections kept open between the emails are closed when the app stops.
+func NewSMTPService(lc fx.Lifecycle, cfg *config.Config, log *zap.Logger) (*notifications.SMTPService, error) {
+	service, err := notifications.NewSMTPService(cfg.SMTPConfig, log,
+		notifications.WithTemplateStore(notifications.NewTemplateStore(cfg.TemplateConfig, cfg.S3Config.Client, log)),
+		notifications.WithTemplateVersions(cfg.TemplateConfig.Versions),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return service.Close()
+		},
+	})
+
+	return service, nil
+}
+
+func NewAccountRepository(cfg *config.Config, db *gorm.DB) *accountrepositories.AccountRepository {
+	repository := accountrepositories.NewAccountRepository(db)
+	repository.DefaultLocale = cfg.LocaleConfig.DefaultLocale
+	return repository
+}
+
+// NewUnsubscribeTokens the apps don't start without the secret, anyone could sign the tokens with an empty one.
+func NewUnsubscribeTokens(cfg *config.Config) (*accountnotifications.UnsubscribeTokens, error) {
+	if cfg.UnsubscribeConfig.Secret == "" {
+		return nil, errors.New("the unsubscribe secret is not set, see UNSUBSCRIBE_SECRET")
+	}
+
+	return accountnotifications.NewUnsubscribeTokens([]byte(cfg.UnsubscribeConfig.Secret), cfg.UnsubscribeConfig.TokenTTL, cfg.UnsubscribeConfig.BaseURL), nil
+}
+
+// NewBalancesRepository reads the balances maintained by the importer in the incremental mode, the materialized views otherwise.
+func NewBalancesRepository(cfg *config.Config, db *gorm.DB) *repositories.BalancesDBRepository {
+	repository := repositories.NewBalancesRepository(db)
+	if cfg.BalanceConfig.Mode == balances.ModeIncremental {
+		repository = repositories.NewIncrementalBalancesRepository(db)
+	}
+	repository.DailyBalanceDays = cfg.BalanceConfig.DailyBalanceDays
+	return repository
+}
@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"context"
+	"errors"
+
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	statementrepositories "github.com/juaguz/storid/internal/accounts/statements/repositories"
+	"gorm.io/gorm"
+)
+
+// errRollback discards the statements created by the previews.
+var errRollback = errors.New("rollback")
+
+// RollbackStatementRepository previewing a statement must not store it, it is created in a transaction
+// that is rolled back so it is rendered as it would be sent.
+type RollbackStatementRepository struct {
+	*statementrepositories.StatementDBRepository
+}
+
+func NewRollbackStatementRepository(db *gorm.DB) *RollbackStatementRepository {
+	return &RollbackStatementRepository{StatementDBRepository: statementrepositories.NewStatementRepository(db)}
+}
+
+func (rr *RollbackStatementRepository) Create(ctx context.Context, statement *statementdtos.Statement) error {
+	err := rr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := statementrepositories.NewStatementRepository(tx).Create(ctx, statement); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if errors.Is(err, errRollback) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/juaguz/storid/cmd/preview/internal"
+	"github.com/juaguz/storid/internal/accounts/balances/summary"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// StartServer serves the email previews locally, TEMPLATE_SOURCE=dir renders the templates being edited.
+// The previews render the data of real accounts without authentication, the server must not be exposed.
+func StartServer(lc fx.Lifecycle, handler *summary.PreviewHandler, logger *zap.Logger, addr string) {
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				logger.Info("Starting preview server", zap.String("addr", addr))
+				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Fatal("Preview server failed", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	})
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "Address the preview server listens on, only reachable locally by default")
+	flag.Parse()
+
+	app := fx.New(
+		internal.NewApp(),
+		fx.Invoke(func(lc fx.Lifecycle, handler *summary.PreviewHandler, logger *zap.Logger) {
+			StartServer(lc, handler, logger, *addr)
+		}),
+	)
+
+	if err := app.Err(); err != nil {
+		log.Fatalf("Error starting application: %v", err)
+	}
+
+	app.Run()
+}
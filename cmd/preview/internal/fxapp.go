@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"github.com/juaguz/storid/cmd/internal/providers"
+	analysisrepositories "github.com/juaguz/storid/internal/accounts/analysis/repositories"
+	"github.com/juaguz/storid/internal/accounts/balances/summary"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	transactionrepositories "github.com/juaguz/storid/internal/accounts/transactions/repositories"
+	"github.com/juaguz/storid/internal/platform/config"
+	"github.com/juaguz/storid/internal/platform/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func NewApp() fx.Option {
+	return fx.Options(
+		fx.Provide(
+			zap.NewProduction,
+			config.LoadConfig,
+			func(cfg *config.Config) *db.Config {
+				return &db.Config{
+					Host:     cfg.DBConfig.Host,
+					Port:     cfg.DBConfig.Port,
+					User:     cfg.DBConfig.User,
+					Password: cfg.DBConfig.Password,
+					Database: cfg.DBConfig.Database,
+				}
+			},
+			db.NewDB,
+			fx.Annotate(
+				providers.NewAccountRepository,
+				fx.As(new(summary.AccountRepository)),
+			),
+			fx.Annotate(
+				providers.NewSMTPService,
+				fx.As(new(summary.EmailService)),
+				fx.As(new(summary.Renderer)),
+			),
+			fx.Annotate(
+				previewAttachments,
+				fx.ResultTags(`group:"attachments,flatten"`),
+			),
+			fx.Annotate(
+				providers.NewUnsubscribeTokens,
+				fx.As(new(summary.UnsubscribeLinks)),
+			),
+			fx.Annotate(
+				summary.NewEmailSender,
+				fx.ParamTags(``, ``, `group:"attachments"`, ``),
+			),
+			fx.Annotate(
+				providers.NewBalancesRepository,
+				fx.As(new(summary.SummaryGenerator))),
+			fx.Annotate(
+				analysisrepositories.NewSubscriptionRepository,
+				fx.As(new(summary.SubscriptionRepository)),
+			),
+			fx.Annotate(
+				transactionrepositories.NewTransactionRepository,
+				fx.As(new(statements.TransactionRepository)),
+			),
+			fx.Annotate(
+				providers.NewRollbackStatementRepository,
+				fx.As(new(statements.StatementRepository)),
+			),
+			fx.Annotate(
+				statements.NewGenerator,
+				fx.As(new(summary.StatementGenerator)),
+			),
+			summary.NewPreviewHandler,
+		),
+	)
+}
+
+// previewAttachments only the charts change how the email looks, the files attached or linked
+// are left out so the previews do not upload exports.
+func previewAttachments(cfg *config.Config) []summary.AttachmentBuilder {
+	if !cfg.SummaryConfig.Charts {
+		return nil
+	}
+	return []summary.AttachmentBuilder{summary.NewMonthlyBalanceChart(), summary.NewCreditDebitChart()}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/juaguz/storid/cmd/sender/internal"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
 	"github.com/juaguz/storid/internal/accounts/balances/summary"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
 	"go.uber.org/fx"
 )
 
@@ -18,19 +22,89 @@ func NewNotifierHandler(summary *summary.Sender) *NotifierHandler {
 	return &NotifierHandler{summary: summary}
 }
 
-func (h *NotifierHandler) Run(ctx context.Context) {
-	if err := h.summary.Send(ctx); err != nil {
+// statementRequest picks the statements sent instead of the summary, a billing cycle or a period.
+type statementRequest struct {
+	cycle    int
+	cycleDay int
+	from, to time.Time
+}
+
+func (h *NotifierHandler) Run(ctx context.Context, statements statementRequest, options ...summary.SendOption) {
+	if !statements.from.IsZero() {
+		report, err := h.summary.SendPeriodStatements(ctx, statements.from, statements.to, options...)
+		if err != nil {
+			log.Fatalf("Error sending statements: %v", err)
+		}
+		h.done(report)
+		return
+	}
+
+	if statements.cycle > 0 {
+		report, err := h.summary.SendStatements(ctx, statements.cycleDay, statements.cycle, options...)
+		if err != nil {
+			log.Fatalf("Error sending statements: %v", err)
+		}
+		h.done(report)
+		return
+	}
+
+	report, err := h.summary.Send(ctx, options...)
+	if err != nil {
 		log.Fatalf("Error running sender: %v", err)
 	}
+	h.done(report)
+}
+
+func (h *NotifierHandler) done(report *summary.Report) {
+	fmt.Printf("sent %d failed %d skipped %d\n", report.Sent, report.Failed, report.Skipped)
+	if report.HasFailures() {
+		log.Fatalf("%d notifications failed, run with --resend-failed to retry them", report.Failed)
+	}
 	fmt.Println("Done")
 }
 
 func main() {
+	cycle := flag.Int("statement-cycle", 0, "Send the statement of the given billing cycle instead of the summary")
+	cycleDay := flag.Int("cycle-day", 1, "Day of the month the billing cycles start")
+	statementFrom := flag.String("statement-from", "", "Send the statement of the period starting on the date (YYYY-MM-DD) instead of the summary")
+	statementTo := flag.String("statement-to", "", "Last day of the --statement-from period (YYYY-MM-DD)")
+	resendFailed := flag.Bool("resend-failed", false, "Only send the notifications whose delivery failed")
+	idempotencyKey := flag.String("idempotency-key", "", "Identifies the summaries sent, defaults to the current day")
+	accounts := flag.String("account", "", "Only send the summaries of the accounts e.g. 1,5,10-20")
+	since := flag.String("since", "", "Only send the summaries of the accounts with transactions since the date (YYYY-MM-DD)")
+	createdAfter := flag.String("created-after", "", "Only send the summaries of the accounts created after the date (YYYY-MM-DD)")
+	output := flag.String("output", "", "Write the emails to the directory instead of sending them")
+	flag.Parse()
+
+	selector, err := accountSelector(*accounts, *since, *createdAfter)
+	if err != nil {
+		log.Fatalf("Invalid accounts: %v", err)
+	}
+
+	statements := statementRequest{cycle: *cycle, cycleDay: *cycleDay}
+	if *statementFrom != "" || *statementTo != "" {
+		if *cycle > 0 {
+			log.Fatalf("--statement-cycle and --statement-from can't be used together")
+		}
+		if statements.from, statements.to, err = statementdtos.ParsePeriod(*statementFrom, *statementTo); err != nil {
+			log.Fatalf("Invalid statement period: %v", err)
+		}
+	}
+
+	options := []summary.SendOption{summary.WithAccounts(selector)}
+	if *resendFailed {
+		options = append(options, summary.WithResendFailed())
+	}
+	if *idempotencyKey != "" {
+		options = append(options, summary.WithIdempotencyKey(*idempotencyKey))
+	}
+
 	app := fx.New(
 		internal.NewApp(),
 		fx.Provide(NewNotifierHandler),
+		outputTo(*output),
 		fx.Invoke(func(handler *NotifierHandler) {
-			handler.Run(context.Background())
+			handler.Run(context.Background(), statements, options...)
 		}),
 	)
 
@@ -40,3 +114,33 @@ func main() {
 
 	defer app.Stop(context.Background())
 }
+
+func outputTo(dir string) fx.Option {
+	if dir == "" {
+		return fx.Options()
+	}
+	return internal.OutputTo(dir)
+}
+
+func accountSelector(accounts, since, createdAfter string) (dtos.AccountSelector, error) {
+	var selector dtos.AccountSelector
+	var err error
+
+	if selector.AccountIDs, selector.Ranges, err = dtos.ParseAccounts(accounts); err != nil {
+		return selector, err
+	}
+
+	if since != "" {
+		if selector.ActiveSince, err = time.Parse(time.DateOnly, since); err != nil {
+			return selector, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+
+	if createdAfter != "" {
+		if selector.CreatedAfter, err = time.Parse(time.DateOnly, createdAfter); err != nil {
+			return selector, fmt.Errorf("invalid --created-after: %w", err)
+		}
+	}
+
+	return selector, nil
+}
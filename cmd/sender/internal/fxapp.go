@@ -1,15 +1,25 @@
 package internal
 
 import (
-	accountrepositories "github.com/juaguz/storid/internal/accounts/repositories"
+	"fmt"
+
+	"github.com/juaguz/storid/cmd/internal/providers"
+	analysisrepositories "github.com/juaguz/storid/internal/accounts/analysis/repositories"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	notificationrepositories "github.com/juaguz/storid/internal/accounts/notifications/repositories"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	statementrepositories "github.com/juaguz/storid/internal/accounts/statements/repositories"
+	transactionrepositories "github.com/juaguz/storid/internal/accounts/transactions/repositories"
 	"github.com/juaguz/storid/internal/platform/config"
 
-	"github.com/juaguz/storid/internal/accounts/balances/repositories"
 	"github.com/juaguz/storid/internal/accounts/balances/summary"
 	"github.com/juaguz/storid/internal/platform/db"
+	"github.com/juaguz/storid/internal/platform/filestores"
 	"github.com/juaguz/storid/internal/platform/notifications"
+	"github.com/juaguz/storid/internal/platform/sms"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func NewApp() fx.Option {
@@ -26,36 +36,209 @@ func NewApp() fx.Option {
 					Database: cfg.DBConfig.Database,
 				}
 			},
-			func(cfg *config.Config) *notifications.SMTPConfig {
-				return &notifications.SMTPConfig{
-					Host:     cfg.SMTPConfig.Host,
-					Port:     cfg.SMTPConfig.Port,
-					Username: cfg.SMTPConfig.Username,
-					Password: cfg.SMTPConfig.Password,
-				}
-			},
 			db.NewDB,
 			fx.Annotate(
-				accountrepositories.NewAccountRepository,
+				providers.NewAccountRepository,
 				fx.As(new(summary.AccountRepository)),
+				fx.As(new(summary.PhoneRepository)),
 			),
 			fx.Annotate(
-				notifications.NewSMTPService,
+				providers.NewSMTPService,
+				fx.As(fx.Self()),
 				fx.As(new(summary.EmailService)),
 			),
+			fx.Annotate(
+				summaryAttachments,
+				fx.ResultTags(`group:"attachments,flatten"`),
+			),
+			fx.Annotate(
+				providers.NewUnsubscribeTokens,
+				fx.As(new(summary.UnsubscribeLinks)),
+			),
 			fx.Annotate(
 				summary.NewEmailSender,
+				fx.ParamTags(``, ``, `group:"attachments"`, ``),
 				fx.As(new(summary.Notifier)),
 				fx.ResultTags(`group:"notifiers"`),
 			),
 			fx.Annotate(
-				repositories.NewBalancesRepository,
+				webhookNotifiers,
+				fx.ResultTags(`group:"notifiers,flatten"`),
+			),
+			fx.Annotate(
+				smsNotifiers,
+				fx.ResultTags(`group:"notifiers,flatten"`),
+			),
+			fx.Annotate(
+				chatNotifiers,
+				fx.ResultTags(`group:"notifiers,flatten"`),
+			),
+			fx.Annotate(
+				providers.NewBalancesRepository,
 				fx.As(new(summary.SummaryGenerator))),
-
 			fx.Annotate(
-				summary.NewSender,
-				fx.ParamTags(``, `group:"notifiers"`),
+				analysisrepositories.NewSubscriptionRepository,
+				fx.As(new(summary.SubscriptionRepository)),
+			),
+			fx.Annotate(
+				transactionrepositories.NewTransactionRepository,
+				fx.As(new(statements.TransactionRepository)),
+			),
+			fx.Annotate(
+				statementrepositories.NewStatementRepository,
+				fx.As(new(statements.StatementRepository)),
+			),
+			fx.Annotate(
+				statements.NewGenerator,
+				fx.As(new(summary.StatementGenerator)),
+			),
+			fx.Annotate(
+				notificationrepositories.NewPreferenceRepository,
+				fx.As(new(summary.PreferenceRepository)),
+			),
+			fx.Annotate(
+				notificationrepositories.NewSendLogRepository,
+				fx.As(new(summary.SendLog)),
+			),
+			fx.Annotate(
+				notificationrepositories.NewDeliveryRepository,
+				fx.As(new(summary.DeliveryRepository)),
+			),
+			fx.Annotate(
+				newSender,
+				fx.ParamTags(``, ``, `group:"notifiers"`),
 			),
 		),
 	)
 }
+
+// OutputTo writes the emails to dir instead of sending them. Only the emails are rendered and nothing is
+// recorded as sent or uploaded, the statements are rolled back, so running the sender afterwards sends the
+// same summaries and statements.
+func OutputTo(dir string) fx.Option {
+	return fx.Options(
+		fx.Decorate(func(service *notifications.SMTPService) summary.EmailService {
+			return notifications.NewFileEmailService(service, dir)
+		}),
+		fx.Decorate(fx.Annotate(
+			localAttachments,
+			fx.ParamTags(`group:"attachments"`),
+			fx.ResultTags(`group:"attachments"`),
+		)),
+		fx.Decorate(func(_ statements.StatementRepository, db *gorm.DB) statements.StatementRepository {
+			return providers.NewRollbackStatementRepository(db)
+		}),
+		fx.Decorate(func(sender *summary.Sender) *summary.Sender {
+			var notifiers []summary.Notifier
+			for _, notifier := range sender.Notifier {
+				if notifier.Channel() == notificationdtos.ChannelEmail {
+					notifiers = append(notifiers, notifier)
+				}
+			}
+			sender.Notifier = notifiers
+			// every account is rendered regardless of its preferences and of what was already sent
+			sender.PreferenceRepository = nil
+			sender.SendLog = nil
+			sender.DeliveryRepository = nil
+			sender.RateLimits = nil
+			return sender
+		}),
+	)
+}
+
+func summaryAttachments(cfg *config.Config, db *gorm.DB) []summary.AttachmentBuilder {
+	transactions := transactionrepositories.NewTransactionRepository(db)
+
+	var attachments []summary.AttachmentBuilder
+	if cfg.SummaryConfig.AttachPDF {
+		attachments = append(attachments, summary.NewStatementPDF(transactions))
+	}
+
+	if cfg.SummaryConfig.Charts {
+		attachments = append(attachments, summary.NewMonthlyBalanceChart(), summary.NewCreditDebitChart())
+	}
+
+	switch cfg.SummaryConfig.CSVDelivery {
+	case summary.CSVDeliveryAttachment:
+		attachments = append(attachments, summary.NewTransactionsCSV(transactions))
+	case summary.CSVDeliveryS3:
+		store := filestores.NewS3FileStore(cfg.S3Config.Client, cfg.SummaryConfig.ExportBucket, cfg.SummaryConfig.ExportLinkTTL)
+		attachments = append(attachments, summary.NewLinkedAttachment(summary.NewTransactionsCSV(transactions), store))
+	}
+
+	return attachments
+}
+
+// localAttachments the files linked from the emails are attached instead, they are written next to the email.
+func localAttachments(attachments []summary.AttachmentBuilder) []summary.AttachmentBuilder {
+	local := make([]summary.AttachmentBuilder, 0, len(attachments))
+	for _, attachment := range attachments {
+		if linked, ok := attachment.(*summary.LinkedAttachment); ok {
+			attachment = linked.Builder
+		}
+		local = append(local, attachment)
+	}
+	return local
+}
+
+func webhookNotifiers(cfg *config.Config, db *gorm.DB) []summary.Notifier {
+	if !cfg.WebhookConfig.Enabled {
+		return nil
+	}
+
+	webhooks := notificationrepositories.NewWebhookRepository(db)
+	return []summary.Notifier{
+		summary.NewWebhookSender(webhooks, cfg.WebhookConfig.DefaultURL, cfg.WebhookConfig.Secret, cfg.WebhookConfig.Timeout),
+	}
+}
+
+// smsNotifiers an unknown provider or an invalid gateway URL stops the app instead of failing every message.
+func smsNotifiers(cfg *config.Config, accounts summary.AccountRepository, phones summary.PhoneRepository, log *zap.Logger) ([]summary.Notifier, error) {
+	if !cfg.SMSConfig.Enabled {
+		return nil, nil
+	}
+
+	var provider summary.SMSProvider
+	switch cfg.SMSConfig.Provider {
+	case sms.ProviderHTTP:
+		if err := sms.ValidateURL(cfg.SMSConfig.URL); err != nil {
+			return nil, fmt.Errorf("invalid SMS_API_URL: %w", err)
+		}
+		provider = sms.NewHTTPProvider(cfg.SMSConfig.URL, cfg.SMSConfig.APIKey, cfg.SMSConfig.From, cfg.SMSConfig.Timeout)
+	case sms.ProviderFake:
+		provider = sms.NewFakeProvider(log)
+	default:
+		return nil, fmt.Errorf("unknown SMS_PROVIDER %q, it must be %q or %q", cfg.SMSConfig.Provider, sms.ProviderHTTP, sms.ProviderFake)
+	}
+
+	return []summary.Notifier{summary.NewSMSSender(accounts, phones, provider)}, nil
+}
+
+func chatNotifiers(cfg *config.Config, accounts summary.AccountRepository, db *gorm.DB) []summary.Notifier {
+	if !cfg.ChatConfig.Enabled {
+		return nil
+	}
+
+	channels := notificationrepositories.NewChatChannelRepository(db)
+	return []summary.Notifier{summary.NewChatSender(accounts, channels, cfg.ChatConfig.Timeout)}
+}
+
+func newSender(cfg *config.Config, generator summary.SummaryGenerator, notifiers []summary.Notifier, subscriptions summary.SubscriptionRepository,
+	statements summary.StatementGenerator, preferences summary.PreferenceRepository, sendLog summary.SendLog, deliveries summary.DeliveryRepository,
+	log *zap.Logger) *summary.Sender {
+	sender := summary.NewSender(generator, notifiers, subscriptions, statements, preferences, sendLog, deliveries, log)
+	sender.RateLimits = cfg.SenderConfig.RateLimits
+	if cfg.SenderConfig.Workers > 0 {
+		sender.Workers = cfg.SenderConfig.Workers
+	}
+	if cfg.SenderConfig.MaxRetries != nil {
+		sender.MaxRetries = *cfg.SenderConfig.MaxRetries
+	}
+	if cfg.SenderConfig.RetryBackoff > 0 {
+		sender.RetryBackoff = cfg.SenderConfig.RetryBackoff
+	}
+	if cfg.SenderConfig.PageSize > 0 {
+		sender.PageSize = cfg.SenderConfig.PageSize
+	}
+	return sender
+}
@@ -3,19 +3,61 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/juaguz/storid/cmd/sender/internal"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
 	"github.com/juaguz/storid/internal/accounts/balances/summary"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// LambdaEvent the accounts fields select the summaries sent, every account is sent when they are empty.
+// StatementFrom and StatementTo, the first and last day of a period, send the statement of the period of
+// every account instead. The dates are formatted as YYYY-MM-DD.
 type LambdaEvent struct {
-	EventName string `json:"event_name"`
-	Payload   string `json:"payload"`
+	EventName     string         `json:"event_name"`
+	Payload       string         `json:"payload"`
+	AccountIDs    []uint         `json:"account_ids"`
+	AccountRanges []dtos.IDRange `json:"account_ranges"`
+	ActiveSince   string         `json:"active_since"`
+	ActiveUntil   string         `json:"active_until"`
+	CreatedAfter  string         `json:"created_after"`
+	StatementFrom string         `json:"statement_from"`
+	StatementTo   string         `json:"statement_to"`
+}
+
+func (e LambdaEvent) Selector() (dtos.AccountSelector, error) {
+	selector := dtos.AccountSelector{
+		AccountIDs: e.AccountIDs,
+		Ranges:     e.AccountRanges,
+	}
+
+	dates := []struct {
+		value string
+		date  *time.Time
+	}{
+		{e.ActiveSince, &selector.ActiveSince},
+		{e.ActiveUntil, &selector.ActiveUntil},
+		{e.CreatedAfter, &selector.CreatedAfter},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, d.value)
+		if err != nil {
+			return selector, fmt.Errorf("invalid date %s: %w", d.value, err)
+		}
+		*d.date = date
+	}
+
+	return selector, nil
 }
 
 func StartLambdaHandler(s *summary.Sender, logger *zap.Logger) {
@@ -32,8 +74,35 @@ func StartLambdaHandler(s *summary.Sender, logger *zap.Logger) {
 			}, err
 		}
 
+		selector, err := event.Selector()
+		if err != nil {
+			logger.Error("Invalid accounts", zap.Error(err))
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       err.Error(),
+			}, nil
+		}
+
+		send := func() (*summary.Report, error) {
+			return s.Send(ctx, summary.WithAccounts(selector))
+		}
+		if event.StatementFrom != "" || event.StatementTo != "" {
+			from, to, err := statementdtos.ParsePeriod(event.StatementFrom, event.StatementTo)
+			if err != nil {
+				logger.Error("Invalid statement period", zap.Error(err))
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       err.Error(),
+				}, nil
+			}
+			send = func() (*summary.Report, error) {
+				return s.SendPeriodStatements(ctx, from, to, summary.WithAccounts(selector))
+			}
+		}
+
 		logger.Info("Starting sender process")
-		if err := s.Send(ctx); err != nil {
+		report, err := send()
+		if err != nil {
 			logger.Error("Failed to send summary", zap.Error(err))
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusInternalServerError,
@@ -41,10 +110,24 @@ func StartLambdaHandler(s *summary.Sender, logger *zap.Logger) {
 			}, err
 		}
 
+		body, err := json.Marshal(report)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, err
+		}
+
+		// some of the notifications failed, the caller can retry them with the same request
+		if report.HasFailures() {
+			logger.Warn("Summary partially sent", zap.Int("failed", report.Failed))
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusMultiStatus,
+				Body:       string(body),
+			}, nil
+		}
+
 		logger.Info("Summary sent successfully")
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusOK,
-			Body:       "Sender executed successfully",
+			Body:       string(body),
 		}, nil
 	})
 }
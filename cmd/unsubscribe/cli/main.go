@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/juaguz/storid/cmd/unsubscribe/internal"
+	"github.com/juaguz/storid/internal/accounts/notifications"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// StartServer serves the unsubscribe links locally, UNSUBSCRIBE_BASE_URL should point to it.
+func StartServer(lc fx.Lifecycle, handler *notifications.UnsubscribeHandler, logger *zap.Logger, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/unsubscribe", handler)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				logger.Info("Starting unsubscribe server", zap.String("addr", addr))
+				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Fatal("Unsubscribe server failed", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	})
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "Address the unsubscribe server listens on")
+	flag.Parse()
+
+	app := fx.New(
+		internal.NewApp(),
+		fx.Invoke(func(lc fx.Lifecycle, handler *notifications.UnsubscribeHandler, logger *zap.Logger) {
+			StartServer(lc, handler, logger, *addr)
+		}),
+	)
+
+	if err := app.Err(); err != nil {
+		log.Fatalf("Error starting application: %v", err)
+	}
+
+	app.Run()
+}
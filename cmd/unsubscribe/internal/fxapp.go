@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"github.com/juaguz/storid/cmd/internal/providers"
+	accountnotifications "github.com/juaguz/storid/internal/accounts/notifications"
+	notificationrepositories "github.com/juaguz/storid/internal/accounts/notifications/repositories"
+	"github.com/juaguz/storid/internal/platform/config"
+	"github.com/juaguz/storid/internal/platform/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func NewApp() fx.Option {
+	return fx.Options(
+		fx.Provide(
+			zap.NewProduction,
+			config.LoadConfig,
+			func(cfg *config.Config) *db.Config {
+				return &db.Config{
+					Host:     cfg.DBConfig.Host,
+					Port:     cfg.DBConfig.Port,
+					User:     cfg.DBConfig.User,
+					Password: cfg.DBConfig.Password,
+					Database: cfg.DBConfig.Database,
+				}
+			},
+			db.NewDB,
+			fx.Annotate(
+				notificationrepositories.NewPreferenceRepository,
+				fx.As(new(accountnotifications.PreferenceRepository)),
+			),
+			providers.NewUnsubscribeTokens,
+			accountnotifications.NewUnsubscribeHandler,
+		),
+	)
+}
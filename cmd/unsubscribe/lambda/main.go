@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/juaguz/storid/cmd/unsubscribe/internal"
+	"github.com/juaguz/storid/internal/accounts/notifications"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// StartLambdaHandler converts the API Gateway proxy requests to the HTTP requests of the handler.
+func StartLambdaHandler(handler *notifications.UnsubscribeHandler, logger *zap.Logger) {
+	logger.Info("Starting Lambda handler")
+	lambda.Start(func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		logger.Info("Received HTTP request", zap.String("path", request.Path), zap.String("method", request.HTTPMethod))
+
+		body := request.Body
+		if request.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(request.Body)
+			if err != nil {
+				logger.Error("Failed to decode request body", zap.Error(err))
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       "Invalid input",
+				}, nil
+			}
+			body = string(decoded)
+		}
+
+		query := url.Values{}
+		for key, value := range request.QueryStringParameters {
+			query.Set(key, value)
+		}
+
+		httpRequest, err := http.NewRequestWithContext(ctx, request.HTTPMethod, request.Path+"?"+query.Encode(), strings.NewReader(body))
+		if err != nil {
+			logger.Error("Failed to build request", zap.Error(err))
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid input",
+			}, nil
+		}
+		for key, value := range request.Headers {
+			httpRequest.Header.Set(key, value)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httpRequest)
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: recorder.Code,
+			Headers:    map[string]string{"Content-Type": recorder.Header().Get("Content-Type")},
+			Body:       recorder.Body.String(),
+		}, nil
+	})
+}
+
+func main() {
+	app := fx.New(
+		internal.NewApp(),
+		fx.Invoke(StartLambdaHandler),
+	)
+
+	app.Run()
+}
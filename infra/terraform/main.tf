@@ -20,6 +20,11 @@ output "sender_api_url" {
   value       = "${aws_api_gateway_rest_api.my_api.execution_arn}/prod/sender"
 }
 
+output "unsubscribe_api_url" {
+  description = "The API Gateway URL of the unsubscribe links"
+  value       = "${aws_api_gateway_rest_api.my_api.execution_arn}/prod/unsubscribe"
+}
+
 # Output for the Importer Lambda ARN
 output "importer_lambda_arn" {
   description = "The ARN of the Importer Lambda function"
@@ -59,6 +64,13 @@ resource "aws_s3_object" "lambda_sender_zip" {
   etag   = filemd5(var.lambda_sender_zip)
 }
 
+resource "aws_s3_object" "lambda_unsubscribe_zip" {
+  bucket = aws_s3_bucket.lambda_bucket.bucket
+  key    = var.lambda_unsubscribe_zip
+  source = var.lambda_unsubscribe_zip
+  etag   = filemd5(var.lambda_unsubscribe_zip)
+}
+
 resource "aws_iam_role" "lambda_exec_role" {
   name = "lambda_exec_role"
 
@@ -102,6 +114,8 @@ resource "aws_lambda_function" "importer_lambda" {
       DB_PASSWORD_SECRET_ID     = aws_secretsmanager_secret.db_password_secret.id
       SMTP_CREDENTIALS_SECRET_ID = aws_secretsmanager_secret.smtp_credentials_secret.id
       S3_BUCKET_NAME = aws_s3_bucket.data_bucket.bucket
+      SMTP_TLS_MODE             = var.smtp_tls_mode
+      SMTP_AUTH                 = var.smtp_auth
     }
   }
 }
@@ -124,6 +138,47 @@ resource "aws_lambda_function" "sender_lambda" {
       DB_PASSWORD_SECRET_ID     = aws_secretsmanager_secret.db_password_secret.id
       SMTP_CREDENTIALS_SECRET_ID = aws_secretsmanager_secret.smtp_credentials_secret.id
       S3_BUCKET_NAME = aws_s3_bucket.data_bucket.bucket
+      SMTP_TLS_MODE             = var.smtp_tls_mode
+      SMTP_AUTH                 = var.smtp_auth
+      UNSUBSCRIBE_SECRET_ID     = aws_secretsmanager_secret.unsubscribe_secret.id
+      UNSUBSCRIBE_BASE_URL      = local.unsubscribe_url
+      WEBHOOKS_ENABLED          = var.webhooks_enabled
+      WEBHOOK_DEFAULT_URL       = var.webhook_default_url
+      WEBHOOK_SECRET_ID         = aws_secretsmanager_secret.webhook_secret.id
+      SMS_ENABLED               = var.sms_enabled
+      SMS_PROVIDER              = var.sms_provider
+      SMS_API_URL               = var.sms_api_url
+      SMS_FROM                  = var.sms_from
+      SMS_API_KEY_SECRET_ID     = aws_secretsmanager_secret.sms_api_key_secret.id
+      CHAT_ENABLED              = var.chat_enabled
+    }
+  }
+}
+
+# the url is built from the api id, the deployment depends on the lambdas
+locals {
+  unsubscribe_url = "https://${aws_api_gateway_rest_api.my_api.id}.execute-api.${var.aws_region}.amazonaws.com/prod/unsubscribe"
+}
+
+resource "aws_lambda_function" "unsubscribe_lambda" {
+  filename         = var.lambda_unsubscribe_zip
+  function_name    = "unsubscribe_lambda"
+  role             = aws_iam_role.lambda_exec_role.arn
+  handler          = "main"
+  source_code_hash = filebase64sha256(var.lambda_unsubscribe_zip)
+  runtime          = "provided.al2"
+  timeout          = 30
+  environment {
+    variables = {
+      DB_HOST                   = aws_db_instance.postgres.address
+      DB_PORT                   = "5432"
+      DB_USER                   = var.db_username
+      DB_NAME                   = var.db_name
+      ENVIRONMENT               = var.environment
+      DB_PASSWORD_SECRET_ID     = aws_secretsmanager_secret.db_password_secret.id
+      SMTP_CREDENTIALS_SECRET_ID = aws_secretsmanager_secret.smtp_credentials_secret.id
+      UNSUBSCRIBE_SECRET_ID     = aws_secretsmanager_secret.unsubscribe_secret.id
+      UNSUBSCRIBE_BASE_URL      = local.unsubscribe_url
     }
   }
 }
@@ -166,6 +221,42 @@ resource "aws_secretsmanager_secret_version" "smtp_credentials_secret_value" {
   })
 }
 
+resource "aws_secretsmanager_secret" "unsubscribe_secret" {
+  name        = "unsubscribe-secret"
+  description = "Key used to sign the unsubscribe links"
+}
+
+resource "aws_secretsmanager_secret_version" "unsubscribe_secret_value" {
+  secret_id     = aws_secretsmanager_secret.unsubscribe_secret.id
+  secret_string = jsonencode({
+    UNSUBSCRIBE_SECRET = var.unsubscribe_secret
+  })
+}
+
+resource "aws_secretsmanager_secret" "webhook_secret" {
+  name        = "webhook-secret"
+  description = "Key used to sign the webhooks without their own secret"
+}
+
+resource "aws_secretsmanager_secret_version" "webhook_secret_value" {
+  secret_id     = aws_secretsmanager_secret.webhook_secret.id
+  secret_string = jsonencode({
+    WEBHOOK_SECRET = var.webhook_secret
+  })
+}
+
+resource "aws_secretsmanager_secret" "sms_api_key_secret" {
+  name        = "sms-api-key"
+  description = "API key of the SMS gateway"
+}
+
+resource "aws_secretsmanager_secret_version" "sms_api_key_secret_value" {
+  secret_id     = aws_secretsmanager_secret.sms_api_key_secret.id
+  secret_string = jsonencode({
+    SMS_API_KEY = var.sms_api_key
+  })
+}
+
 resource "aws_iam_policy" "secretsmanager_access" {
   name        = "SecretsManagerAccessPolicy"
   description = "Allow lambdas to access Secrets Manager"
@@ -180,7 +271,10 @@ resource "aws_iam_policy" "secretsmanager_access" {
       ],
       "Resource": [
         "${aws_secretsmanager_secret.db_password_secret.arn}",
-        "${aws_secretsmanager_secret.smtp_credentials_secret.arn}"
+        "${aws_secretsmanager_secret.smtp_credentials_secret.arn}",
+        "${aws_secretsmanager_secret.unsubscribe_secret.arn}",
+        "${aws_secretsmanager_secret.webhook_secret.arn}",
+        "${aws_secretsmanager_secret.sms_api_key_secret.arn}"
       ]
     }
   ]
@@ -257,6 +351,12 @@ resource "aws_api_gateway_resource" "sender" {
   path_part   = "sender"
 }
 
+resource "aws_api_gateway_resource" "unsubscribe" {
+  rest_api_id = aws_api_gateway_rest_api.my_api.id
+  parent_id   = aws_api_gateway_rest_api.my_api.root_resource_id
+  path_part   = "unsubscribe"
+}
+
 # Method for Importer Lambda
 resource "aws_api_gateway_method" "importer_post" {
   rest_api_id   = aws_api_gateway_rest_api.my_api.id
@@ -273,6 +373,21 @@ resource "aws_api_gateway_method" "sender_post" {
   authorization = "NONE"
 }
 
+# Methods for Unsubscribe Lambda, GET opens the confirmation page and POST is the one-click unsubscribe
+resource "aws_api_gateway_method" "unsubscribe_get" {
+  rest_api_id   = aws_api_gateway_rest_api.my_api.id
+  resource_id   = aws_api_gateway_resource.unsubscribe.id
+  http_method   = "GET"
+  authorization = "NONE"
+}
+
+resource "aws_api_gateway_method" "unsubscribe_post" {
+  rest_api_id   = aws_api_gateway_rest_api.my_api.id
+  resource_id   = aws_api_gateway_resource.unsubscribe.id
+  http_method   = "POST"
+  authorization = "NONE"
+}
+
 # Integration for Importer Lambda
 resource "aws_api_gateway_integration" "importer_integration" {
   rest_api_id             = aws_api_gateway_rest_api.my_api.id
@@ -293,12 +408,33 @@ resource "aws_api_gateway_integration" "sender_integration" {
   uri                     = aws_lambda_function.sender_lambda.invoke_arn
 }
 
+# Integrations for Unsubscribe Lambda
+resource "aws_api_gateway_integration" "unsubscribe_get_integration" {
+  rest_api_id             = aws_api_gateway_rest_api.my_api.id
+  resource_id             = aws_api_gateway_resource.unsubscribe.id
+  http_method             = aws_api_gateway_method.unsubscribe_get.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = aws_lambda_function.unsubscribe_lambda.invoke_arn
+}
+
+resource "aws_api_gateway_integration" "unsubscribe_post_integration" {
+  rest_api_id             = aws_api_gateway_rest_api.my_api.id
+  resource_id             = aws_api_gateway_resource.unsubscribe.id
+  http_method             = aws_api_gateway_method.unsubscribe_post.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = aws_lambda_function.unsubscribe_lambda.invoke_arn
+}
+
 # Deployment
 resource "aws_api_gateway_deployment" "my_api_deployment" {
   rest_api_id = aws_api_gateway_rest_api.my_api.id
   depends_on = [
     aws_api_gateway_integration.importer_integration,
-    aws_api_gateway_integration.sender_integration
+    aws_api_gateway_integration.sender_integration,
+    aws_api_gateway_integration.unsubscribe_get_integration,
+    aws_api_gateway_integration.unsubscribe_post_integration
   ]
   stage_name = "prod"
 }
@@ -321,6 +457,15 @@ resource "aws_lambda_permission" "sender_permission" {
   source_arn    = "${aws_api_gateway_rest_api.my_api.execution_arn}/*/POST/sender"
 }
 
+# Permissions for Unsubscribe Lambda to be invoked by API Gateway
+resource "aws_lambda_permission" "unsubscribe_permission" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.unsubscribe_lambda.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_api_gateway_rest_api.my_api.execution_arn}/*/*/unsubscribe"
+}
+
 # Enable CORS for Importer
 resource "aws_api_gateway_method" "importer_options" {
   rest_api_id   = aws_api_gateway_rest_api.my_api.id
@@ -32,6 +32,18 @@ variable "smtp_port" {
     default     = "587"
 }
 
+variable "smtp_tls_mode" {
+    description = "The TLS mode of the SMTP connection: starttls, or implicit for port 465"
+    type        = string
+    default     = "starttls"
+}
+
+variable "smtp_auth" {
+    description = "The SMTP auth mechanism: plain, login or cram-md5"
+    type        = string
+    default     = "plain"
+}
+
 # Variable for environment configuration
 variable "environment" {
   description = "The environment where the application is running (e.g., local, production)"
@@ -104,6 +116,76 @@ variable "lambda_sender_zip" {
   default     = "lambda_sender.zip"
 }
 
+variable "lambda_unsubscribe_zip" {
+  description = "The ZIP file for the Unsubscribe Lambda function code"
+  type        = string
+  default     = "lambda_unsubscribe.zip"
+}
+
+# Variable for the key that signs the unsubscribe links
+variable "unsubscribe_secret" {
+  description = "The key used to sign the unsubscribe links"
+  type        = string
+  sensitive   = true
+}
+
+variable "webhooks_enabled" {
+  description = "Whether the summaries are posted to the webhooks of the accounts"
+  type        = string
+  default     = "false"
+}
+
+# Variable for the webhook of the accounts without their own
+variable "webhook_default_url" {
+  description = "The webhook the summaries of the accounts without a webhook are posted to"
+  type        = string
+  default     = ""
+}
+
+variable "webhook_secret" {
+  description = "The key used to sign the webhooks without their own secret"
+  type        = string
+  sensitive   = true
+  default     = ""
+}
+
+variable "sms_enabled" {
+  description = "Whether the summaries are also sent by SMS"
+  type        = string
+  default     = "false"
+}
+
+variable "sms_provider" {
+  description = "The SMS provider, http or fake"
+  type        = string
+  default     = "http"
+}
+
+variable "sms_api_url" {
+  description = "The URL of the SMS gateway"
+  type        = string
+  default     = ""
+}
+
+variable "sms_from" {
+  description = "The sender ID of the SMS"
+  type        = string
+  default     = "Storid"
+}
+
+variable "sms_api_key" {
+  description = "The API key of the SMS gateway"
+  type        = string
+  sensitive   = true
+  default     = ""
+}
+
+variable "chat_enabled" {
+  description = "Whether the summaries are posted to the chat channels of the accounts"
+  type        = string
+  default     = "false"
+}
+
 variable "allowed_ip" {
   description = "The IP address allowed to access the RDS instance"
   type        = string
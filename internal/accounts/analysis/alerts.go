@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	balancedtos "github.com/juaguz/storid/internal/accounts/balances/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/summary"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/accounts/transactions/importer"
+	"go.uber.org/zap"
+)
+
+type AccountRepository interface {
+	GetAccountByID(accountID uint) (*accountdtos.Account, error)
+}
+
+// AnomalyAlerter checks the imported transactions and alerts the accounts
+// right away instead of waiting for the periodic summary.
+type AnomalyAlerter struct {
+	TransactionRepository TransactionRepository
+	AccountRepository     AccountRepository
+	Notifiers             []summary.Notifier
+	// Thresholds per account tier, DefaultThresholds is used for the missing ones
+	Thresholds map[string]Thresholds
+	Log        *zap.Logger
+}
+
+func NewAnomalyAlerter(transactions TransactionRepository, accounts AccountRepository, notifiers []summary.Notifier, thresholds map[string]Thresholds, log *zap.Logger) *AnomalyAlerter {
+	return &AnomalyAlerter{
+		TransactionRepository: transactions,
+		AccountRepository:     accounts,
+		Notifiers:             notifiers,
+		Thresholds:            thresholds,
+		Log:                   log,
+	}
+}
+
+func (a *AnomalyAlerter) Handle(ctx context.Context, event string, payload []byte) error {
+	var imported importer.ImportedPayload
+	if err := json.Unmarshal(payload, &imported); err != nil {
+		return fmt.Errorf("error decoding imported payload: %w", err)
+	}
+
+	if err := a.Alert(ctx, imported); err != nil {
+		return fmt.Errorf("error alerting anomalies: %w", err)
+	}
+
+	return nil
+}
+
+// Alert looks for anomalies in the transactions created by the import.
+func (a *AnomalyAlerter) Alert(ctx context.Context, imported importer.ImportedPayload) error {
+	transactions, err := a.TransactionRepository.FindByImportID(ctx, imported.ImportID)
+	if err != nil {
+		return err
+	}
+
+	byAccount := make(map[uint][]*transactiondto.Transaction)
+	for _, t := range transactions {
+		byAccount[t.AccountID] = append(byAccount[t.AccountID], t)
+	}
+
+	for accountID, created := range byAccount {
+		if err := a.alertAccount(ctx, accountID, created); err != nil {
+			a.Log.Error("error alerting account", zap.Uint("account_id", accountID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (a *AnomalyAlerter) alertAccount(ctx context.Context, accountID uint, created []*transactiondto.Transaction) error {
+	all, err := a.TransactionRepository.FindByAccountID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	isNew := make(map[uint]bool, len(created))
+	for _, t := range created {
+		isNew[t.ID] = true
+	}
+
+	history := make([]*transactiondto.Transaction, 0, len(all))
+	for _, t := range all {
+		if !isNew[t.ID] {
+			history = append(history, t)
+		}
+	}
+
+	account, err := a.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return fmt.Errorf("error getting account by ID: %w", err)
+	}
+
+	alerts := DetectAnomalies(history, created, a.thresholds(account.Tier))
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	a.Log.Info("anomalies detected", zap.Uint("account_id", accountID), zap.Int("alerts", len(alerts)))
+
+	alert := &balancedtos.SummaryBalance{
+		Balance: balancedtos.Balance{AccountID: accountID},
+		Alerts:  alerts,
+	}
+
+	for _, notifier := range a.Notifiers {
+		if _, err := notifier.Send(ctx, accountID, alert); err != nil {
+			a.Log.Error("error sending alert", zap.Uint("account_id", accountID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (a *AnomalyAlerter) thresholds(tier string) Thresholds {
+	if t, ok := a.Thresholds[tier]; ok {
+		return t
+	}
+	return DefaultThresholds
+}
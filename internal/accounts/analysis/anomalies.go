@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+)
+
+// Thresholds used to decide whether a transaction is unusual for an account.
+type Thresholds struct {
+	// ZScore how many standard deviations a debit can be away from the average
+	ZScore float64 `json:"z_score"`
+	// LargeDebit amount in cents considered large when the account never had a debit like it
+	LargeDebit int `json:"large_debit"`
+	// DailyBurst max amount of transactions in a single day
+	DailyBurst int `json:"daily_burst"`
+	// MinHistory amount of previous transactions needed to know the account pattern
+	MinHistory int `json:"min_history"`
+}
+
+// DefaultThresholds is used for the tiers without a configuration.
+var DefaultThresholds = Thresholds{
+	ZScore:     3,
+	LargeDebit: 100_000,
+	DailyBurst: 30,
+	MinHistory: 20,
+}
+
+// DetectAnomalies compares the new transactions of an account against its history.
+// Accounts without enough history are skipped, there is no pattern to compare with.
+func DetectAnomalies(history, transactions []*transactiondto.Transaction, thresholds Thresholds) []*dtos.Alert {
+	if len(history) < thresholds.MinHistory || len(transactions) == 0 {
+		return nil
+	}
+
+	var alerts []*dtos.Alert
+
+	mean, stdDev, largest := debitStats(history)
+
+	for _, t := range transactions {
+		if t.Type != transactiondto.Debit {
+			continue
+		}
+
+		amount := abs(t.Amount)
+
+		if stdDev > 0 {
+			if z := (float64(amount) - mean) / stdDev; z > thresholds.ZScore {
+				alert := newAlert(t, dtos.AlertUnusualAmount)
+				alert.ZScore, alert.Average = z, int(mean)
+				alerts = append(alerts, alert)
+				continue
+			}
+		}
+
+		if thresholds.LargeDebit > 0 && amount >= thresholds.LargeDebit && largest < thresholds.LargeDebit {
+			alert := newAlert(t, dtos.AlertFirstLargeDebit)
+			alert.Threshold = thresholds.LargeDebit
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return append(alerts, detectBursts(history, transactions, thresholds)...)
+}
+
+// debitStats returns the mean, standard deviation and largest absolute debit amount.
+func debitStats(history []*transactiondto.Transaction) (float64, float64, int) {
+	var sum float64
+	var count, largest int
+	for _, t := range history {
+		if t.Type != transactiondto.Debit {
+			continue
+		}
+		amount := abs(t.Amount)
+		sum += float64(amount)
+		count++
+		if amount > largest {
+			largest = amount
+		}
+	}
+
+	if count == 0 {
+		return 0, 0, 0
+	}
+
+	mean := sum / float64(count)
+
+	var variance float64
+	for _, t := range history {
+		if t.Type != transactiondto.Debit {
+			continue
+		}
+		variance += math.Pow(float64(abs(t.Amount))-mean, 2)
+	}
+
+	return mean, math.Sqrt(variance / float64(count)), largest
+}
+
+// detectBursts flags the days touched by the new transactions with too many transactions.
+func detectBursts(history, transactions []*transactiondto.Transaction, thresholds Thresholds) []*dtos.Alert {
+	if thresholds.DailyBurst <= 0 {
+		return nil
+	}
+
+	perDay := make(map[time.Time]int)
+	for _, t := range history {
+		perDay[day(t.Date)]++
+	}
+
+	touched := make(map[time.Time]*transactiondto.Transaction)
+	for _, t := range transactions {
+		d := day(t.Date)
+		perDay[d]++
+		if _, ok := touched[d]; !ok {
+			touched[d] = t
+		}
+	}
+
+	var alerts []*dtos.Alert
+	for d, t := range touched {
+		if perDay[d] <= thresholds.DailyBurst {
+			continue
+		}
+		alerts = append(alerts, &dtos.Alert{
+			AccountID: t.AccountID,
+			Kind:      dtos.AlertBurst,
+			Date:      d,
+			Count:     perDay[d],
+		})
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Date.Before(alerts[j].Date)
+	})
+
+	return alerts
+}
+
+func newAlert(t *transactiondto.Transaction, kind dtos.AlertKind) *dtos.Alert {
+	return &dtos.Alert{
+		AccountID:  t.AccountID,
+		Kind:       kind,
+		ExternalID: t.ExternalID,
+		Date:       t.Date,
+		Amount:     t.Amount,
+	}
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	thresholds := Thresholds{ZScore: 3, LargeDebit: 50_000, DailyBurst: 3, MinHistory: 10}
+
+	var history []*transactiondto.Transaction
+	for i := 0; i < 20; i++ {
+		history = append(history, debit(start.AddDate(0, 0, i), 1000+i*10, "groceries"))
+	}
+
+	unusual := debit(start.AddDate(0, 1, 0), 9000, "electronics")
+	unusual.ExternalID = "unusual"
+
+	burstDay := start.AddDate(0, 2, 0)
+	transactions := []*transactiondto.Transaction{
+		unusual,
+		debit(burstDay, 1000, "groceries"),
+		debit(burstDay, 1000, "groceries"),
+		debit(burstDay, 1000, "groceries"),
+		debit(burstDay, 1000, "groceries"),
+	}
+
+	alerts := DetectAnomalies(history, transactions, thresholds)
+
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, dtos.AlertUnusualAmount, alerts[0].Kind)
+	assert.Equal(t, "unusual", alerts[0].ExternalID)
+	assert.Greater(t, alerts[0].ZScore, thresholds.ZScore)
+	assert.Positive(t, alerts[0].Average)
+	assert.Equal(t, dtos.AlertBurst, alerts[1].Kind)
+	assert.Equal(t, burstDay, alerts[1].Date)
+}
+
+func TestDetectAnomalies_FirstLargeDebit(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	thresholds := Thresholds{ZScore: 100, LargeDebit: 50_000, MinHistory: 2}
+
+	history := []*transactiondto.Transaction{
+		debit(start, 40_000, "rent"),
+		debit(start.AddDate(0, 1, 0), 40_000, "rent"),
+	}
+
+	alerts := DetectAnomalies(history, []*transactiondto.Transaction{debit(start.AddDate(0, 2, 0), 60_000, "rent")}, thresholds)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, dtos.AlertFirstLargeDebit, alerts[0].Kind)
+	assert.Equal(t, 50_000, alerts[0].Threshold)
+
+	history = append(history, debit(start.AddDate(0, 2, 0), 60_000, "rent"))
+	alerts = DetectAnomalies(history, []*transactiondto.Transaction{debit(start.AddDate(0, 3, 0), 60_000, "rent")}, thresholds)
+	assert.Empty(t, alerts)
+}
+
+func TestDetectAnomalies_NotEnoughHistory(t *testing.T) {
+	transactions := []*transactiondto.Transaction{debit(time.Now(), 1_000_000, "cars")}
+
+	assert.Empty(t, DetectAnomalies(nil, transactions, DefaultThresholds))
+}
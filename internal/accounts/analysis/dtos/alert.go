@@ -0,0 +1,32 @@
+package dtos
+
+import "time"
+
+type AlertKind string
+
+const (
+	// AlertUnusualAmount the amount is far from the account average
+	AlertUnusualAmount AlertKind = "unusual_amount"
+	// AlertFirstLargeDebit the account never had a debit this large
+	AlertFirstLargeDebit AlertKind = "first_large_debit"
+	// AlertBurst too many transactions in a single day
+	AlertBurst AlertKind = "burst"
+)
+
+// Alert is a transaction, or a day for bursts, flagged as unusual for the account. The detail shown
+// to the account is rendered in its locale from the kind and the parameters of the kind:
+// ZScore and Average for AlertUnusualAmount, Threshold for AlertFirstLargeDebit and Count for AlertBurst.
+type Alert struct {
+	AccountID  uint      `json:"account_id"`
+	Kind       AlertKind `json:"kind"`
+	ExternalID string    `json:"external_id"`
+	Date       time.Time `json:"date"`
+	Amount     int       `json:"amount"`
+	// ZScore standard deviations the debit is above the Average debit in cents
+	ZScore  float64 `json:"z_score,omitempty"`
+	Average int     `json:"average,omitempty"`
+	// Threshold large debit amount in cents
+	Threshold int `json:"threshold,omitempty"`
+	// Count transactions of the day
+	Count int `json:"count,omitempty"`
+}
@@ -0,0 +1,59 @@
+package dtos
+
+import "time"
+
+type Interval string
+
+const (
+	Weekly  Interval = "weekly"
+	Monthly Interval = "monthly"
+	Yearly  Interval = "yearly"
+)
+
+// Days returns the expected amount of days between two charges.
+func (i Interval) Days() int {
+	switch i {
+	case Weekly:
+		return 7
+	case Monthly:
+		return 30
+	case Yearly:
+		return 365
+	default:
+		return 0
+	}
+}
+
+// Tolerance returns how many days a charge can drift and still be considered part of the interval.
+func (i Interval) Tolerance() int {
+	switch i {
+	case Weekly:
+		return 2
+	case Monthly:
+		return 4
+	case Yearly:
+		return 15
+	default:
+		return 0
+	}
+}
+
+// Subscription is a recurring charge detected on an account, amounts are positive cents.
+type Subscription struct {
+	AccountID        uint      `json:"account_id"`
+	Merchant         string    `json:"merchant"`
+	Interval         Interval  `json:"interval"`
+	Amount           int       `json:"amount"`
+	PreviousAmount   int       `json:"previous_amount"`
+	Occurrences      int       `json:"occurrences"`
+	LastDate         time.Time `json:"last_date"`
+	NextExpectedDate time.Time `json:"next_expected_date"`
+	PriceIncreased   bool      `json:"price_increased"`
+	Missed           bool      `json:"missed"`
+}
+
+// IsMissed reports whether the next charge should have happened before now.
+func (s *Subscription) IsMissed(now time.Time) bool {
+	deadline := s.NextExpectedDate.AddDate(0, 0, s.Interval.Tolerance())
+	return now.After(deadline)
+}
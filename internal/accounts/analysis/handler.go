@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/accounts/transactions/importer"
+	"go.uber.org/zap"
+)
+
+type TransactionRepository interface {
+	GetAccountIDs(ctx context.Context) ([]uint, error)
+	GetAccountIDsByImportID(ctx context.Context, importID string) ([]uint, error)
+	FindByAccountID(ctx context.Context, accountID uint) ([]*transactiondto.Transaction, error)
+	FindByImportID(ctx context.Context, importID string) ([]*transactiondto.Transaction, error)
+}
+
+type SubscriptionRepository interface {
+	ReplaceSubscriptions(ctx context.Context, accountID uint, subscriptions []*dtos.Subscription) error
+}
+
+// SubscriptionDetector looks for recurring charges of the imported accounts every time a file is imported.
+type SubscriptionDetector struct {
+	TransactionRepository  TransactionRepository
+	SubscriptionRepository SubscriptionRepository
+	Log                    *zap.Logger
+}
+
+func NewSubscriptionDetector(transactions TransactionRepository, subscriptions SubscriptionRepository, log *zap.Logger) *SubscriptionDetector {
+	return &SubscriptionDetector{
+		TransactionRepository:  transactions,
+		SubscriptionRepository: subscriptions,
+		Log:                    log,
+	}
+}
+
+// Detect scans every account and stores the subscriptions found.
+func (sd *SubscriptionDetector) Detect(ctx context.Context) error {
+	accountIDs, err := sd.TransactionRepository.GetAccountIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting account IDs: %w", err)
+	}
+
+	return sd.detectAccounts(ctx, accountIDs)
+}
+
+// DetectImport scans the accounts with transactions created by the import, the subscriptions of
+// the rest of the accounts did not change.
+func (sd *SubscriptionDetector) DetectImport(ctx context.Context, importID string) error {
+	accountIDs, err := sd.TransactionRepository.GetAccountIDsByImportID(ctx, importID)
+	if err != nil {
+		return fmt.Errorf("error getting account IDs of import %s: %w", importID, err)
+	}
+
+	return sd.detectAccounts(ctx, accountIDs)
+}
+
+func (sd *SubscriptionDetector) detectAccounts(ctx context.Context, accountIDs []uint) error {
+	for _, accountID := range accountIDs {
+		if err := sd.DetectByAccountID(ctx, accountID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DetectByAccountID scans the transactions of a single account.
+func (sd *SubscriptionDetector) DetectByAccountID(ctx context.Context, accountID uint) error {
+	transactions, err := sd.TransactionRepository.FindByAccountID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("error getting transactions for account %d: %w", accountID, err)
+	}
+
+	subscriptions := DetectSubscriptions(transactions)
+	sd.Log.Info("subscriptions detected", zap.Uint("account_id", accountID), zap.Int("subscriptions", len(subscriptions)))
+
+	if err := sd.SubscriptionRepository.ReplaceSubscriptions(ctx, accountID, subscriptions); err != nil {
+		return fmt.Errorf("error saving subscriptions for account %d: %w", accountID, err)
+	}
+
+	return nil
+}
+
+func (sd *SubscriptionDetector) Handle(ctx context.Context, event string, payload []byte) error {
+	var imported importer.ImportedPayload
+	if err := json.Unmarshal(payload, &imported); err != nil {
+		return fmt.Errorf("error decoding imported payload: %w", err)
+	}
+
+	if err := sd.DetectImport(ctx, imported.ImportID); err != nil {
+		return fmt.Errorf("error detecting subscriptions: %w", err)
+	}
+
+	return nil
+}
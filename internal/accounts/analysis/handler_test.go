@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/accounts/transactions/importer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockTransactionRepository struct {
+	imports map[string][]uint
+	scanned []uint
+}
+
+func (m *MockTransactionRepository) GetAccountIDs(ctx context.Context) ([]uint, error) {
+	return []uint{1, 2, 3}, nil
+}
+
+func (m *MockTransactionRepository) GetAccountIDsByImportID(ctx context.Context, importID string) ([]uint, error) {
+	return m.imports[importID], nil
+}
+
+func (m *MockTransactionRepository) FindByAccountID(ctx context.Context, accountID uint) ([]*transactiondto.Transaction, error) {
+	m.scanned = append(m.scanned, accountID)
+	return nil, nil
+}
+
+func (m *MockTransactionRepository) FindByImportID(ctx context.Context, importID string) ([]*transactiondto.Transaction, error) {
+	return nil, nil
+}
+
+type MockSubscriptionRepository struct {
+	replaced []uint
+}
+
+func (m *MockSubscriptionRepository) ReplaceSubscriptions(ctx context.Context, accountID uint, subscriptions []*dtos.Subscription) error {
+	m.replaced = append(m.replaced, accountID)
+	return nil
+}
+
+func TestSubscriptionDetector_Handle(t *testing.T) {
+	transactions := &MockTransactionRepository{imports: map[string][]uint{"import-1": {2}}}
+	subscriptions := &MockSubscriptionRepository{}
+	detector := NewSubscriptionDetector(transactions, subscriptions, zap.NewNop())
+
+	payload, err := json.Marshal(importer.ImportedPayload{ImportID: "import-1"})
+	assert.NoError(t, err)
+
+	// only the accounts of the import are scanned
+	assert.NoError(t, detector.Handle(context.Background(), importer.EventImported, payload))
+	assert.Equal(t, []uint{2}, transactions.scanned)
+	assert.Equal(t, []uint{2}, subscriptions.replaced)
+
+	assert.Error(t, detector.Handle(context.Background(), importer.EventImported, []byte("{")))
+}
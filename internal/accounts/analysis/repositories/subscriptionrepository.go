@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/models"
+	"gorm.io/gorm"
+)
+
+type SubscriptionDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionDBRepository {
+	return &SubscriptionDBRepository{
+		DB: db,
+	}
+}
+
+// ReplaceSubscriptions replaces the subscriptions of the account with the detected ones,
+// the detection always scans the full history so the previous results are outdated.
+func (sr *SubscriptionDBRepository) ReplaceSubscriptions(ctx context.Context, accountID uint, subscriptions []*dtos.Subscription) error {
+	return sr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("account_id = ?", accountID).Delete(&models.Subscription{}).Error; err != nil {
+			return fmt.Errorf("error deleting subscriptions: %w", err)
+		}
+
+		if len(subscriptions) == 0 {
+			return nil
+		}
+
+		records := make([]models.Subscription, 0, len(subscriptions))
+		for _, s := range subscriptions {
+			records = append(records, models.Subscription{
+				AccountID:        accountID,
+				Merchant:         s.Merchant,
+				Interval:         string(s.Interval),
+				Amount:           s.Amount,
+				PreviousAmount:   s.PreviousAmount,
+				Occurrences:      s.Occurrences,
+				LastDate:         s.LastDate,
+				NextExpectedDate: s.NextExpectedDate,
+				PriceIncreased:   s.PriceIncreased,
+			})
+		}
+
+		if err := tx.Create(&records).Error; err != nil {
+			return fmt.Errorf("error creating subscriptions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetSubscriptionsByAccountIDs returns the subscriptions of the accounts flagging the missed ones,
+// the accounts without subscriptions are not in the map.
+func (sr *SubscriptionDBRepository) GetSubscriptionsByAccountIDs(ctx context.Context, accountIDs []uint) (map[uint][]*dtos.Subscription, error) {
+	subscriptions := make(map[uint][]*dtos.Subscription)
+	if len(accountIDs) == 0 {
+		return subscriptions, nil
+	}
+
+	var records []models.Subscription
+	err := sr.DB.WithContext(ctx).Where("account_id IN ?", accountIDs).Order("account_id, merchant").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting subscriptions by account IDs: %w", err)
+	}
+
+	now := time.Now()
+	for _, r := range records {
+		s := &dtos.Subscription{
+			AccountID:        r.AccountID,
+			Merchant:         r.Merchant,
+			Interval:         dtos.Interval(r.Interval),
+			Amount:           r.Amount,
+			PreviousAmount:   r.PreviousAmount,
+			Occurrences:      r.Occurrences,
+			LastDate:         r.LastDate,
+			NextExpectedDate: r.NextExpectedDate,
+			PriceIncreased:   r.PriceIncreased,
+		}
+		s.Missed = s.IsMissed(now)
+		subscriptions[r.AccountID] = append(subscriptions[r.AccountID], s)
+	}
+
+	return subscriptions, nil
+}
@@ -0,0 +1,150 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+)
+
+const (
+	// amountBand how much a charge can vary (in percent) and still belong to the same subscription
+	amountBand = 25
+
+	// minOccurrences the amount of charges needed to consider a weekly or monthly subscription
+	minOccurrences = 3
+
+	// minYearlyOccurrences yearly charges need less history to be detected
+	minYearlyOccurrences = 2
+
+	// matchRatio percentage of intervals that must match the period
+	matchRatio = 0.75
+)
+
+var intervals = []dtos.Interval{dtos.Weekly, dtos.Monthly, dtos.Yearly}
+
+// DetectSubscriptions scans the transactions of an account looking for recurring debits.
+// There is no merchant in the imported files, so the category is used as the merchant
+// and charges are grouped by category and amount band.
+func DetectSubscriptions(transactions []*transactiondto.Transaction) []*dtos.Subscription {
+	byMerchant := make(map[string][]*transactiondto.Transaction)
+	for _, t := range transactions {
+		if t.Type != transactiondto.Debit {
+			continue
+		}
+		merchant := t.Category
+		if merchant == "" {
+			merchant = transactiondto.Uncategorized
+		}
+		byMerchant[merchant] = append(byMerchant[merchant], t)
+	}
+
+	var subscriptions []*dtos.Subscription
+	for merchant, charges := range byMerchant {
+		for _, band := range groupByAmountBand(charges) {
+			if s := detect(merchant, band); s != nil {
+				subscriptions = append(subscriptions, s)
+			}
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].Merchant == subscriptions[j].Merchant {
+			return subscriptions[i].Amount < subscriptions[j].Amount
+		}
+		return subscriptions[i].Merchant < subscriptions[j].Merchant
+	})
+
+	return subscriptions
+}
+
+// groupByAmountBand splits the charges in groups with similar amounts.
+func groupByAmountBand(charges []*transactiondto.Transaction) [][]*transactiondto.Transaction {
+	sorted := make([]*transactiondto.Transaction, len(charges))
+	copy(sorted, charges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return abs(sorted[i].Amount) < abs(sorted[j].Amount)
+	})
+
+	var bands [][]*transactiondto.Transaction
+	var current []*transactiondto.Transaction
+	for _, c := range sorted {
+		if len(current) > 0 && abs(c.Amount)*100 > abs(current[0].Amount)*(100+amountBand) {
+			bands = append(bands, current)
+			current = nil
+		}
+		current = append(current, c)
+	}
+	if len(current) > 0 {
+		bands = append(bands, current)
+	}
+
+	return bands
+}
+
+func detect(merchant string, charges []*transactiondto.Transaction) *dtos.Subscription {
+	if len(charges) < minYearlyOccurrences {
+		return nil
+	}
+
+	sort.Slice(charges, func(i, j int) bool {
+		return charges[i].Date.Before(charges[j].Date)
+	})
+
+	for _, interval := range intervals {
+		if interval != dtos.Yearly && len(charges) < minOccurrences {
+			continue
+		}
+		if !matchesInterval(charges, interval) {
+			continue
+		}
+
+		last := charges[len(charges)-1]
+		previous := charges[len(charges)-2]
+
+		return &dtos.Subscription{
+			AccountID:        last.AccountID,
+			Merchant:         merchant,
+			Interval:         interval,
+			Amount:           abs(last.Amount),
+			PreviousAmount:   abs(previous.Amount),
+			Occurrences:      len(charges),
+			LastDate:         last.Date,
+			NextExpectedDate: nextDate(last.Date, interval),
+			PriceIncreased:   abs(last.Amount) > abs(previous.Amount),
+		}
+	}
+
+	return nil
+}
+
+func matchesInterval(charges []*transactiondto.Transaction, interval dtos.Interval) bool {
+	matches := 0
+	for i := 1; i < len(charges); i++ {
+		days := int(charges[i].Date.Sub(charges[i-1].Date).Hours() / 24)
+		if abs(days-interval.Days()) <= interval.Tolerance() {
+			matches++
+		}
+	}
+
+	return float64(matches) >= float64(len(charges)-1)*matchRatio
+}
+
+func nextDate(last time.Time, interval dtos.Interval) time.Time {
+	switch interval {
+	case dtos.Weekly:
+		return last.AddDate(0, 0, 7)
+	case dtos.Monthly:
+		return last.AddDate(0, 1, 0)
+	default:
+		return last.AddDate(1, 0, 0)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func debit(date time.Time, amount int, category string) *transactiondto.Transaction {
+	return &transactiondto.Transaction{
+		Date:      date,
+		Amount:    -amount,
+		AccountID: 1,
+		Type:      transactiondto.Debit,
+		Category:  category,
+	}
+}
+
+func TestDetectSubscriptions(t *testing.T) {
+	start := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+
+	transactions := []*transactiondto.Transaction{
+		// monthly streaming with a price increase
+		debit(start, 999, "streaming"),
+		debit(start.AddDate(0, 1, 0), 999, "streaming"),
+		debit(start.AddDate(0, 2, 1), 999, "streaming"),
+		debit(start.AddDate(0, 3, 0), 1199, "streaming"),
+		// weekly gym
+		debit(start, 1500, "gym"),
+		debit(start.AddDate(0, 0, 7), 1500, "gym"),
+		debit(start.AddDate(0, 0, 14), 1500, "gym"),
+		// yearly insurance
+		debit(start, 50000, "insurance"),
+		debit(start.AddDate(1, 0, 0), 51000, "insurance"),
+		// random groceries
+		debit(start, 3200, "groceries"),
+		debit(start.AddDate(0, 0, 3), 2900, "groceries"),
+		debit(start.AddDate(0, 0, 17), 3100, "groceries"),
+		// credits are never subscriptions
+		{Date: start, Amount: 1000, AccountID: 1, Type: transactiondto.Credit},
+	}
+
+	subscriptions := DetectSubscriptions(transactions)
+
+	assert.Len(t, subscriptions, 3)
+
+	gym := subscriptions[0]
+	assert.Equal(t, "gym", gym.Merchant)
+	assert.Equal(t, dtos.Weekly, gym.Interval)
+	assert.Equal(t, 1500, gym.Amount)
+	assert.Equal(t, start.AddDate(0, 0, 21), gym.NextExpectedDate)
+	assert.False(t, gym.PriceIncreased)
+
+	insurance := subscriptions[1]
+	assert.Equal(t, "insurance", insurance.Merchant)
+	assert.Equal(t, dtos.Yearly, insurance.Interval)
+	assert.True(t, insurance.PriceIncreased)
+
+	streaming := subscriptions[2]
+	assert.Equal(t, "streaming", streaming.Merchant)
+	assert.Equal(t, dtos.Monthly, streaming.Interval)
+	assert.Equal(t, 1199, streaming.Amount)
+	assert.Equal(t, 999, streaming.PreviousAmount)
+	assert.Equal(t, 4, streaming.Occurrences)
+	assert.True(t, streaming.PriceIncreased)
+	assert.Equal(t, start.AddDate(0, 4, 0), streaming.NextExpectedDate)
+}
+
+func TestSubscription_IsMissed(t *testing.T) {
+	s := &dtos.Subscription{
+		Interval:         dtos.Monthly,
+		NextExpectedDate: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.False(t, s.IsMissed(time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, s.IsMissed(time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,15 @@
+package dtos
+
+// BalanceMismatch is a period where the incremental totals differ from the transactions.
+type BalanceMismatch struct {
+	AccountID uint   `json:"account_id"`
+	Month     string `json:"month,omitempty"`
+	// Day is set instead of Month when the mismatch is in the daily totals
+	Day               string `json:"day,omitempty"`
+	ExpectedCreditSum int    `json:"expected_credit_sum"`
+	ActualCreditSum   int    `json:"actual_credit_sum"`
+	ExpectedDebitSum  int    `json:"expected_debit_sum"`
+	ActualDebitSum    int    `json:"actual_debit_sum"`
+	ExpectedCount     int    `json:"expected_count"`
+	ActualCount       int    `json:"actual_count"`
+}
@@ -0,0 +1,70 @@
+package dtos
+
+import (
+	"strings"
+	"time"
+)
+
+// DailyBalance Balance is the end of day balance, Debits is negative.
+type DailyBalance struct {
+	Day              time.Time `json:"day"`
+	Credits          int       `json:"credits"`
+	Debits           int       `json:"debits"`
+	TransactionCount int       `json:"transaction_count"`
+	Balance          int       `json:"balance"`
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// FillDailyBalances returns one balance per day between from and to (both included),
+// days without transactions keep the balance of the previous day starting from opening.
+func FillDailyBalances(opening int, days []*DailyBalance, from, to time.Time) []*DailyBalance {
+	byDay := make(map[string]*DailyBalance, len(days))
+	for _, d := range days {
+		byDay[d.Day.Format(time.DateOnly)] = d
+	}
+
+	from = truncateDay(from)
+	to = truncateDay(to)
+
+	var result []*DailyBalance
+	balance := opening
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		d, ok := byDay[day.Format(time.DateOnly)]
+		if !ok {
+			d = &DailyBalance{Day: day, Balance: balance}
+		}
+		balance = d.Balance
+		result = append(result, d)
+	}
+
+	return result
+}
+
+// Sparkline draws the balances using block characters, it renders in text and html clients.
+func Sparkline(days []*DailyBalance) string {
+	if len(days) == 0 {
+		return ""
+	}
+
+	lowest, highest := days[0].Balance, days[0].Balance
+	for _, d := range days {
+		lowest = min(lowest, d.Balance)
+		highest = max(highest, d.Balance)
+	}
+
+	var builder strings.Builder
+	for _, d := range days {
+		i := 0
+		if highest > lowest {
+			i = (d.Balance - lowest) * (len(sparks) - 1) / (highest - lowest)
+		}
+		builder.WriteRune(sparks[i])
+	}
+
+	return builder.String()
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,40 @@
+package dtos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillDailyBalances(t *testing.T) {
+	from := time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)
+
+	days := []*DailyBalance{
+		{Day: time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), Credits: 500, Debits: -200, TransactionCount: 2, Balance: 1300},
+		{Day: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), Debits: -1000, TransactionCount: 1, Balance: 300},
+	}
+
+	result := FillDailyBalances(1000, days, from, to)
+
+	assert.Len(t, result, 5)
+
+	balances := make([]int, 0, len(result))
+	for _, d := range result {
+		balances = append(balances, d.Balance)
+	}
+	assert.Equal(t, []int{1000, 1300, 1300, 300, 300}, balances)
+
+	// March 14th has no transactions, it keeps the balance of the 13th
+	assert.Equal(t, time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), result[2].Day)
+	assert.Equal(t, 0, result[2].TransactionCount)
+}
+
+func TestSparkline(t *testing.T) {
+	days := []*DailyBalance{{Balance: 0}, {Balance: 700}, {Balance: 350}, {Balance: 700}}
+
+	assert.Equal(t, "▁█▄█", Sparkline(days))
+	assert.Equal(t, "▁▁", Sparkline([]*DailyBalance{{Balance: 5}, {Balance: 5}}))
+	assert.Equal(t, "", Sparkline(nil))
+}
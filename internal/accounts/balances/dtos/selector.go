@@ -0,0 +1,77 @@
+package dtos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IDRange account IDs between From and To, both included.
+type IDRange struct {
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
+
+// AccountSelector picks the accounts whose summaries are sent, the zero value selects every account.
+// AccountIDs and Ranges are combined, an account must match them and the rest of the criteria.
+type AccountSelector struct {
+	AccountIDs []uint
+	Ranges     []IDRange
+	// ActiveSince accounts with transactions since the day, until the end of the ActiveUntil day when it is set
+	ActiveSince time.Time
+	ActiveUntil time.Time
+	// CreatedAfter accounts created after the time
+	CreatedAfter time.Time
+}
+
+// All the selector does not filter any account.
+func (s AccountSelector) All() bool {
+	return len(s.AccountIDs) == 0 && len(s.Ranges) == 0 && s.ActiveSince.IsZero() && s.ActiveUntil.IsZero() && s.CreatedAfter.IsZero()
+}
+
+// ParseAccounts reads a comma separated list of account IDs and ranges e.g. "1,5,10-20".
+func ParseAccounts(accounts string) ([]uint, []IDRange, error) {
+	var ids []uint
+	var ranges []IDRange
+
+	for _, part := range strings.Split(accounts, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			id, err := parseAccountID(part)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids = append(ids, id)
+			continue
+		}
+
+		r := IDRange{}
+		var err error
+		if r.From, err = parseAccountID(from); err != nil {
+			return nil, nil, err
+		}
+		if r.To, err = parseAccountID(to); err != nil {
+			return nil, nil, err
+		}
+		if r.From > r.To {
+			return nil, nil, fmt.Errorf("invalid account range %s", part)
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ids, ranges, nil
+}
+
+func parseAccountID(id string) (uint, error) {
+	parsed, err := strconv.ParseUint(strings.TrimSpace(id), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid account ID %s: %w", id, err)
+	}
+	return uint(parsed), nil
+}
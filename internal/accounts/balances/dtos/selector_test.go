@@ -0,0 +1,28 @@
+package dtos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccounts(t *testing.T) {
+	ids, ranges, err := ParseAccounts("1, 5,10-20,")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 5}, ids)
+	assert.Equal(t, []IDRange{{From: 10, To: 20}}, ranges)
+
+	_, _, err = ParseAccounts("20-10")
+	assert.Error(t, err)
+
+	_, _, err = ParseAccounts("abc")
+	assert.Error(t, err)
+}
+
+func TestAccountSelector_All(t *testing.T) {
+	assert.True(t, AccountSelector{}.All())
+	assert.False(t, AccountSelector{AccountIDs: []uint{1}}.All())
+	assert.False(t, AccountSelector{ActiveSince: time.Now()}.All())
+}
@@ -1,7 +1,13 @@
 package dtos
 
 import (
+	"fmt"
+	"sort"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
 	"github.com/juaguz/storid/internal/platform/currencies"
+	"github.com/juaguz/storid/internal/platform/i18n"
 	"github.com/juaguz/storid/internal/platform/months"
 )
 
@@ -18,23 +24,150 @@ type MonthlyBalance struct {
 	Month int `json:"month"`
 }
 
+// OtherCategory groups every category that is not part of the top N.
+const OtherCategory = "other"
+
+// CategorySpending is the amount debited for a category, Amount is always positive.
+type CategorySpending struct {
+	Category   string  `json:"category"`
+	Amount     int     `json:"amount"`
+	Percentage float64 `json:"percentage"`
+}
+
 type SummaryBalance struct {
 	Balance
 	MonthlyBalance map[months.Month]*MonthlyBalance `json:"monthly_balance"`
+	Categories     []*CategorySpending              `json:"categories"`
+	Subscriptions  []*analysisdtos.Subscription     `json:"subscriptions"`
+	DailyBalances  []*DailyBalance                  `json:"daily_balances"`
+	// Alerts is only set when the summary is used to notify unusual activity
+	Alerts []*analysisdtos.Alert `json:"alerts"`
+	// Statement is only set when the summary is used to send the statement of a cycle
+	Statement *statementdtos.Statement `json:"statement"`
+}
+
+// TopCategories returns the top n categories ordered by amount,
+// the remaining ones are grouped under OtherCategory.
+func TopCategories(spending map[string]int, n int) []*CategorySpending {
+	total := 0
+	categories := make([]*CategorySpending, 0, len(spending))
+	for category, amount := range spending {
+		if amount < 0 {
+			amount = -amount
+		}
+		total += amount
+		categories = append(categories, &CategorySpending{Category: category, Amount: amount})
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Amount == categories[j].Amount {
+			return categories[i].Category < categories[j].Category
+		}
+		return categories[i].Amount > categories[j].Amount
+	})
+
+	if n > 0 && len(categories) > n {
+		other := &CategorySpending{Category: OtherCategory}
+		for _, c := range categories[n:] {
+			other.Amount += c.Amount
+		}
+		categories = append(categories[:n], other)
+	}
+
+	for _, c := range categories {
+		if total > 0 {
+			c.Percentage = float64(c.Amount) * 100 / float64(total)
+		}
+	}
+
+	return categories
 }
 
 type ToMapOption func(map[string]interface{})
 
 func WithDecimalConversion() ToMapOption {
 	return func(result map[string]interface{}) {
-		if val, ok := result["TotalBalance"].(int); ok {
-			result["TotalBalance"] = currencies.CentsToString(val)
+		convertAmounts(result, currencies.CentsToString)
+	}
+}
+
+// WithLocale formats the amounts and month names for the locale of the account, it is used
+// instead of WithDecimalConversion. The locale is added to the result for the templates.
+func WithLocale(locale string) ToMapOption {
+	return func(result map[string]interface{}) {
+		convertAmounts(result, func(cents int) string {
+			return i18n.FormatCents(locale, cents)
+		})
+		if monthly, ok := result["MonthlyBalance"].([]monthBalance); ok {
+			for i := range monthly {
+				for _, month := range months.OrderMonths {
+					if monthly[i].Month == month.String() {
+						monthly[i].Month = i18n.MonthName(locale, month)
+					}
+				}
+			}
+		}
+		result["Locale"] = locale
+	}
+}
+
+func convertAmounts(result map[string]interface{}, format func(int) string) {
+	if val, ok := result["TotalBalance"].(int); ok {
+		result["TotalBalance"] = format(val)
+	}
+	if val, ok := result["AvrDebitAmount"].(int); ok {
+		result["AvrDebitAmount"] = format(val)
+	}
+	if val, ok := result["AvrCreditAmount"].(int); ok {
+		result["AvrCreditAmount"] = format(val)
+	}
+	if categories, ok := result["Categories"].([]categorySpending); ok {
+		for i := range categories {
+			if val, ok := categories[i].Amount.(int); ok {
+				categories[i].Amount = format(val)
+			}
 		}
-		if val, ok := result["AvrDebitAmount"].(int); ok {
-			result["AvrDebitAmount"] = currencies.CentsToString(val)
+	}
+	if subscriptions, ok := result["Subscriptions"].([]subscription); ok {
+		for i := range subscriptions {
+			if val, ok := subscriptions[i].Amount.(int); ok {
+				subscriptions[i].Amount = format(val)
+			}
+			if val, ok := subscriptions[i].PreviousAmount.(int); ok {
+				subscriptions[i].PreviousAmount = format(val)
+			}
 		}
-		if val, ok := result["AvrCreditAmount"].(int); ok {
-			result["AvrCreditAmount"] = currencies.CentsToString(val)
+	}
+	if days, ok := result["DailyBalances"].([]dailyBalance); ok {
+		for i := range days {
+			if val, ok := days[i].Credits.(int); ok {
+				days[i].Credits = format(val)
+			}
+			if val, ok := days[i].Debits.(int); ok {
+				days[i].Debits = format(val)
+			}
+			if val, ok := days[i].Balance.(int); ok {
+				days[i].Balance = format(val)
+			}
+		}
+	}
+	if st, ok := result["Statement"].(*statement); ok && st != nil {
+		for _, val := range []*interface{}{&st.OpeningBalance, &st.ClosingBalance, &st.TotalCredits, &st.TotalDebits} {
+			if cents, ok := (*val).(int); ok {
+				*val = format(cents)
+			}
+		}
+		for i := range st.Transactions {
+			if val, ok := st.Transactions[i].Amount.(int); ok {
+				st.Transactions[i].Amount = format(val)
+			}
+		}
+	}
+	if alerts, ok := result["Alerts"].([]alert); ok {
+		for i := range alerts {
+			if val, ok := alerts[i].Amount.(int); ok {
+				alerts[i].Amount = format(val)
+			}
 		}
 	}
 }
@@ -44,6 +177,58 @@ type monthBalance struct {
 	Count int    `json:"count"`
 }
 
+type categorySpending struct {
+	Category   string      `json:"category"`
+	Amount     interface{} `json:"amount"`
+	Percentage string      `json:"percentage"`
+}
+
+type dailyBalance struct {
+	Day              string      `json:"day"`
+	Credits          interface{} `json:"credits"`
+	Debits           interface{} `json:"debits"`
+	TransactionCount int         `json:"transaction_count"`
+	Balance          interface{} `json:"balance"`
+}
+
+type alert struct {
+	Kind       string      `json:"kind"`
+	ExternalID string      `json:"external_id"`
+	Date       string      `json:"date"`
+	Amount     interface{} `json:"amount"`
+	Detail     string      `json:"detail"`
+}
+
+type statement struct {
+	Sequence       int             `json:"sequence"`
+	PeriodStart    string          `json:"period_start"`
+	PeriodEnd      string          `json:"period_end"`
+	OpeningBalance interface{}     `json:"opening_balance"`
+	ClosingBalance interface{}     `json:"closing_balance"`
+	TotalCredits   interface{}     `json:"total_credits"`
+	TotalDebits    interface{}     `json:"total_debits"`
+	CreditCount    int             `json:"credit_count"`
+	DebitCount     int             `json:"debit_count"`
+	Transactions   []statementLine `json:"transactions"`
+}
+
+type statementLine struct {
+	Date     string      `json:"date"`
+	Type     string      `json:"type"`
+	Category string      `json:"category"`
+	Amount   interface{} `json:"amount"`
+}
+
+type subscription struct {
+	Merchant         string      `json:"merchant"`
+	Interval         string      `json:"interval"`
+	Amount           interface{} `json:"amount"`
+	PreviousAmount   interface{} `json:"previous_amount"`
+	NextExpectedDate string      `json:"next_expected_date"`
+	PriceIncreased   bool        `json:"price_increased"`
+	Missed           bool        `json:"missed"`
+}
+
 func (sb *SummaryBalance) ToMap(options ...ToMapOption) map[string]interface{} {
 	result := map[string]interface{}{
 		"TotalBalance":     sb.TotalBalance,
@@ -67,9 +252,105 @@ func (sb *SummaryBalance) ToMap(options ...ToMapOption) map[string]interface{} {
 
 	result["MonthlyBalance"] = monthlyBalances
 
+	var categories []categorySpending
+	for _, c := range sb.Categories {
+		categories = append(categories, categorySpending{
+			Category:   c.Category,
+			Amount:     c.Amount,
+			Percentage: fmt.Sprintf("%.1f%%", c.Percentage),
+		})
+	}
+
+	result["Categories"] = categories
+
+	var subscriptions []subscription
+	for _, s := range sb.Subscriptions {
+		subscriptions = append(subscriptions, subscription{
+			Merchant:         s.Merchant,
+			Interval:         string(s.Interval),
+			Amount:           s.Amount,
+			PreviousAmount:   s.PreviousAmount,
+			NextExpectedDate: s.NextExpectedDate.Format("2006-01-02"),
+			PriceIncreased:   s.PriceIncreased,
+			Missed:           s.Missed,
+		})
+	}
+
+	result["Subscriptions"] = subscriptions
+
+	var days []dailyBalance
+	for _, d := range sb.DailyBalances {
+		days = append(days, dailyBalance{
+			Day:              d.Day.Format("2006-01-02"),
+			Credits:          d.Credits,
+			Debits:           d.Debits,
+			TransactionCount: d.TransactionCount,
+			Balance:          d.Balance,
+		})
+	}
+
+	result["DailyBalances"] = days
+	result["Sparkline"] = Sparkline(sb.DailyBalances)
+
+	var alerts []alert
+	for _, a := range sb.Alerts {
+		alerts = append(alerts, alert{
+			Kind:       string(a.Kind),
+			ExternalID: a.ExternalID,
+			Date:       a.Date.Format("2006-01-02"),
+			Amount:     a.Amount,
+		})
+	}
+
+	result["Alerts"] = alerts
+
+	var st *statement
+	if sb.Statement != nil {
+		st = &statement{
+			Sequence:       sb.Statement.Sequence,
+			PeriodStart:    sb.Statement.PeriodStart.Format("2006-01-02"),
+			PeriodEnd:      sb.Statement.LastDay().Format("2006-01-02"),
+			OpeningBalance: sb.Statement.OpeningBalance,
+			ClosingBalance: sb.Statement.ClosingBalance,
+			TotalCredits:   sb.Statement.TotalCredits,
+			TotalDebits:    sb.Statement.TotalDebits,
+			CreditCount:    sb.Statement.CreditCount,
+			DebitCount:     sb.Statement.DebitCount,
+		}
+		for _, t := range sb.Statement.Transactions {
+			st.Transactions = append(st.Transactions, statementLine{
+				Date:     t.Date.Format("2006-01-02"),
+				Type:     string(t.Type),
+				Category: t.Category,
+				Amount:   t.Amount,
+			})
+		}
+	}
+
+	result["Statement"] = st
+
 	for _, opt := range options {
 		opt(result)
 	}
 
+	// the alert details are rendered once the locale, if any, was set
+	locale, _ := result["Locale"].(string)
+	for i, a := range sb.Alerts {
+		alerts[i].Detail = alertDetail(locale, a)
+	}
+
 	return result
 }
+
+// alertDetail renders the detail of the alert from its kind and parameters.
+func alertDetail(locale string, a *analysisdtos.Alert) string {
+	switch a.Kind {
+	case analysisdtos.AlertUnusualAmount:
+		return i18n.T(locale, "alert_unusual_amount", i18n.FormatDecimal(locale, a.ZScore, 1), i18n.FormatCents(locale, a.Average))
+	case analysisdtos.AlertFirstLargeDebit:
+		return i18n.T(locale, "alert_first_large_debit", i18n.FormatCents(locale, a.Threshold))
+	case analysisdtos.AlertBurst:
+		return i18n.T(locale, "alert_burst", a.Count, a.Date.Format("2006-01-02"))
+	}
+	return ""
+}
@@ -2,7 +2,9 @@ package dtos
 
 import (
 	"testing"
+	"time"
 
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
 	"github.com/juaguz/storid/internal/platform/months"
 	"github.com/stretchr/testify/assert"
 )
@@ -60,3 +62,85 @@ func TestSummaryBalance_ToMap(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, expectedMonthlyBalance, monthlyBalance)
 }
+
+func TestTopCategories(t *testing.T) {
+	spending := map[string]int{
+		"groceries":     -5000,
+		"rent":          -10000,
+		"entertainment": -2000,
+		"transport":     -2000,
+		"health":        -1000,
+	}
+
+	result := TopCategories(spending, 3)
+
+	assert.Len(t, result, 4)
+	assert.Equal(t, &CategorySpending{Category: "rent", Amount: 10000, Percentage: 50}, result[0])
+	assert.Equal(t, &CategorySpending{Category: "groceries", Amount: 5000, Percentage: 25}, result[1])
+	assert.Equal(t, &CategorySpending{Category: "entertainment", Amount: 2000, Percentage: 10}, result[2])
+	assert.Equal(t, &CategorySpending{Category: OtherCategory, Amount: 3000, Percentage: 15}, result[3])
+
+	assert.Len(t, TopCategories(spending, 10), 5)
+	assert.Empty(t, TopCategories(map[string]int{}, 3))
+}
+
+func TestSummaryBalance_ToMapCategories(t *testing.T) {
+	summaryBalance := &SummaryBalance{
+		Categories: []*CategorySpending{
+			{Category: "rent", Amount: 10000, Percentage: 66.666},
+			{Category: "groceries", Amount: 5000, Percentage: 33.333},
+		},
+	}
+
+	result := summaryBalance.ToMap(WithDecimalConversion())
+
+	categories, ok := result["Categories"].([]categorySpending)
+	assert.True(t, ok)
+	assert.Equal(t, []categorySpending{
+		{Category: "rent", Amount: "100.00", Percentage: "66.7%"},
+		{Category: "groceries", Amount: "50.00", Percentage: "33.3%"},
+	}, categories)
+}
+
+func TestSummaryBalance_ToMapWithLocale(t *testing.T) {
+	summaryBalance := &SummaryBalance{
+		Balance: Balance{
+			TotalBalance:    123456,
+			AvrDebitAmount:  -250,
+			AvrCreditAmount: 300,
+		},
+		MonthlyBalance: map[months.Month]*MonthlyBalance{
+			months.January: {Balance: Balance{TransactionCount: 3}, Month: int(months.January)},
+		},
+	}
+
+	result := summaryBalance.ToMap(WithLocale("es-AR"))
+
+	assert.Equal(t, "$ 1.234,56", result["TotalBalance"])
+	assert.Equal(t, "-$ 2,50", result["AvrDebitAmount"])
+	assert.Equal(t, "$ 3,00", result["AvrCreditAmount"])
+	assert.Equal(t, "es-AR", result["Locale"])
+	assert.Equal(t, []monthBalance{{Month: "Enero", Count: 3}}, result["MonthlyBalance"])
+
+	result = summaryBalance.ToMap(WithLocale("en"))
+
+	assert.Equal(t, "$1,234.56", result["TotalBalance"])
+	assert.Equal(t, []monthBalance{{Month: "January", Count: 3}}, result["MonthlyBalance"])
+}
+
+func TestSummaryBalance_ToMapAlertDetails(t *testing.T) {
+	summaryBalance := &SummaryBalance{Alerts: []*analysisdtos.Alert{
+		{Kind: analysisdtos.AlertUnusualAmount, Amount: -90000, ZScore: 3.46, Average: 12050},
+		{Kind: analysisdtos.AlertFirstLargeDebit, Amount: -150000, Threshold: 100000},
+		{Kind: analysisdtos.AlertBurst, Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), Count: 31},
+	}}
+
+	alerts := summaryBalance.ToMap(WithLocale("es"))["Alerts"].([]alert)
+	assert.Equal(t, "3,5 desviaciones estándar por encima del débito promedio de $ 120,50", alerts[0].Detail)
+	assert.Equal(t, "Primer débito mayor a $ 1.000,00", alerts[1].Detail)
+	assert.Equal(t, "31 transacciones el 2024-03-05", alerts[2].Detail)
+
+	// without a locale the details are rendered in the default one
+	alerts = summaryBalance.ToMap()["Alerts"].([]alert)
+	assert.Equal(t, "3.5 standard deviations above the average debit of $120.50", alerts[0].Detail)
+}
@@ -0,0 +1,185 @@
+package balances
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// ModeRefresh the materialized views are refreshed after every import
+	ModeRefresh = "refresh"
+	// ModeIncremental the balance totals are updated in the same transaction as the insert
+	ModeIncremental = "incremental"
+
+	BalanceTotalsTable = "balance_totals"
+	DailyTotalsTable   = "daily_totals"
+)
+
+// totalsSelect aggregates the transactions the same way the materialized views do.
+const totalsSelect = `
+SELECT account_id,
+       TO_CHAR(date, 'MM')                                          AS month,
+       COALESCE(SUM(CASE WHEN type = 'credit' THEN amount END), 0) AS credit_sum,
+       COUNT(CASE WHEN type = 'credit' THEN 1 END)                  AS credit_count,
+       COALESCE(SUM(CASE WHEN type = 'debit' THEN amount END), 0)  AS debit_sum,
+       COUNT(CASE WHEN type = 'debit' THEN 1 END)                   AS debit_count
+FROM transactions`
+
+// the rows are upserted ordered by key so concurrent imports lock them in the same order
+const upsertTotals = `
+INSERT INTO balance_totals (account_id, month, credit_sum, credit_count, debit_sum, debit_count)
+%s
+%s
+GROUP BY account_id, TO_CHAR(date, 'MM')
+ORDER BY account_id, month
+ON CONFLICT (account_id, month) DO UPDATE SET
+    credit_sum   = balance_totals.credit_sum + EXCLUDED.credit_sum,
+    credit_count = balance_totals.credit_count + EXCLUDED.credit_count,
+    debit_sum    = balance_totals.debit_sum + EXCLUDED.debit_sum,
+    debit_count  = balance_totals.debit_count + EXCLUDED.debit_count`
+
+// dailySelect aggregates the transactions the same way the daily_balances view does.
+const dailySelect = `
+SELECT account_id,
+       DATE(date)                                            AS day,
+       SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END) AS credits,
+       SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END)  AS debits,
+       COUNT(*)                                              AS transaction_count
+FROM transactions`
+
+const upsertDailyTotals = `
+INSERT INTO daily_totals (account_id, day, credits, debits, transaction_count)
+%s
+%s
+GROUP BY account_id, DATE(date)
+ORDER BY account_id, day
+ON CONFLICT (account_id, day) DO UPDATE SET
+    credits           = daily_totals.credits + EXCLUDED.credits,
+    debits            = daily_totals.debits + EXCLUDED.debits,
+    transaction_count = daily_totals.transaction_count + EXCLUDED.transaction_count`
+
+const checkTotals = `
+WITH expected AS (%s
+    GROUP BY account_id, TO_CHAR(date, 'MM'))
+SELECT COALESCE(e.account_id, t.account_id) AS account_id,
+       COALESCE(e.month, t.month)           AS month,
+       COALESCE(e.credit_sum, 0)            AS expected_credit_sum,
+       COALESCE(t.credit_sum, 0)            AS actual_credit_sum,
+       COALESCE(e.debit_sum, 0)             AS expected_debit_sum,
+       COALESCE(t.debit_sum, 0)             AS actual_debit_sum,
+       COALESCE(e.credit_count, 0) + COALESCE(e.debit_count, 0) AS expected_count,
+       COALESCE(t.credit_count, 0) + COALESCE(t.debit_count, 0) AS actual_count
+FROM expected e
+         FULL OUTER JOIN balance_totals t ON t.account_id = e.account_id AND t.month = e.month
+WHERE e.account_id IS NULL
+   OR t.account_id IS NULL
+   OR e.credit_sum <> t.credit_sum
+   OR e.credit_count <> t.credit_count
+   OR e.debit_sum <> t.debit_sum
+   OR e.debit_count <> t.debit_count
+ORDER BY 1, 2`
+
+const checkDailyTotals = `
+WITH expected AS (%s
+    GROUP BY account_id, DATE(date))
+SELECT COALESCE(e.account_id, t.account_id)                   AS account_id,
+       TO_CHAR(COALESCE(e.day, t.day), 'YYYY-MM-DD')          AS day,
+       COALESCE(e.credits, 0)                                 AS expected_credit_sum,
+       COALESCE(t.credits, 0)                                 AS actual_credit_sum,
+       COALESCE(e.debits, 0)                                  AS expected_debit_sum,
+       COALESCE(t.debits, 0)                                  AS actual_debit_sum,
+       COALESCE(e.transaction_count, 0)                       AS expected_count,
+       COALESCE(t.transaction_count, 0)                       AS actual_count
+FROM expected e
+         FULL OUTER JOIN daily_totals t ON t.account_id = e.account_id AND t.day = e.day
+WHERE e.account_id IS NULL
+   OR t.account_id IS NULL
+   OR e.credits <> t.credits
+   OR e.debits <> t.debits
+   OR e.transaction_count <> t.transaction_count
+ORDER BY 1, 2`
+
+// IncrementalBalances keeps balance_totals and daily_totals up to date without recalculating every account.
+type IncrementalBalances struct {
+	DB  *gorm.DB
+	Log *zap.Logger
+}
+
+func NewIncrementalBalances(DB *gorm.DB, log *zap.Logger) *IncrementalBalances {
+	return &IncrementalBalances{DB: DB, Log: log}
+}
+
+// Apply adds the transactions inserted by tx to the totals, it must run in the same DB transaction as the insert.
+// xmin is used to skip the rows that already existed and were ignored by the insert.
+func (ib *IncrementalBalances) Apply(tx *gorm.DB, externalIDs []string) error {
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	where := "WHERE external_id IN ? AND xmin = pg_current_xact_id()::xid"
+	if err := tx.Exec(fmt.Sprintf(upsertTotals, totalsSelect, where), externalIDs).Error; err != nil {
+		return fmt.Errorf("error updating balance totals: %w", err)
+	}
+
+	if err := tx.Exec(fmt.Sprintf(upsertDailyTotals, dailySelect, where), externalIDs).Error; err != nil {
+		return fmt.Errorf("error updating daily totals: %w", err)
+	}
+
+	return nil
+}
+
+// Rebuild recalculates balance_totals and daily_totals from scratch, it is meant to repair the totals.
+func (ib *IncrementalBalances) Rebuild(ctx context.Context) error {
+	ib.Log.Info("rebuilding balance totals")
+
+	return ib.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE transactions IN SHARE MODE").Error; err != nil {
+			return fmt.Errorf("error locking transactions: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM " + BalanceTotalsTable).Error; err != nil {
+			return fmt.Errorf("error deleting balance totals: %w", err)
+		}
+
+		if err := tx.Exec(fmt.Sprintf(upsertTotals, totalsSelect, "")).Error; err != nil {
+			return fmt.Errorf("error rebuilding balance totals: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM " + DailyTotalsTable).Error; err != nil {
+			return fmt.Errorf("error deleting daily totals: %w", err)
+		}
+
+		if err := tx.Exec(fmt.Sprintf(upsertDailyTotals, dailySelect, "")).Error; err != nil {
+			return fmt.Errorf("error rebuilding daily totals: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Check compares balance_totals and daily_totals against the transactions and returns the periods that differ.
+func (ib *IncrementalBalances) Check(ctx context.Context) ([]dtos.BalanceMismatch, error) {
+	var mismatches []dtos.BalanceMismatch
+
+	err := ib.DB.WithContext(ctx).Raw(fmt.Sprintf(checkTotals, totalsSelect)).Scan(&mismatches).Error
+	if err != nil {
+		return nil, fmt.Errorf("error checking balance totals: %w", err)
+	}
+
+	var days []dtos.BalanceMismatch
+	err = ib.DB.WithContext(ctx).Raw(fmt.Sprintf(checkDailyTotals, dailySelect)).Scan(&days).Error
+	if err != nil {
+		return nil, fmt.Errorf("error checking daily totals: %w", err)
+	}
+	mismatches = append(mismatches, days...)
+
+	if len(mismatches) > 0 {
+		ib.Log.Warn("balance totals are inconsistent", zap.Int("mismatches", len(mismatches)))
+	}
+
+	return mismatches, nil
+}
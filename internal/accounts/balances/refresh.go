@@ -2,39 +2,76 @@ package balances
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/juaguz/storid/internal/accounts/models"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-var views = make([]string, 0, 2)
+const (
+	RefreshSucceeded = "succeeded"
+	RefreshFailed    = "failed"
+)
 
 type BalanceRefresher struct {
-	DB  *gorm.DB
-	Log *zap.Logger
+	DB    *gorm.DB
+	Log   *zap.Logger
+	Views []string
 }
 
 func NewBalanceRefresher(DB *gorm.DB, log *zap.Logger) *BalanceRefresher {
-	views = append(views, models.MonthlyBalanceTable, models.BalancesTable)
-	return &BalanceRefresher{DB: DB, Log: log}
+	return &BalanceRefresher{
+		DB:    DB,
+		Log:   log,
+		Views: []string{models.MonthlyBalanceTable, models.BalancesTable, models.DailyBalanceTable},
+	}
 }
 
+// Refresh refreshes the views one after another, each one with REFRESH ... CONCURRENTLY so the readers
+// are not locked, a failing view does not prevent the others from being refreshed.
 func (b *BalanceRefresher) Refresh(ctx context.Context) error {
-	b.Log.Info("refreshing views", zap.Int("views", len(views)))
-	for _, view := range views {
-		b.Log.Info("refreshing view", zap.String("view", view))
-		sql := fmt.Sprintf("REFRESH MATERIALIZED VIEW %s", view)
-		if err := b.DB.Exec(sql).Error; err != nil {
-			b.Log.Error("refreshing views", zap.Error(err), zap.String("view", view))
+	b.Log.Info("refreshing views", zap.Int("views", len(b.Views)))
+
+	var errs []error
+	for _, view := range b.Views {
+		if err := b.refreshView(ctx, view); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+
+	return errors.Join(errs...)
 }
 
-func (b *BalanceRefresher) Handle(ctx context.Context, event string, payload []byte) {
-	if err := b.Refresh(ctx); err != nil {
-		b.Log.Error("error refreshing views", zap.Error(err))
+func (b *BalanceRefresher) refreshView(ctx context.Context, view string) error {
+	b.Log.Info("refreshing view", zap.String("view", view))
+
+	startedAt := time.Now()
+	sql := fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", view)
+	err := b.DB.WithContext(ctx).Exec(sql).Error
+
+	history := models.RefreshHistory{
+		View:       view,
+		StartedAt:  startedAt,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+		Status:     RefreshSucceeded,
 	}
+	if err != nil {
+		b.Log.Error("refreshing view", zap.Error(err), zap.String("view", view))
+		history.Status = RefreshFailed
+		history.Error = err.Error()
+		err = fmt.Errorf("error refreshing view %s: %w", view, err)
+	}
+
+	if historyErr := b.DB.WithContext(ctx).Create(&history).Error; historyErr != nil {
+		b.Log.Error("saving refresh history", zap.Error(historyErr), zap.String("view", view))
+	}
+
+	return err
+}
+
+func (b *BalanceRefresher) Handle(ctx context.Context, event string, payload []byte) error {
+	return b.Refresh(ctx)
 }
@@ -3,20 +3,54 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/juaguz/storid/internal/accounts/balances/dtos"
 	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/transactions/dto"
 	"github.com/juaguz/storid/internal/platform/months"
 	"gorm.io/gorm"
 )
 
+// defaultTopCategories amount of categories shown in the summary before grouping the rest as "other"
+const defaultTopCategories = 5
+
+// incremental views read balance_totals but expose the same columns as the materialized views
+const (
+	incrementalBalancesView        = "incremental_balances"
+	incrementalMonthlyBalancesView = "incremental_monthly_balances"
+	incrementalDailyBalancesView   = "incremental_daily_balances"
+)
+
 type BalancesDBRepository struct {
-	DB *gorm.DB
+	DB                  *gorm.DB
+	TopCategories       int
+	BalancesTable       string
+	MonthlyBalanceTable string
+	DailyBalanceTable   string
+	// DailyBalanceDays amount of days of daily balances added to the summary, 0 disables them
+	DailyBalanceDays int
 }
 
 func NewBalancesRepository(db *gorm.DB) *BalancesDBRepository {
 	return &BalancesDBRepository{
-		DB: db,
+		DB:                  db,
+		TopCategories:       defaultTopCategories,
+		BalancesTable:       models.BalancesTable,
+		MonthlyBalanceTable: models.MonthlyBalanceTable,
+		DailyBalanceTable:   models.DailyBalanceTable,
+	}
+}
+
+// NewIncrementalBalancesRepository reads the balances maintained incrementally by the importer.
+func NewIncrementalBalancesRepository(db *gorm.DB) *BalancesDBRepository {
+	return &BalancesDBRepository{
+		DB:                  db,
+		TopCategories:       defaultTopCategories,
+		BalancesTable:       incrementalBalancesView,
+		MonthlyBalanceTable: incrementalMonthlyBalancesView,
+		DailyBalanceTable:   incrementalDailyBalancesView,
 	}
 }
 
@@ -25,7 +59,7 @@ func NewBalancesRepository(db *gorm.DB) *BalancesDBRepository {
 // I would use a Criteria pattern to filter the results.
 func (br *BalancesDBRepository) GetBalanceByAccountID(_ context.Context, accountID uint) (*models.Balance, error) {
 	var balance models.Balance
-	err := br.DB.Where("account_id = ?", accountID).Find(&balance).Error
+	err := br.DB.Table(br.BalancesTable).Where("account_id = ?", accountID).Find(&balance).Error
 	if err != nil {
 		return nil, fmt.Errorf("error getting balance by account ID: %w", err)
 	}
@@ -36,7 +70,7 @@ func (br *BalancesDBRepository) GetBalanceByAccountID(_ context.Context, account
 // GetMonthlyBalancesByAccountID returns the monthly balances for the given account ID.
 func (br *BalancesDBRepository) GetMonthlyBalancesByAccountID(_ context.Context, accountID uint) (map[months.Month]*dtos.Balance, error) {
 	var balances []models.MonthlyBalance
-	err := br.DB.Where("account_id = ?", accountID).Order("month").Find(&balances).Error
+	err := br.DB.Table(br.MonthlyBalanceTable).Where("account_id = ?", accountID).Order("month").Find(&balances).Error
 	if err != nil {
 		return nil, err
 	}
@@ -57,14 +91,56 @@ func (br *BalancesDBRepository) GetMonthlyBalancesByAccountID(_ context.Context,
 	return monthlyBalances, nil
 }
 
-func (br *BalancesDBRepository) GetSummaryBalance(_ context.Context) (map[uint]*dtos.SummaryBalance, error) {
+// EachSummaryPage computes the summaries of pageSize accounts at a time, the accounts are paginated by ID
+// so the memory used does not grow with the amount of accounts.
+func (br *BalancesDBRepository) EachSummaryPage(ctx context.Context, selector dtos.AccountSelector, pageSize int, fn func(summaries map[uint]*dtos.SummaryBalance) error) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("invalid page size %d", pageSize)
+	}
+
+	var after uint
+	for {
+		var accountIDs []uint
+		err := br.DB.WithContext(ctx).Table(br.BalancesTable).
+			Scopes(selectAccounts("account_id", selector)).
+			Where("account_id > ?", after).
+			Order("account_id").
+			Limit(pageSize).
+			Pluck("account_id", &accountIDs).Error
+		if err != nil {
+			return fmt.Errorf("error getting accounts after %d: %w", after, err)
+		}
+
+		if len(accountIDs) == 0 {
+			return nil
+		}
+
+		summaries, err := br.GetSummaryBalance(ctx, dtos.AccountSelector{AccountIDs: accountIDs})
+		if err != nil {
+			return err
+		}
+
+		if err := fn(summaries); err != nil {
+			return err
+		}
+
+		if len(accountIDs) < pageSize {
+			return nil
+		}
+		after = accountIDs[len(accountIDs)-1]
+	}
+}
+
+// GetSummaryBalance only computes the summaries of the accounts picked by the selector.
+func (br *BalancesDBRepository) GetSummaryBalance(ctx context.Context, selector dtos.AccountSelector) (map[uint]*dtos.SummaryBalance, error) {
 	var results []struct {
 		models.Balance
 		models.MonthlyBalance
 	}
 
-	err := br.DB.Table(models.BalancesTable).
-		Joins("INNER JOIN monthly_balances a ON a.account_id = balances.account_id").
+	err := br.DB.WithContext(ctx).Table(fmt.Sprintf("%s AS balances", br.BalancesTable)).
+		Joins(fmt.Sprintf("INNER JOIN %s a ON a.account_id = balances.account_id", br.MonthlyBalanceTable)).
+		Scopes(selectAccounts("balances.account_id", selector)).
 		Scan(&results).Error
 
 	if err != nil {
@@ -105,5 +181,184 @@ func (br *BalancesDBRepository) GetSummaryBalance(_ context.Context) (map[uint]*
 		d.MonthlyBalance[months.Month(mb.Month)] = mb
 	}
 
+	spending, err := br.getCategorySpending(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	for accountID, categories := range spending {
+		if d, ok := balances[accountID]; ok {
+			d.Categories = dtos.TopCategories(categories, br.TopCategories)
+		}
+	}
+
+	if br.DailyBalanceDays > 0 {
+		to := time.Now()
+		from := to.AddDate(0, 0, -br.DailyBalanceDays+1)
+
+		var accountIDs []uint
+		if !selector.All() {
+			accountIDs = make([]uint, 0, len(balances))
+			for accountID := range balances {
+				accountIDs = append(accountIDs, accountID)
+			}
+		}
+
+		daily, err := br.getDailyBalances(ctx, accountIDs, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for accountID, days := range daily {
+			if d, ok := balances[accountID]; ok {
+				d.DailyBalances = days
+			}
+		}
+	}
+
 	return balances, nil
 }
+
+// GetDailyBalances returns the end of day balance of the account for every day between from and to.
+func (br *BalancesDBRepository) GetDailyBalances(ctx context.Context, accountID uint, from, to time.Time) ([]*dtos.DailyBalance, error) {
+	daily, err := br.getDailyBalances(ctx, []uint{accountID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if days, ok := daily[accountID]; ok {
+		return days, nil
+	}
+
+	return dtos.FillDailyBalances(0, nil, from, to), nil
+}
+
+// getDailyBalances returns the daily balances indexed by account, all the accounts are returned when accountIDs is empty.
+func (br *BalancesDBRepository) getDailyBalances(ctx context.Context, accountIDs []uint, from, to time.Time) (map[uint][]*dtos.DailyBalance, error) {
+	byAccount := func(db *gorm.DB) *gorm.DB {
+		if len(accountIDs) > 0 {
+			return db.Where("account_id IN ?", accountIDs)
+		}
+		return db
+	}
+
+	// the opening balance is the last balance before the range
+	var openings []models.DailyBalance
+	err := br.DB.WithContext(ctx).Table(br.DailyBalanceTable).
+		Scopes(byAccount).
+		Select("DISTINCT ON (account_id) account_id, balance").
+		Where("day < ?", from.Format(time.DateOnly)).
+		Order("account_id, day DESC").
+		Find(&openings).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting opening balances: %w", err)
+	}
+
+	var records []models.DailyBalance
+	err = br.DB.WithContext(ctx).Table(br.DailyBalanceTable).
+		Scopes(byAccount).
+		Where("day BETWEEN ? AND ?", from.Format(time.DateOnly), to.Format(time.DateOnly)).
+		Order("account_id, day").
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting daily balances: %w", err)
+	}
+
+	opening := make(map[uint]int, len(openings))
+	for _, o := range openings {
+		opening[o.AccountID] = o.Balance
+	}
+
+	days := make(map[uint][]*dtos.DailyBalance)
+	for _, r := range records {
+		days[r.AccountID] = append(days[r.AccountID], &dtos.DailyBalance{
+			Day:              r.Day,
+			Credits:          r.Credits,
+			Debits:           r.Debits,
+			TransactionCount: r.TransactionCount,
+			Balance:          r.Balance,
+		})
+	}
+
+	daily := make(map[uint][]*dtos.DailyBalance)
+	for accountID, balance := range opening {
+		daily[accountID] = dtos.FillDailyBalances(balance, days[accountID], from, to)
+	}
+	for accountID, d := range days {
+		if _, ok := daily[accountID]; !ok {
+			daily[accountID] = dtos.FillDailyBalances(0, d, from, to)
+		}
+	}
+
+	return daily, nil
+}
+
+// getCategorySpending returns the debited amount per account and category.
+func (br *BalancesDBRepository) getCategorySpending(ctx context.Context, selector dtos.AccountSelector) (map[uint]map[string]int, error) {
+	var results []struct {
+		AccountID uint
+		Category  string
+		Amount    int
+	}
+
+	// transactions imported before the category column existed have no category
+	category := fmt.Sprintf("COALESCE(NULLIF(category, ''), '%s')", dto.Uncategorized)
+
+	err := br.DB.WithContext(ctx).Model(&models.Transaction{}).
+		Select(fmt.Sprintf("account_id, %s AS category, SUM(amount) AS amount", category)).
+		Where("type = ?", dto.Debit).
+		Scopes(selectAccounts("account_id", selector)).
+		Group("account_id, " + category).
+		Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting category spending: %w", err)
+	}
+
+	spending := make(map[uint]map[string]int)
+	for _, r := range results {
+		if _, ok := spending[r.AccountID]; !ok {
+			spending[r.AccountID] = make(map[string]int)
+		}
+		spending[r.AccountID][r.Category] = r.Amount
+	}
+
+	return spending, nil
+}
+
+// selectAccounts filters column, an account ID, by the selector.
+func selectAccounts(column string, selector dtos.AccountSelector) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(selector.AccountIDs) > 0 || len(selector.Ranges) > 0 {
+			var conditions []string
+			var args []interface{}
+			if len(selector.AccountIDs) > 0 {
+				conditions = append(conditions, column+" IN ?")
+				args = append(args, selector.AccountIDs)
+			}
+			for _, r := range selector.Ranges {
+				conditions = append(conditions, column+" BETWEEN ? AND ?")
+				args = append(args, r.From, r.To)
+			}
+			db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+		}
+
+		if !selector.ActiveSince.IsZero() || !selector.ActiveUntil.IsZero() {
+			active := db.Session(&gorm.Session{NewDB: true}).Model(&models.Transaction{}).Select("account_id")
+			if !selector.ActiveSince.IsZero() {
+				active = active.Where("date >= ?", selector.ActiveSince)
+			}
+			if !selector.ActiveUntil.IsZero() {
+				// ActiveUntil is a day, the transactions later than its midnight are included
+				active = active.Where("date < ?", selector.ActiveUntil.AddDate(0, 0, 1))
+			}
+			db = db.Where(column+" IN (?)", active)
+		}
+
+		if !selector.CreatedAfter.IsZero() {
+			created := db.Session(&gorm.Session{NewDB: true}).Model(&models.Account{}).Select("id").Where("created_at > ?", selector.CreatedAfter)
+			db = db.Where(column+" IN (?)", created)
+		}
+
+		return db
+	}
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB nothing listens on the port, the statements are only built.
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 user=test dbname=test sslmode=disable connect_timeout=1"), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestSelectAccounts_Active(t *testing.T) {
+	db := openTestDB(t)
+	selector := dtos.AccountSelector{
+		ActiveSince: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		ActiveUntil: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var accountIDs []uint
+		return tx.Model(&models.Account{}).Scopes(selectAccounts("id", selector)).Pluck("id", &accountIDs)
+	})
+
+	// the transactions of the whole ActiveUntil day are included, not only the ones at its midnight
+	assert.Contains(t, sql, "date >= '2024-03-01 00:00:00'")
+	assert.Contains(t, sql, "date < '2024-03-16 00:00:00'")
+	assert.NotContains(t, sql, "date <=")
+}
@@ -0,0 +1,91 @@
+package summary
+
+import (
+	"context"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	"github.com/juaguz/storid/internal/platform/charts"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/juaguz/storid/internal/platform/notifications"
+)
+
+// the charts are drawn at the width of the email body
+const (
+	ChartWidth  = 560
+	ChartHeight = 160
+)
+
+const (
+	MonthlyBalanceChartFilename = "monthly-balance.png"
+	CreditDebitChartFilename    = "credits-debits.png"
+)
+
+// MonthlyBalanceChart embeds the net balance of every month in the summary email, the months
+// with a negative balance are drawn in red.
+type MonthlyBalanceChart struct{}
+
+func NewMonthlyBalanceChart() *MonthlyBalanceChart {
+	return &MonthlyBalanceChart{}
+}
+
+// Build returns nil for the statements and for the summaries without monthly balances.
+func (mc *MonthlyBalanceChart) Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error) {
+	if summary.Statement != nil || len(summary.MonthlyBalance) == 0 {
+		return nil, nil
+	}
+
+	// same months and order as the MonthlyBalance of ToMap, the template labels the bars with them
+	var balances []int
+	for _, month := range months.OrderMonths {
+		if balance, ok := summary.MonthlyBalance[month]; ok {
+			balances = append(balances, balance.TotalBalance)
+		}
+	}
+
+	chart := charts.NewBarChart(ChartWidth, ChartHeight, charts.Series{Values: balances, Color: charts.Blue, NegativeColor: charts.Red})
+	return inlineChart(MonthlyBalanceChartFilename, chart)
+}
+
+// CreditDebitChart embeds the credits and the debits of every day of the daily balances in the
+// summary email, the credits go up and the debits down.
+type CreditDebitChart struct{}
+
+func NewCreditDebitChart() *CreditDebitChart {
+	return &CreditDebitChart{}
+}
+
+// Build returns nil for the statements and for the summaries without daily balances.
+func (cc *CreditDebitChart) Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error) {
+	if summary.Statement != nil || len(summary.DailyBalances) == 0 {
+		return nil, nil
+	}
+
+	credits := make([]int, 0, len(summary.DailyBalances))
+	debits := make([]int, 0, len(summary.DailyBalances))
+	for _, day := range summary.DailyBalances {
+		credits = append(credits, day.Credits)
+		debits = append(debits, day.Debits)
+	}
+
+	chart := charts.NewBarChart(ChartWidth, ChartHeight,
+		charts.Series{Values: credits, Color: charts.Green},
+		charts.Series{Values: debits, Color: charts.Red},
+	)
+	return inlineChart(CreditDebitChartFilename, chart)
+}
+
+// inlineChart the template finds the Content-ID of the chart by its filename.
+func inlineChart(filename string, chart *charts.BarChart) (*notifications.Attachment, error) {
+	content, err := chart.PNG()
+	if err != nil {
+		return nil, err
+	}
+
+	return &notifications.Attachment{
+		Filename:    filename,
+		ContentType: "image/png",
+		Content:     content,
+		ContentID:   filename + "@storid",
+	}, nil
+}
@@ -0,0 +1,61 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailSender_SendCharts(t *testing.T) {
+	service := &MockEmailService{}
+	sender := NewEmailSender(&MockAccountRepository{}, service, []AttachmentBuilder{NewMonthlyBalanceChart(), NewCreditDebitChart()}, nil)
+
+	summary := &dtos.SummaryBalance{
+		MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+			months.February: {Balance: dtos.Balance{TotalBalance: 5000}},
+			months.March:    {Balance: dtos.Balance{TotalBalance: -2000}},
+		},
+		DailyBalances: []*dtos.DailyBalance{
+			{Day: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Credits: 1000, Debits: -400},
+		},
+	}
+
+	_, err := sender.Send(context.Background(), 1, summary)
+	assert.NoError(t, err)
+
+	assert.Len(t, service.attachments, 2)
+	for _, attachment := range service.attachments {
+		assert.Equal(t, "image/png", attachment.ContentType)
+		img, err := png.Decode(bytes.NewReader(attachment.Content))
+		assert.NoError(t, err)
+		assert.Equal(t, ChartWidth, img.Bounds().Dx())
+		assert.Equal(t, ChartHeight, img.Bounds().Dy())
+	}
+	assert.Equal(t, map[string]string{
+		MonthlyBalanceChartFilename: "monthly-balance.png@storid",
+		CreditDebitChartFilename:    "credits-debits.png@storid",
+	}, service.variables["Inline"])
+
+	// the statements and the alerts are sent without charts
+	_, err = sender.Send(context.Background(), 1, &dtos.SummaryBalance{MonthlyBalance: summary.MonthlyBalance, Statement: &statementdtos.Statement{}})
+	assert.NoError(t, err)
+	assert.Empty(t, service.attachments)
+
+	_, err = sender.Send(context.Background(), 1, &dtos.SummaryBalance{DailyBalances: summary.DailyBalances, Alerts: []*analysisdtos.Alert{{}}})
+	assert.NoError(t, err)
+	assert.Empty(t, service.attachments)
+
+	// without balances there is nothing to draw
+	_, err = sender.Send(context.Background(), 1, &dtos.SummaryBalance{})
+	assert.NoError(t, err)
+	assert.Empty(t, service.attachments)
+	assert.Empty(t, service.variables["Inline"])
+}
@@ -0,0 +1,364 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/juaguz/storid/internal/platform/i18n"
+)
+
+const DefaultChatTimeout = 10 * time.Second
+
+type ChatRepository interface {
+	GetChatChannels(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.ChatChannel, error)
+}
+
+// ChatSender posts the summaries to the Slack or Teams channel of the account through its incoming webhook,
+// it is meant for internal accounts (e.g. treasury) followed by a team instead of a person.
+type ChatSender struct {
+	AccountRepository AccountRepository
+	ChatRepository    ChatRepository
+	Client            *http.Client
+}
+
+func NewChatSender(accounts AccountRepository, channels ChatRepository, timeout time.Duration) *ChatSender {
+	if timeout <= 0 {
+		timeout = DefaultChatTimeout
+	}
+
+	return &ChatSender{
+		AccountRepository: accounts,
+		ChatRepository:    channels,
+		Client:            newWebhookClient(timeout),
+	}
+}
+
+func (cs *ChatSender) Channel() string {
+	return notificationdtos.ChannelChat
+}
+
+// HasRecipients the accounts without a chat channel are skipped.
+func (cs *ChatSender) HasRecipients(ctx context.Context, accountIDs []uint) (map[uint]bool, error) {
+	channels, err := cs.ChatRepository.GetChatChannels(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[uint]bool, len(channels))
+	for accountID := range channels {
+		recipients[accountID] = true
+	}
+	return recipients, nil
+}
+
+// Send the incoming webhooks do not identify the messages, the returned ID is generated and is the same on every retry.
+// The 429 and 5xx responses are transient so the sender retries them.
+func (cs *ChatSender) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	channels, err := cs.ChatRepository.GetChatChannels(ctx, []uint{accountID})
+	if err != nil {
+		return "", err
+	}
+
+	channel, ok := channels[accountID]
+	if !ok {
+		return "", fmt.Errorf("account %d has no chat channel", accountID)
+	}
+
+	act, err := cs.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return "", fmt.Errorf("error getting account by ID: %w", err)
+	}
+
+	message, err := NewChatMessage(act, summary)
+	if err != nil {
+		return "", err
+	}
+
+	var payload interface{}
+	switch channel.Format {
+	case notificationdtos.ChatFormatSlack, "":
+		payload = message.Slack()
+	case notificationdtos.ChatFormatTeams:
+		payload = message.Teams()
+	default:
+		return "", fmt.Errorf("unknown chat format %q of account %d", channel.Format, accountID)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error encoding chat message: %w", err)
+	}
+
+	if err := ValidateWebhookURL(channel.URL); err != nil {
+		return "", err
+	}
+
+	id, err := notificationID(ctx, accountID, cs.Channel())
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cs.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error posting chat message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return id, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return "", Transient(fmt.Errorf("chat webhook responded %d", resp.StatusCode))
+	default:
+		return "", fmt.Errorf("chat webhook responded %d", resp.StatusCode)
+	}
+}
+
+type ChatFact struct {
+	Title string
+	Value string
+}
+
+type ChatSection struct {
+	Title string
+	Facts []ChatFact
+}
+
+// ChatMessage is the summary rendered for a chat channel, Slack and Teams format it as blocks or as a card.
+type ChatMessage struct {
+	Title    string
+	Subtitle string
+	Facts    []ChatFact
+	Sections []ChatSection
+}
+
+// chatSummary is read from the ToMap data of the summary so the chat shows the same amounts as the emails.
+type chatSummary struct {
+	TotalBalance     string
+	AvrDebitAmount   string
+	AvrCreditAmount  string
+	TransactionCount int
+	MonthlyBalance   []struct {
+		Month string
+		Count int
+	}
+	Categories []struct {
+		Category   string
+		Amount     string
+		Percentage string
+	}
+	Alerts []struct {
+		Date   string
+		Amount string
+		Detail string
+	}
+	Statement *struct {
+		Sequence       int    `json:"sequence"`
+		PeriodStart    string `json:"period_start"`
+		PeriodEnd      string `json:"period_end"`
+		OpeningBalance string `json:"opening_balance"`
+		ClosingBalance string `json:"closing_balance"`
+		TotalCredits   string `json:"total_credits"`
+		TotalDebits    string `json:"total_debits"`
+		CreditCount    int    `json:"credit_count"`
+		DebitCount     int    `json:"debit_count"`
+	}
+}
+
+// NewChatMessage renders the alert, the statement or the summary in the locale of the account.
+func NewChatMessage(account *accountdtos.Account, summary *dtos.SummaryBalance) (*ChatMessage, error) {
+	data, err := json.Marshal(summary.ToMap(dtos.WithLocale(account.Locale)))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding chat summary: %w", err)
+	}
+
+	var s chatSummary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error decoding chat summary: %w", err)
+	}
+
+	locale := account.Locale
+	message := &ChatMessage{Subtitle: account.FullName()}
+
+	switch {
+	case len(s.Alerts) > 0:
+		message.Title = i18n.T(locale, "subject_alert")
+		section := ChatSection{Title: i18n.T(locale, "unusual_activity")}
+		for _, a := range s.Alerts {
+			section.Facts = append(section.Facts, ChatFact{Title: a.Date, Value: strings.TrimSpace(a.Detail + " " + a.Amount)})
+		}
+		message.Sections = append(message.Sections, section)
+	case s.Statement != nil:
+		st := s.Statement
+		message.Title = i18n.T(locale, "subject_statement", st.Sequence)
+		message.Subtitle += " · " + i18n.T(locale, "statement_period", st.Sequence, st.PeriodStart, st.PeriodEnd)
+		message.Facts = []ChatFact{
+			{Title: i18n.T(locale, "opening_balance"), Value: st.OpeningBalance},
+			{Title: i18n.T(locale, "credits_count", st.CreditCount), Value: st.TotalCredits},
+			{Title: i18n.T(locale, "debits_count", st.DebitCount), Value: st.TotalDebits},
+			{Title: i18n.T(locale, "closing_balance"), Value: st.ClosingBalance},
+		}
+	default:
+		message.Title = i18n.T(locale, "subject_summary")
+		message.Facts = []ChatFact{
+			{Title: i18n.T(locale, "total_balance"), Value: s.TotalBalance},
+			{Title: i18n.T(locale, "avg_debit_amount"), Value: s.AvrDebitAmount},
+			{Title: i18n.T(locale, "avg_credit_amount"), Value: s.AvrCreditAmount},
+			{Title: i18n.T(locale, "transactions"), Value: strconv.Itoa(s.TransactionCount)},
+		}
+		if len(s.MonthlyBalance) > 0 {
+			section := ChatSection{Title: i18n.T(locale, "monthly_transactions")}
+			for _, m := range s.MonthlyBalance {
+				section.Facts = append(section.Facts, ChatFact{Title: m.Month, Value: strconv.Itoa(m.Count)})
+			}
+			message.Sections = append(message.Sections, section)
+		}
+		if len(s.Categories) > 0 {
+			section := ChatSection{Title: i18n.T(locale, "spending_by_category")}
+			for _, c := range s.Categories {
+				section.Facts = append(section.Facts, ChatFact{Title: c.Category, Value: c.Amount + " (" + c.Percentage + ")"})
+			}
+			message.Sections = append(message.Sections, section)
+		}
+	}
+
+	return message, nil
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackEscape the mrkdwn texts use &, < and > for links and mentions.
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Slack returns the Block Kit payload, Text is the fallback shown in the notifications.
+func (m *ChatMessage) Slack() interface{} {
+	message := &slackMessage{
+		Text: slackEscape.Replace(m.Title + " - " + m.Subtitle),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: m.Title}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: slackEscape.Replace(m.Subtitle)}}},
+		},
+	}
+
+	if len(m.Facts) > 0 {
+		block := slackBlock{Type: "section"}
+		for _, f := range m.Facts {
+			block.Fields = append(block.Fields, slackText{Type: "mrkdwn", Text: "*" + slackEscape.Replace(f.Title) + "*\n" + slackEscape.Replace(f.Value)})
+		}
+		message.Blocks = append(message.Blocks, block)
+	}
+
+	for _, s := range m.Sections {
+		lines := []string{"*" + slackEscape.Replace(s.Title) + "*"}
+		for _, f := range s.Facts {
+			lines = append(lines, slackEscape.Replace(f.Title+": "+f.Value))
+		}
+		message.Blocks = append(message.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}})
+	}
+
+	return message
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []cardElement `json:"body"`
+}
+
+type cardElement struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text,omitempty"`
+	Size     string     `json:"size,omitempty"`
+	Weight   string     `json:"weight,omitempty"`
+	IsSubtle bool       `json:"isSubtle,omitempty"`
+	Wrap     bool       `json:"wrap,omitempty"`
+	Facts    []cardFact `json:"facts,omitempty"`
+}
+
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Teams returns a message with an Adaptive Card, the payload accepted by the Teams incoming webhooks and workflows.
+func (m *ChatMessage) Teams() interface{} {
+	body := []cardElement{
+		{Type: "TextBlock", Text: m.Title, Size: "Large", Weight: "Bolder", Wrap: true},
+		{Type: "TextBlock", Text: m.Subtitle, IsSubtle: true, Wrap: true},
+	}
+
+	if len(m.Facts) > 0 {
+		body = append(body, cardElement{Type: "FactSet", Facts: cardFacts(m.Facts)})
+	}
+
+	for _, s := range m.Sections {
+		body = append(body,
+			cardElement{Type: "TextBlock", Text: s.Title, Weight: "Bolder", Wrap: true},
+			cardElement{Type: "FactSet", Facts: cardFacts(s.Facts)},
+		)
+	}
+
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+}
+
+func cardFacts(facts []ChatFact) []cardFact {
+	result := make([]cardFact, 0, len(facts))
+	for _, f := range facts {
+		result = append(result, cardFact{Title: f.Title, Value: f.Value})
+	}
+	return result
+}
@@ -0,0 +1,158 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockChatRepository struct {
+	channels map[uint]*notificationdtos.ChatChannel
+}
+
+func (m *MockChatRepository) GetChatChannels(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.ChatChannel, error) {
+	channels := make(map[uint]*notificationdtos.ChatChannel)
+	for _, accountID := range accountIDs {
+		if channel, ok := m.channels[accountID]; ok {
+			channels[accountID] = channel
+		}
+	}
+	return channels, nil
+}
+
+// chatServer stands in for the Slack and Teams incoming webhooks.
+func chatServer(t *testing.T, status *int, bodies *[]map[string]interface{}) *httptest.Server {
+	allowLoopbackWebhooks(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		data, _ := io.ReadAll(r.Body)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(data, &body))
+		*bodies = append(*bodies, body)
+
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChatSender_Send(t *testing.T) {
+	status := http.StatusOK
+	var bodies []map[string]interface{}
+	server := chatServer(t, &status, &bodies)
+
+	repository := &MockChatRepository{channels: map[uint]*notificationdtos.ChatChannel{
+		1: {AccountID: 1, URL: server.URL + "/slack", Format: notificationdtos.ChatFormatSlack},
+		2: {AccountID: 2, URL: server.URL + "/teams", Format: notificationdtos.ChatFormatTeams},
+	}}
+	sender := NewChatSender(&MockAccountRepository{}, repository, time.Second)
+
+	summary := &dtos.SummaryBalance{
+		Balance:        dtos.Balance{TotalBalance: 150000, TransactionCount: 12},
+		MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{months.March: {Balance: dtos.Balance{TransactionCount: 12}}},
+	}
+
+	id, err := sender.Send(context.Background(), 1, summary)
+	assert.NoError(t, err)
+	assert.Len(t, id, 32)
+
+	_, err = sender.Send(context.Background(), 2, summary)
+	assert.NoError(t, err)
+
+	assert.Len(t, bodies, 2)
+
+	slack := bodies[0]
+	assert.Equal(t, "Summary Balance - Zoë Müller", slack["text"])
+	blocks := slack["blocks"].([]interface{})
+	assert.Len(t, blocks, 4)
+	assert.Equal(t, "header", blocks[0].(map[string]interface{})["type"])
+	fields := blocks[2].(map[string]interface{})["fields"].([]interface{})
+	assert.Equal(t, "*Total Balance*\n$1,500.00", fields[0].(map[string]interface{})["text"])
+	assert.Equal(t, "*Monthly Transactions*\nMarch: 12", blocks[3].(map[string]interface{})["text"].(map[string]interface{})["text"])
+
+	teams := bodies[1]
+	assert.Equal(t, "message", teams["type"])
+	attachment := teams["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+	card := attachment["content"].(map[string]interface{})
+	assert.Equal(t, "AdaptiveCard", card["type"])
+	body := card["body"].([]interface{})
+	assert.Equal(t, "Summary Balance", body[0].(map[string]interface{})["text"])
+	facts := body[2].(map[string]interface{})["facts"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"title": "Total Balance", "value": "$1,500.00"}, facts[0])
+}
+
+func TestChatSender_SendErrors(t *testing.T) {
+	status := http.StatusTooManyRequests
+	var bodies []map[string]interface{}
+	server := chatServer(t, &status, &bodies)
+
+	repository := &MockChatRepository{channels: map[uint]*notificationdtos.ChatChannel{
+		1: {AccountID: 1, URL: server.URL},
+		2: {AccountID: 2, URL: server.URL, Format: "irc"},
+	}}
+	sender := NewChatSender(&MockAccountRepository{}, repository, time.Second)
+
+	_, err := sender.Send(context.Background(), 1, &dtos.SummaryBalance{})
+	assert.Error(t, err)
+	assert.True(t, IsTransient(err))
+
+	status = http.StatusNotFound
+	_, err = sender.Send(context.Background(), 1, &dtos.SummaryBalance{})
+	assert.Error(t, err)
+	assert.False(t, IsTransient(err))
+
+	_, err = sender.Send(context.Background(), 2, &dtos.SummaryBalance{})
+	assert.Error(t, err)
+
+	_, err = sender.Send(context.Background(), 3, &dtos.SummaryBalance{})
+	assert.Error(t, err)
+
+	assert.Len(t, bodies, 2)
+
+	recipients, err := sender.HasRecipients(context.Background(), []uint{1, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint]bool{1: true}, recipients)
+}
+
+func TestNewChatMessage(t *testing.T) {
+	account := &accountdtos.Account{Name: "Treasury", Email: "treasury@storid.com", Locale: "es"}
+
+	message, err := NewChatMessage(account, &dtos.SummaryBalance{Statement: &statementdtos.Statement{
+		Sequence:       2,
+		PeriodStart:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:      time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 100000,
+		ClosingBalance: 250000,
+		CreditCount:    3,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Extracto de cuenta N.º 2", message.Title)
+	assert.Equal(t, "Treasury · Extracto N.º 2 (2024-03-01 - 2024-03-31)", message.Subtitle)
+	assert.Equal(t, ChatFact{Title: "Saldo final", Value: "$ 2.500,00"}, message.Facts[3])
+
+	account.Name = "<b>Treasury</b>"
+	message, err = NewChatMessage(account, &dtos.SummaryBalance{Alerts: []*analysisdtos.Alert{
+		{Kind: analysisdtos.AlertUnusualAmount, Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), Amount: -50000, ZScore: 4.26, Average: 10000},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, message.Sections, 1)
+	assert.Equal(t, ChatFact{Title: "2024-03-05", Value: "4,3 desviaciones estándar por encima del débito promedio de $ 100,00 -$ 500,00"}, message.Sections[0].Facts[0])
+
+	// the chat markup of the texts is escaped
+	slack := message.Slack().(*slackMessage)
+	assert.Equal(t, "&lt;b&gt;Treasury&lt;/b&gt;", slack.Blocks[1].Elements[0].Text)
+}
@@ -0,0 +1,151 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"sync"
+	"time"
+
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+)
+
+const (
+	// DefaultWorkers amount of notifications sent at the same time.
+	DefaultWorkers = 10
+	// DefaultMaxRetries times a transient failure is retried before the delivery fails.
+	DefaultMaxRetries = 3
+	// DefaultRetryBackoff wait before the first retry, it doubles on every retry.
+	DefaultRetryBackoff = time.Second
+)
+
+type deliveryKey struct{}
+
+// WithDeliveryKey the notifiers derive from the idempotency key of the delivery the IDs that must be the
+// same on every retry e.g. the ID of a webhook.
+func WithDeliveryKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, key)
+}
+
+// DeliveryKey returns the idempotency key of the delivery, empty when the notification is not sent by the Sender.
+func DeliveryKey(ctx context.Context) string {
+	key, _ := ctx.Value(deliveryKey{}).(string)
+	return key
+}
+
+// Report counts the notifications of a run, the skipped ones include the ones already delivered.
+type Report struct {
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+
+	mu sync.Mutex
+}
+
+func (r *Report) add(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch status {
+	case notificationdtos.StatusSent:
+		r.Sent++
+	case notificationdtos.StatusFailed:
+		r.Failed++
+	case notificationdtos.StatusSkipped:
+		r.Skipped++
+	}
+}
+
+// HasFailures the run is a partial failure when some of the notifications failed.
+func (r *Report) HasFailures() bool {
+	return r.Failed > 0
+}
+
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+func (e *transientError) Transient() bool { return true }
+
+// Transient marks the error of a notifier as retryable.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// IsTransient network errors, the SMTP 4xx replies and the errors with a Transient method that returns
+// true are retried, the rest of the errors (e.g. a bad template or a rejected address) would fail again.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var transient interface{ Transient() bool }
+	if errors.As(err, &transient) {
+		return transient.Transient()
+	}
+
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// rateLimiter spaces the calls evenly, a nil limiter does not wait.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter perSecond <= 0 disables the limit.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next call is allowed or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, wait)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
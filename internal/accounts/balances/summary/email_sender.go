@@ -6,6 +6,9 @@ import (
 	"fmt"
 
 	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"github.com/juaguz/storid/internal/platform/notifications"
 
 	"github.com/juaguz/storid/internal/accounts/balances/dtos"
 )
@@ -15,31 +18,135 @@ type AccountRepository interface {
 }
 
 type EmailService interface {
-	Send(to, subject, template string, variables map[string]interface{}) error
+	Send(to, subject, template string, variables map[string]interface{}, attachments ...notifications.Attachment) (string, error)
+}
+
+// AttachmentBuilder creates a file attached to the summary and statement emails,
+// it returns nil when the file does not apply to the summary.
+type AttachmentBuilder interface {
+	Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error)
+}
+
+// UnsubscribeLinks returns the signed unsubscribe link of the account, empty when they are disabled.
+type UnsubscribeLinks interface {
+	URL(accountID uint, channel string) string
 }
 
 type EmailSender struct {
 	AccountRepository AccountRepository
 	EmailService      EmailService
+	Attachments       []AttachmentBuilder
+	UnsubscribeLinks  UnsubscribeLinks
 }
 
-func NewEmailSender(repository AccountRepository, service EmailService) *EmailSender {
+func NewEmailSender(repository AccountRepository, service EmailService, attachments []AttachmentBuilder, unsubscribeLinks UnsubscribeLinks) *EmailSender {
 	return &EmailSender{
 		AccountRepository: repository,
 		EmailService:      service,
+		Attachments:       attachments,
+		UnsubscribeLinks:  unsubscribeLinks,
 	}
 }
 
-func (se *EmailSender) Send(_ context.Context, accountID uint, summary *dtos.SummaryBalance) error {
+func (se *EmailSender) Channel() string {
+	return notificationdtos.ChannelEmail
+}
+
+// Email is the summary ready to be rendered with Template and sent.
+type Email struct {
+	To          string
+	Subject     string
+	Template    string
+	Variables   map[string]interface{}
+	Attachments []notifications.Attachment
+}
+
+// Send returns the Message-ID of the email.
+func (se *EmailSender) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
 	act, err := se.AccountRepository.GetAccountByID(accountID)
 	if err != nil {
-		return fmt.Errorf("error getting account by ID: %w", err)
+		return "", fmt.Errorf("error getting account by ID: %w", err)
 	}
 
-	err = se.EmailService.Send(act.Email, "Summary Balance", "summary", summary.ToMap(dtos.WithDecimalConversion()))
+	email, err := se.Compose(ctx, accountID, act, summary)
 	if err != nil {
-		return fmt.Errorf("error sending email: %w", err)
+		return "", err
+	}
+
+	messageID, err := se.EmailService.Send(email.To, email.Subject, email.Template, email.Variables, email.Attachments...)
+	if err != nil {
+		return "", fmt.Errorf("error sending email: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// Compose picks the template and builds the variables and the attachments of the email in the locale of the account.
+func (se *EmailSender) Compose(ctx context.Context, accountID uint, act *accountdtos.Account, summary *dtos.SummaryBalance) (*Email, error) {
+	subject, template := i18n.T(act.Locale, "subject_summary"), TemplateSummary
+	switch {
+	case len(summary.Alerts) > 0:
+		subject, template = i18n.T(act.Locale, "subject_alert"), TemplateAlert
+	case summary.Statement != nil:
+		subject, template = i18n.T(act.Locale, "subject_statement", summary.Statement.Sequence), TemplateStatement
+	}
+
+	attachments, err := se.buildAttachments(ctx, act, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := summary.ToMap(dtos.WithLocale(act.Locale))
+
+	// uploaded files are linked in the body, the rest are attached
+	var attached []notifications.Attachment
+	var downloads []map[string]string
+	inline := make(map[string]string)
+	for _, attachment := range attachments {
+		if attachment.URL != "" {
+			downloads = append(downloads, map[string]string{"Filename": attachment.Filename, "URL": attachment.URL})
+			continue
+		}
+		if attachment.ContentID != "" {
+			inline[attachment.Filename] = attachment.ContentID
+		}
+		attached = append(attached, attachment)
+	}
+	variables["Downloads"] = downloads
+	// the templates show the inline files with <img src="cid:{{index .Inline "<filename>"}}">
+	variables["Inline"] = inline
+
+	// alerts are not bulk emails, they can not be unsubscribed
+	if se.UnsubscribeLinks != nil && len(summary.Alerts) == 0 {
+		variables[notifications.UnsubscribeURLVariable] = se.UnsubscribeLinks.URL(accountID, se.Channel())
+	}
+
+	return &Email{
+		To:          act.Recipient(),
+		Subject:     subject,
+		Template:    template,
+		Variables:   variables,
+		Attachments: attached,
+	}, nil
+}
+
+// buildAttachments alerts are sent without attachments.
+func (se *EmailSender) buildAttachments(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) ([]notifications.Attachment, error) {
+	if len(summary.Alerts) > 0 {
+		return nil, nil
+	}
+
+	attachments := make([]notifications.Attachment, 0, len(se.Attachments))
+	for _, builder := range se.Attachments {
+		attachment, err := builder.Build(ctx, account, summary)
+		if err != nil {
+			return nil, fmt.Errorf("error building attachment: %w", err)
+		}
+		if attachment == nil {
+			continue
+		}
+		attachments = append(attachments, *attachment)
 	}
 
-	return nil
+	return attachments, nil
 }
@@ -0,0 +1,114 @@
+package summary
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	accountnotifications "github.com/juaguz/storid/internal/accounts/notifications"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/platform/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAccountRepository struct{}
+
+func (m *MockAccountRepository) GetAccountByID(accountID uint) (*accountdtos.Account, error) {
+	return &accountdtos.Account{Name: "Zoë", LastName: "Müller", Email: "test@storid.com"}, nil
+}
+
+type MockEmailService struct {
+	to          string
+	subject     string
+	variables   map[string]interface{}
+	attachments []notifications.Attachment
+}
+
+func (m *MockEmailService) Send(to, subject, template string, variables map[string]interface{}, attachments ...notifications.Attachment) (string, error) {
+	m.to = to
+	m.subject = subject
+	m.variables = variables
+	m.attachments = attachments
+	return "<1@storid.com>", nil
+}
+
+type MockTransactionRepository struct{}
+
+func (m *MockTransactionRepository) FindByAccountID(ctx context.Context, accountID uint) ([]*transactiondto.Transaction, error) {
+	return m.FindByAccountIDAndPeriod(ctx, accountID, time.Time{}, time.Now())
+}
+
+func (m *MockTransactionRepository) FindByAccountIDAndPeriod(ctx context.Context, accountID uint, from, to time.Time) ([]*transactiondto.Transaction, error) {
+	return []*transactiondto.Transaction{
+		{ExternalID: "1", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 1050, AccountID: accountID, Type: transactiondto.Credit, Category: "salary"},
+		{ExternalID: "2", Date: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), Amount: -250, AccountID: accountID, Type: transactiondto.Debit, Category: "food, drinks"},
+	}, nil
+}
+
+type MockFileStore struct {
+	key string
+}
+
+func (m *MockFileStore) Upload(ctx context.Context, key, contentType string, content []byte) (string, error) {
+	m.key = key
+	return "https://exports.storid.com/" + key, nil
+}
+
+func TestEmailSender_SendCSVAttachment(t *testing.T) {
+	service := &MockEmailService{}
+	sender := NewEmailSender(&MockAccountRepository{}, service, []AttachmentBuilder{NewTransactionsCSV(&MockTransactionRepository{})}, nil)
+
+	_, err := sender.Send(context.Background(), 1, &dtos.SummaryBalance{Balance: dtos.Balance{AccountID: 1}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "=?utf-8?q?Zo=C3=AB_M=C3=BCller?= <test@storid.com>", service.to)
+	assert.Len(t, service.attachments, 1)
+	assert.Equal(t, "transactions.csv", service.attachments[0].Filename)
+	assert.Equal(t, "external_id,date,type,category,amount\n"+
+		"1,2024-03-01,credit,salary,10.50\n"+
+		"2,2024-03-02,debit,\"food, drinks\",-2.50\n", string(service.attachments[0].Content))
+	assert.Empty(t, service.variables["Downloads"])
+}
+
+func TestEmailSender_SendCSVLink(t *testing.T) {
+	service := &MockEmailService{}
+	store := &MockFileStore{}
+	csv := NewLinkedAttachment(NewTransactionsCSV(&MockTransactionRepository{}), store)
+	sender := NewEmailSender(&MockAccountRepository{}, service, []AttachmentBuilder{csv}, nil)
+
+	_, err := sender.Send(context.Background(), 7, &dtos.SummaryBalance{Balance: dtos.Balance{AccountID: 7}})
+	assert.NoError(t, err)
+
+	assert.Empty(t, service.attachments)
+	assert.Regexp(t, `^7/\d{8}T\d{6}/transactions.csv$`, store.key)
+	assert.Equal(t, []map[string]string{
+		{"Filename": "transactions.csv", "URL": "https://exports.storid.com/" + store.key},
+	}, service.variables["Downloads"])
+}
+
+func TestEmailSender_SendUnsubscribeLink(t *testing.T) {
+	service := &MockEmailService{}
+	tokens := accountnotifications.NewUnsubscribeTokens([]byte("secret"), time.Hour, "https://api.storid.com/prod/unsubscribe")
+	sender := NewEmailSender(&MockAccountRepository{}, service, nil, tokens)
+
+	_, err := sender.Send(context.Background(), 7, &dtos.SummaryBalance{Balance: dtos.Balance{AccountID: 7}})
+	assert.NoError(t, err)
+
+	link, ok := service.variables[notifications.UnsubscribeURLVariable].(string)
+	assert.True(t, ok)
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+	accountID, channel, err := tokens.Verify(parsed.Query().Get("token"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), accountID)
+	assert.Equal(t, notificationdtos.ChannelEmail, channel)
+
+	_, err = sender.Send(context.Background(), 7, &dtos.SummaryBalance{Alerts: []*analysisdtos.Alert{{}}})
+	assert.NoError(t, err)
+	assert.NotContains(t, service.variables, notifications.UnsubscribeURLVariable)
+}
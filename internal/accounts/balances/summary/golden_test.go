@@ -0,0 +1,212 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/juaguz/storid/internal/platform/notifications"
+	"github.com/juaguz/storid/internal/platform/sms"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// go test ./internal/accounts/balances/summary/ -run TestGolden -update
+var update = flag.Bool("update", false, "regenerate the golden files of testdata/golden")
+
+var goldenNow = time.Date(2024, time.April, 10, 12, 0, 0, 0, time.UTC)
+
+type MockGoldenAccountRepository struct {
+	locale string
+}
+
+func (m *MockGoldenAccountRepository) GetAccountByID(accountID uint) (*accountdtos.Account, error) {
+	account := SampleAccount()
+	account.Locale = m.locale
+	account.Phone = "+5491122334455"
+	return account, nil
+}
+
+func (m *MockGoldenAccountRepository) GetPhones(ctx context.Context, accountIDs []uint) (map[uint]string, error) {
+	phones := make(map[uint]string, len(accountIDs))
+	for _, accountID := range accountIDs {
+		phones[accountID] = "+5491122334455"
+	}
+	return phones, nil
+}
+
+// MockRenderedEmailService renders the email with the embedded templates instead of sending it.
+type MockRenderedEmailService struct {
+	renderer *notifications.SMTPService
+	subject  string
+	html     string
+	text     string
+}
+
+func (m *MockRenderedEmailService) Send(to, subject, template string, variables map[string]interface{}, attachments ...notifications.Attachment) (string, error) {
+	html, text, err := m.renderer.Render(context.Background(), template, variables)
+	m.subject, m.html, m.text = subject, html, text
+	return "<1@storid.com>", err
+}
+
+type MockUnsubscribeLinks struct{}
+
+func (m *MockUnsubscribeLinks) URL(accountID uint, channel string) string {
+	return "https://storid.com/unsubscribe?token=golden"
+}
+
+// goldenFixtures the samples of the preview plus the edge cases of the templates.
+var goldenFixtures = []struct {
+	name    string
+	locale  string
+	summary func() *dtos.SummaryBalance
+}{
+	{"summary", "en", func() *dtos.SummaryBalance { return SampleSummary(TemplateSummary, goldenNow) }},
+	{"summary_es", "es", func() *dtos.SummaryBalance { return SampleSummary(TemplateSummary, goldenNow) }},
+	{"statement", "en", func() *dtos.SummaryBalance { return SampleSummary(TemplateStatement, goldenNow) }},
+	{"statement_es", "es", func() *dtos.SummaryBalance { return SampleSummary(TemplateStatement, goldenNow) }},
+	{"alert", "en", func() *dtos.SummaryBalance { return SampleSummary(TemplateAlert, goldenNow) }},
+	{"no_transactions", "en", func() *dtos.SummaryBalance {
+		return &dtos.SummaryBalance{Balance: dtos.Balance{AccountID: 1}}
+	}},
+	{"only_debits", "en", func() *dtos.SummaryBalance {
+		return &dtos.SummaryBalance{
+			Balance: dtos.Balance{AccountID: 1, TotalBalance: -18250, AvrDebitAmount: -6083, TransactionCount: 3},
+			MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+				months.March: {Balance: dtos.Balance{TotalBalance: -18250, TransactionCount: 3}, Month: int(months.March)},
+			},
+			DailyBalances: []*dtos.DailyBalance{
+				{Day: time.Date(2024, time.March, 28, 0, 0, 0, 0, time.UTC), Debits: -12000, Balance: -12000, TransactionCount: 1},
+				{Day: time.Date(2024, time.March, 29, 0, 0, 0, 0, time.UTC), Balance: -12000},
+				{Day: time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC), Debits: -6250, Balance: -18250, TransactionCount: 2},
+			},
+			Categories: dtos.TopCategories(map[string]int{"rent": -12000, "groceries": -6250}, 3),
+		}
+	}},
+	{"negative_balance", "en", func() *dtos.SummaryBalance {
+		return &dtos.SummaryBalance{
+			Balance: dtos.Balance{AccountID: 1, TotalBalance: -250075, AvrDebitAmount: -90025, AvrCreditAmount: 40000, TransactionCount: 4},
+			MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+				months.February: {Balance: dtos.Balance{TotalBalance: -100000, TransactionCount: 2}, Month: int(months.February)},
+				months.March:    {Balance: dtos.Balance{TotalBalance: -150075, TransactionCount: 2}, Month: int(months.March)},
+			},
+		}
+	}},
+	{"missing_months", "en", func() *dtos.SummaryBalance {
+		return &dtos.SummaryBalance{
+			Balance: dtos.Balance{AccountID: 1, TotalBalance: 56000, AvrDebitAmount: -2000, AvrCreditAmount: 30000, TransactionCount: 3},
+			MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+				months.January: {Balance: dtos.Balance{TotalBalance: 30000, TransactionCount: 1}, Month: int(months.January)},
+				months.April:   {Balance: dtos.Balance{TotalBalance: 26000, TransactionCount: 2}, Month: int(months.April)},
+			},
+		}
+	}},
+	{"empty_statement", "en", func() *dtos.SummaryBalance {
+		return &dtos.SummaryBalance{
+			Balance: dtos.Balance{AccountID: 1, TotalBalance: 5000},
+			Statement: &statementdtos.Statement{
+				Sequence:       1,
+				PeriodStart:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+				PeriodEnd:      time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+				OpeningBalance: 5000,
+				ClosingBalance: 5000,
+				Transactions:   []*transactiondto.Transaction{},
+			},
+		}
+	}},
+}
+
+// TestGolden renders every fixture on every channel and compares it with testdata/golden/<fixture>/,
+// run it with -update after changing a template and review the diff of the golden files.
+func TestGolden(t *testing.T) {
+	allowLoopbackWebhooks(t)
+
+	for _, fixture := range goldenFixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			accounts := &MockGoldenAccountRepository{locale: fixture.locale}
+			dir := filepath.Join("testdata", "golden", fixture.name)
+
+			renderer, err := notifications.NewSMTPService(&notifications.SMTPConfig{}, nil)
+			assert.NoError(t, err)
+			email := &MockRenderedEmailService{renderer: renderer}
+			emailSender := NewEmailSender(accounts, email, []AttachmentBuilder{NewMonthlyBalanceChart(), NewCreditDebitChart()}, &MockUnsubscribeLinks{})
+			_, err = emailSender.Send(context.Background(), 1, fixture.summary())
+			assert.NoError(t, err)
+			assertGolden(t, filepath.Join(dir, "email.html"), []byte(email.html))
+			assertGolden(t, filepath.Join(dir, "email.txt"), []byte("Subject: "+email.subject+"\n\n"+email.text))
+
+			provider := sms.NewFakeProvider(zap.NewNop())
+			smsSender := NewSMSSender(accounts, accounts, provider)
+			smsSender.now = func() time.Time { return goldenNow }
+			_, err = smsSender.Send(context.Background(), 1, fixture.summary())
+			assert.NoError(t, err)
+			if sent := provider.Sent(); assert.Len(t, sent, 1) {
+				assertGolden(t, filepath.Join(dir, "sms.txt"), []byte(sent[0].Body+"\n"))
+			}
+
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			webhookSender := NewWebhookSender(&MockWebhookRepository{}, server.URL, "secret", time.Second)
+			webhookSender.now = func() time.Time { return goldenNow }
+			id, err := webhookSender.Send(context.Background(), 1, fixture.summary())
+			assert.NoError(t, err)
+			// the IDs are random
+			assertGoldenJSON(t, filepath.Join(dir, "webhook.json"), bytes.ReplaceAll(body, []byte(id), []byte("webhook-id")))
+
+			chatSender := NewChatSender(accounts, &MockChatRepository{channels: map[uint]*notificationdtos.ChatChannel{
+				1: {AccountID: 1, URL: server.URL, Format: notificationdtos.ChatFormatSlack},
+				2: {AccountID: 2, URL: server.URL, Format: notificationdtos.ChatFormatTeams},
+			}}, time.Second)
+			_, err = chatSender.Send(context.Background(), 1, fixture.summary())
+			assert.NoError(t, err)
+			assertGoldenJSON(t, filepath.Join(dir, "slack.json"), body)
+
+			_, err = chatSender.Send(context.Background(), 2, fixture.summary())
+			assert.NoError(t, err)
+			assertGoldenJSON(t, filepath.Join(dir, "teams.json"), body)
+		})
+	}
+}
+
+func assertGoldenJSON(t *testing.T, path string, body []byte) {
+	t.Helper()
+	var indented bytes.Buffer
+	assert.NoError(t, json.Indent(&indented, body, "", "  "))
+	indented.WriteString("\n")
+	assertGolden(t, path, indented.Bytes())
+}
+
+// assertGolden the diff of a failure shows what changed in the rendered notification.
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if !assert.NoError(t, err, "run the test with -update to create the golden file") {
+		return
+	}
+	assert.Equal(t, string(want), string(got), "%s changed, run the test with -update if it is expected", path)
+}
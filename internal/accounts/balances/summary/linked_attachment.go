@@ -0,0 +1,48 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	"github.com/juaguz/storid/internal/platform/notifications"
+)
+
+type FileStore interface {
+	Upload(ctx context.Context, key, contentType string, content []byte) (string, error)
+}
+
+// LinkedAttachment uploads the file built by Builder and links it in the email body
+// instead of attaching it.
+type LinkedAttachment struct {
+	Builder   AttachmentBuilder
+	FileStore FileStore
+}
+
+func NewLinkedAttachment(builder AttachmentBuilder, store FileStore) *LinkedAttachment {
+	return &LinkedAttachment{
+		Builder:   builder,
+		FileStore: store,
+	}
+}
+
+func (la *LinkedAttachment) Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error) {
+	attachment, err := la.Builder.Build(ctx, account, summary)
+	if err != nil || attachment == nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d/%s/%s", summary.AccountID, time.Now().UTC().Format("20060102T150405"), attachment.Filename)
+	url, err := la.FileStore.Upload(ctx, key, attachment.ContentType, attachment.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notifications.Attachment{
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		URL:         url,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package summary
+
+import (
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/platform/months"
+)
+
+const (
+	TemplateSummary   = "summary"
+	TemplateStatement = "statement"
+	TemplateAlert     = "alert"
+)
+
+// PreviewTemplates the templates that can be previewed, EmailSender picks them by the kind of summary.
+var PreviewTemplates = []string{TemplateSummary, TemplateStatement, TemplateAlert}
+
+// SampleAccount is the account of the sample summaries.
+func SampleAccount() *accountdtos.Account {
+	return &accountdtos.Account{Name: "Jane", LastName: "Doe", Email: "jane@storid.com", Tier: accountdtos.DefaultTier}
+}
+
+// SampleSummary returns a summary with every section of the template filled, the dates are relative to now.
+func SampleSummary(template string, now time.Time) *dtos.SummaryBalance {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch template {
+	case TemplateAlert:
+		return &dtos.SummaryBalance{Alerts: []*analysisdtos.Alert{
+			{Kind: analysisdtos.AlertUnusualAmount, ExternalID: "tx-1042", Date: today.AddDate(0, 0, -1), Amount: -185000, ZScore: 5.2, Average: 35000},
+			{Kind: analysisdtos.AlertFirstLargeDebit, ExternalID: "tx-1043", Date: today, Amount: -92000, Threshold: 90000},
+		}}
+	case TemplateStatement:
+		start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+		return &dtos.SummaryBalance{
+			Balance: dtos.Balance{TotalBalance: 254050},
+			Statement: &statementdtos.Statement{
+				Sequence:       3,
+				PeriodStart:    start,
+				PeriodEnd:      start.AddDate(0, 1, 0),
+				OpeningBalance: 200000,
+				ClosingBalance: 254050,
+				TotalCredits:   350000,
+				TotalDebits:    -295950,
+				CreditCount:    1,
+				DebitCount:     3,
+				Transactions: []*transactiondto.Transaction{
+					{ExternalID: "tx-1", Date: start.AddDate(0, 0, 1), Amount: 350000, Type: transactiondto.Credit, Category: "salary"},
+					{ExternalID: "tx-2", Date: start.AddDate(0, 0, 3), Amount: -120000, Type: transactiondto.Debit, Category: "rent"},
+					{ExternalID: "tx-3", Date: start.AddDate(0, 0, 9), Amount: -45950, Type: transactiondto.Debit, Category: "groceries"},
+					{ExternalID: "tx-4", Date: start.AddDate(0, 0, 20), Amount: -130000, Type: transactiondto.Debit, Category: "travel"},
+				},
+			},
+		}
+	}
+
+	summary := &dtos.SummaryBalance{
+		Balance:        dtos.Balance{TotalBalance: 254050, AvrDebitAmount: -4525, AvrCreditAmount: 175000, TransactionCount: 42},
+		MonthlyBalance: make(map[months.Month]*dtos.MonthlyBalance),
+		Categories: dtos.TopCategories(map[string]int{
+			"rent": -120000, "groceries": -45950, "travel": -30000, "restaurants": -12500, "transport": -6400,
+		}, 3),
+		Subscriptions: []*analysisdtos.Subscription{
+			{Merchant: "Streamflix", Interval: analysisdtos.Monthly, Amount: -1599, PreviousAmount: -1299, NextExpectedDate: today.AddDate(0, 0, 12), PriceIncreased: true},
+			{Merchant: "Gym", Interval: analysisdtos.Monthly, Amount: -4500, NextExpectedDate: today.AddDate(0, 0, -3), Missed: true},
+		},
+	}
+
+	// the last four months, one of them with a negative net balance
+	firstDay := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i, balance := range []int{125000, -32000, 88000, 73050} {
+		month := months.Month(firstDay.AddDate(0, i-3, 0).Month())
+		summary.MonthlyBalance[month] = &dtos.MonthlyBalance{Balance: dtos.Balance{TotalBalance: balance, TransactionCount: 8 + i*3}, Month: int(month)}
+	}
+
+	balance := 200000
+	for i := 13; i >= 0; i-- {
+		day := &dtos.DailyBalance{Day: today.AddDate(0, 0, -i)}
+		if i%3 == 0 {
+			day.Credits = 25000 + i*1000
+			day.TransactionCount++
+		}
+		if i%2 == 0 {
+			day.Debits = -(8000 + i*700)
+			day.TransactionCount++
+		}
+		balance += day.Credits + day.Debits
+		day.Balance = balance
+		summary.DailyBalances = append(summary.DailyBalances, day)
+	}
+
+	return summary
+}
@@ -0,0 +1,261 @@
+package summary
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"go.uber.org/zap"
+)
+
+// Renderer renders the html and the text bodies of a template without sending them.
+type Renderer interface {
+	Render(ctx context.Context, templateName string, variables map[string]interface{}) (string, string, error)
+}
+
+var (
+	// errPreviewNotFound the account has no summary to preview.
+	errPreviewNotFound = errors.New("summary not found")
+	// errInvalidPreview the query of the preview is wrong.
+	errInvalidPreview = errors.New("invalid preview")
+)
+
+var previewIndex = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Email previews</title></head>
+<body style="font-family: Arial, sans-serif; margin: 40px;">
+<h1>Email previews</h1>
+<table cellpadding="6">
+{{range $template := .Templates}}<tr>
+<th align="left">{{$template}}</th>
+{{range $.Languages}}<td><a href="/{{$template}}?locale={{.}}">{{.}} html</a> · <a href="/{{$template}}?locale={{.}}&format=text">{{.}} text</a></td>{{end}}
+<td>
+<form method="get" action="/{{$template}}">
+<input name="account" placeholder="account ID" size="10" required>
+<select name="locale"><option value="">account locale</option>{{range $.Languages}}<option>{{.}}</option>{{end}}</select>
+<select name="format"><option value="html">html</option><option value="text">text</option></select>
+{{if eq $template "statement"}}<input name="cycle" placeholder="cycle" size="4"> <input name="cycle_day" placeholder="cycle day" size="4">{{end}}
+<button type="submit">Preview</button>
+</form>
+</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// PreviewHandler renders the email templates in the browser instead of sending them. GET / lists
+// the templates and GET /{template} renders one with the sample summary, or with the summary of
+// ?account=<id> when it is given. ?locale= switches the language, ?format=text renders the text body
+// and ?cycle= and ?cycle_day= pick the billing cycle of the statements.
+type PreviewHandler struct {
+	EmailSender            *EmailSender
+	Renderer               Renderer
+	SummaryGenerator       SummaryGenerator
+	StatementGenerator     StatementGenerator
+	SubscriptionRepository SubscriptionRepository
+	Log                    *zap.Logger
+
+	mux *http.ServeMux
+}
+
+func NewPreviewHandler(sender *EmailSender, renderer Renderer, generator SummaryGenerator, statements StatementGenerator,
+	subscriptions SubscriptionRepository, log *zap.Logger) *PreviewHandler {
+	h := &PreviewHandler{
+		EmailSender:            sender,
+		Renderer:               renderer,
+		SummaryGenerator:       generator,
+		StatementGenerator:     statements,
+		SubscriptionRepository: subscriptions,
+		Log:                    log,
+		mux:                    http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /{$}", h.index)
+	h.mux.HandleFunc("GET /{template}", h.preview)
+	return h
+}
+
+func (h *PreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *PreviewHandler) index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := previewIndex.Execute(w, map[string]interface{}{"Templates": PreviewTemplates, "Languages": i18n.Languages()})
+	if err != nil {
+		h.Log.Error("error rendering preview index", zap.Error(err))
+	}
+}
+
+func (h *PreviewHandler) preview(w http.ResponseWriter, r *http.Request) {
+	templateName := r.PathValue("template")
+	if !slices.Contains(PreviewTemplates, templateName) {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	accountID, act, summary, err := h.load(r.Context(), templateName, query)
+	if err != nil {
+		h.fail(w, templateName, err)
+		return
+	}
+
+	if locale := query.Get("locale"); locale != "" {
+		act.Locale = locale
+	}
+
+	email, err := h.EmailSender.Compose(r.Context(), accountID, act, summary)
+	if err != nil {
+		h.fail(w, templateName, err)
+		return
+	}
+
+	htmlBody, textBody, err := h.Renderer.Render(r.Context(), email.Template, email.Variables)
+	if err != nil {
+		h.fail(w, templateName, err)
+		return
+	}
+
+	w.Header().Set("X-Preview-Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	if query.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "Subject: %s\n\n%s", email.Subject, textBody)
+		return
+	}
+
+	// the browser can not resolve cid: so the inline files are embedded in the page
+	for _, attachment := range email.Attachments {
+		if attachment.ContentID == "" {
+			continue
+		}
+		dataURI := "data:" + attachment.ContentType + ";base64," + base64.StdEncoding.EncodeToString(attachment.Content)
+		htmlBody = strings.ReplaceAll(htmlBody, "cid:"+attachment.ContentID, dataURI)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, htmlBody)
+}
+
+// load returns the sample account and summary unless an account is given, the alerts of a real
+// account are still the sample ones because they are only detected while importing.
+func (h *PreviewHandler) load(ctx context.Context, templateName string, query url.Values) (uint, *accountdtos.Account, *dtos.SummaryBalance, error) {
+	if query.Get("account") == "" {
+		return 0, SampleAccount(), SampleSummary(templateName, time.Now()), nil
+	}
+
+	id, err := strconv.ParseUint(query.Get("account"), 10, 0)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid account %q: %w", query.Get("account"), errInvalidPreview)
+	}
+	accountID := uint(id)
+
+	summary, err := h.summary(ctx, accountID)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	act, err := h.EmailSender.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("error getting account by ID: %w", err)
+	}
+
+	switch templateName {
+	case TemplateAlert:
+		summary = &dtos.SummaryBalance{Balance: summary.Balance, Alerts: SampleSummary(TemplateAlert, time.Now()).Alerts}
+	case TemplateStatement:
+		if h.StatementGenerator == nil {
+			return 0, nil, nil, fmt.Errorf("statements are not configured: %w", errInvalidPreview)
+		}
+
+		cycle, cycleDay, err := previewCycle(query)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		statement, err := h.StatementGenerator.GenerateCycle(ctx, accountID, cycleDay, cycle)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("error generating statement: %w", err)
+		}
+		summary = &dtos.SummaryBalance{
+			Balance:   dtos.Balance{AccountID: accountID, TotalBalance: statement.ClosingBalance},
+			Statement: statement,
+		}
+	}
+
+	return accountID, act, summary, nil
+}
+
+// summary returns the summary of the account with its subscriptions, like the sender does.
+func (h *PreviewHandler) summary(ctx context.Context, accountID uint) (*dtos.SummaryBalance, error) {
+	var summary *dtos.SummaryBalance
+	err := h.SummaryGenerator.EachSummaryPage(ctx, dtos.AccountSelector{AccountIDs: []uint{accountID}}, 1, func(summaries map[uint]*dtos.SummaryBalance) error {
+		if found, ok := summaries[accountID]; ok {
+			summary = found
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error generating summary: %w", err)
+	}
+	if summary == nil {
+		return nil, fmt.Errorf("account %d: %w", accountID, errPreviewNotFound)
+	}
+
+	if h.SubscriptionRepository != nil {
+		var subscriptions map[uint][]*analysisdtos.Subscription
+		subscriptions, err = h.SubscriptionRepository.GetSubscriptionsByAccountIDs(ctx, []uint{accountID})
+		if err != nil {
+			return nil, fmt.Errorf("error getting subscriptions: %w", err)
+		}
+		summary.Subscriptions = subscriptions[accountID]
+	}
+
+	return summary, nil
+}
+
+// previewCycle defaults to the first cycle starting on the first day of the month, the generator
+// checks the values are in range.
+func previewCycle(query url.Values) (int, int, error) {
+	cycle, cycleDay := 1, 1
+
+	var err error
+	if value := query.Get("cycle"); value != "" {
+		if cycle, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid cycle %q: %w", value, errInvalidPreview)
+		}
+	}
+	if value := query.Get("cycle_day"); value != "" {
+		if cycleDay, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid cycle day %q: %w", value, errInvalidPreview)
+		}
+	}
+
+	return cycle, cycleDay, nil
+}
+
+func (h *PreviewHandler) fail(w http.ResponseWriter, templateName string, err error) {
+	switch {
+	case errors.Is(err, errPreviewNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errInvalidPreview):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		// the internal errors are only logged, they can carry the queries or the paths of the templates
+		h.Log.Error("error rendering preview", zap.String("template", templateName), zap.Error(err))
+		http.Error(w, "error rendering preview, see the server logs", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,167 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockRenderer struct {
+	template  string
+	variables map[string]interface{}
+	err       error
+}
+
+func (m *MockRenderer) Render(ctx context.Context, templateName string, variables map[string]interface{}) (string, string, error) {
+	m.template = templateName
+	m.variables = variables
+	if m.err != nil {
+		return "", "", m.err
+	}
+	return `<img src="cid:monthly-balance.png@storid">` + variables["TotalBalance"].(string), "total " + variables["TotalBalance"].(string), nil
+}
+
+type MockStatementGenerator struct {
+	accountIDs []uint
+	errs       map[uint]error
+	cycleDay   int
+	cycle      int
+}
+
+func (m *MockStatementGenerator) GetAccountIDs(ctx context.Context) ([]uint, error) {
+	return m.accountIDs, nil
+}
+
+func (m *MockStatementGenerator) GenerateCycle(ctx context.Context, accountID uint, cycleDay, cycle int) (*statementdtos.Statement, error) {
+	if err := m.errs[accountID]; err != nil {
+		return nil, err
+	}
+	m.cycleDay, m.cycle = cycleDay, cycle
+	return &statementdtos.Statement{Sequence: cycle, ClosingBalance: 700}, nil
+}
+
+func (m *MockStatementGenerator) Generate(ctx context.Context, accountID uint, from, to time.Time) (*statementdtos.Statement, error) {
+	if err := m.errs[accountID]; err != nil {
+		return nil, err
+	}
+	return &statementdtos.Statement{Sequence: 1, PeriodStart: from, PeriodEnd: to, ClosingBalance: 700}, nil
+}
+
+type MockSubscriptionRepository struct {
+	calls int
+}
+
+func (m *MockSubscriptionRepository) GetSubscriptionsByAccountIDs(ctx context.Context, accountIDs []uint) (map[uint][]*analysisdtos.Subscription, error) {
+	m.calls++
+	subscriptions := make(map[uint][]*analysisdtos.Subscription, len(accountIDs))
+	for _, accountID := range accountIDs {
+		subscriptions[accountID] = []*analysisdtos.Subscription{{Merchant: "Streamflix", Interval: analysisdtos.Monthly, Amount: -1599}}
+	}
+	return subscriptions, nil
+}
+
+func newTestPreviewHandler() (*PreviewHandler, *MockRenderer, *MockStatementGenerator) {
+	renderer := &MockRenderer{}
+	statements := &MockStatementGenerator{}
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{
+		1: {Balance: dtos.Balance{AccountID: 1, TotalBalance: 1050}, MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+			months.March: {Balance: dtos.Balance{TotalBalance: 1050}},
+		}},
+	}}
+	sender := NewEmailSender(&MockAccountRepository{}, &MockEmailService{}, []AttachmentBuilder{NewMonthlyBalanceChart()}, nil)
+	return NewPreviewHandler(sender, renderer, generator, statements, &MockSubscriptionRepository{}, zap.NewNop()), renderer, statements
+}
+
+func preview(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestPreviewHandler_Index(t *testing.T) {
+	handler, _, _ := newTestPreviewHandler()
+
+	recorder := preview(handler, "/")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	for _, template := range PreviewTemplates {
+		for _, language := range i18n.Languages() {
+			assert.Contains(t, recorder.Body.String(), `href="/`+template+`?locale=`+language+`&format=text"`)
+		}
+	}
+}
+
+func TestPreviewHandler_Sample(t *testing.T) {
+	handler, renderer, _ := newTestPreviewHandler()
+
+	for _, template := range PreviewTemplates {
+		recorder := preview(handler, "/"+template)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, template, renderer.template)
+		assert.NotEmpty(t, recorder.Header().Get("X-Preview-Subject"))
+	}
+
+	// the sample summary is rendered in the locale asked with the charts embedded
+	recorder := preview(handler, "/summary?locale=es")
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `src="data:image/png;base64,`)
+	assert.NotContains(t, recorder.Body.String(), "cid:")
+	assert.Contains(t, recorder.Body.String(), i18n.FormatCents("es", 254050))
+
+	recorder = preview(handler, "/summary?format=text")
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "Subject: "+i18n.T("en", "subject_summary")+"\n\ntotal "+i18n.FormatCents("en", 254050), recorder.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, preview(handler, "/missing").Code)
+}
+
+func TestPreviewHandler_Account(t *testing.T) {
+	handler, renderer, statements := newTestPreviewHandler()
+
+	recorder := preview(handler, "/summary?account=1")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), i18n.FormatCents("en", 1050))
+	assert.Len(t, renderer.variables["Subscriptions"], 1)
+
+	recorder = preview(handler, "/statement?account=1&cycle=2&cycle_day=15")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, TemplateStatement, renderer.template)
+	assert.Equal(t, 15, statements.cycleDay)
+	assert.Equal(t, 2, statements.cycle)
+
+	recorder = preview(handler, "/alert?account=1")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, TemplateAlert, renderer.template)
+
+	assert.Equal(t, http.StatusNotFound, preview(handler, "/summary?account=2").Code)
+	assert.Equal(t, http.StatusBadRequest, preview(handler, "/summary?account=one").Code)
+	assert.Equal(t, http.StatusBadRequest, preview(handler, "/statement?account=1&cycle=last").Code)
+
+	// the internal errors are not shown
+	renderer.err = errors.New("open /srv/templates/summary.html: permission denied")
+	recorder = preview(handler, "/summary?account=1")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "/srv/templates")
+}
+
+func TestSampleSummary(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 10, 0, 0, 0, time.UTC)
+
+	summary := SampleSummary(TemplateSummary, now)
+	assert.Len(t, summary.MonthlyBalance, 4)
+	assert.Len(t, summary.DailyBalances, 14)
+	assert.NotEmpty(t, summary.Subscriptions)
+
+	assert.NotNil(t, SampleSummary(TemplateStatement, now).Statement)
+	assert.NotEmpty(t, SampleSummary(TemplateAlert, now).Alerts)
+}
@@ -2,60 +2,517 @@ package summary
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
 	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	"github.com/juaguz/storid/internal/accounts/notifications"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
 	"go.uber.org/zap"
 )
 
+// Notifier Send returns the ID the provider gave to the notification, empty when there is none.
 type Notifier interface {
-	Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) error
+	Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error)
+	// Channel is used to check the preferences of the account e.g. email
+	Channel() string
 }
 
+// Recipients is implemented by the notifiers that only reach some of the accounts e.g. the ones with a webhook,
+// the rest of the accounts are skipped.
+type Recipients interface {
+	HasRecipients(ctx context.Context, accountIDs []uint) (map[uint]bool, error)
+}
+
+// SummaryGenerator EachSummaryPage calls fn with the summaries of up to pageSize accounts at a time
+// so the accounts are not loaded in memory at once, it stops at the first error of fn.
 type SummaryGenerator interface {
-	GetSummaryBalance(ctx context.Context) (map[uint]*dtos.SummaryBalance, error)
+	EachSummaryPage(ctx context.Context, selector dtos.AccountSelector, pageSize int, fn func(summaries map[uint]*dtos.SummaryBalance) error) error
+}
+
+type SubscriptionRepository interface {
+	GetSubscriptionsByAccountIDs(ctx context.Context, accountIDs []uint) (map[uint][]*analysisdtos.Subscription, error)
+}
+
+type StatementGenerator interface {
+	GetAccountIDs(ctx context.Context) ([]uint, error)
+	GenerateCycle(ctx context.Context, accountID uint, cycleDay, cycle int) (*statementdtos.Statement, error)
+	Generate(ctx context.Context, accountID uint, from, to time.Time) (*statementdtos.Statement, error)
+}
+
+type PreferenceRepository interface {
+	GetPreferences(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.Preferences, error)
+}
+
+// SendLog records every notification sent, failed or skipped.
+type SendLog interface {
+	Record(ctx context.Context, entry *notificationdtos.SendLogEntry) error
+	GetLastSent(ctx context.Context, channel, kind string, accountIDs []uint) (map[uint]time.Time, error)
+}
+
+// DeliveryRepository keeps the notifications delivered so a retried sender does not send them again.
+type DeliveryRepository interface {
+	Claim(ctx context.Context, accountID uint, channel, key string, lease time.Duration) (bool, error)
+	Complete(ctx context.Context, accountID uint, channel, key, messageID string, sendErr error) error
+	GetFailed(ctx context.Context, keys []string) ([]*notificationdtos.Delivery, error)
+}
+
+// DefaultPageSize amount of accounts loaded at a time.
+const DefaultPageSize = 1000
+
+// DefaultDeliveryLease time a claimed delivery is reserved for the sender that claimed it.
+const DefaultDeliveryLease = 15 * time.Minute
+
+type SendOption func(*sendOptions)
+
+type sendOptions struct {
+	idempotencyKey string
+	resendFailed   bool
+	selector       dtos.AccountSelector
+}
+
+// WithIdempotencyKey replaces the day used to identify the summaries, a summary is delivered
+// once per key. The statements are identified by their sequence.
+func WithIdempotencyKey(key string) SendOption {
+	return func(o *sendOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithAccounts only sends the summaries of the accounts picked by the selector.
+func WithAccounts(selector dtos.AccountSelector) SendOption {
+	return func(o *sendOptions) {
+		o.selector = selector
+	}
+}
+
+// WithResendFailed only sends the notifications whose delivery failed.
+func WithResendFailed() SendOption {
+	return func(o *sendOptions) {
+		o.resendFailed = true
+	}
 }
 
 type Sender struct {
-	SummaryGenerator SummaryGenerator
-	Notifier         []Notifier
-	logger           *zap.Logger
+	SummaryGenerator       SummaryGenerator
+	Notifier               []Notifier
+	SubscriptionRepository SubscriptionRepository
+	StatementGenerator     StatementGenerator
+	PreferenceRepository   PreferenceRepository
+	SendLog                SendLog
+	DeliveryRepository     DeliveryRepository
+	DeliveryLease          time.Duration
+	// Workers amount of notifications sent at the same time
+	Workers int
+	// RateLimits notifications per second indexed by channel, the missing channels are not limited
+	RateLimits   map[string]float64
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// PageSize amount of accounts loaded at a time
+	PageSize int
+	logger   *zap.Logger
+	now      func() time.Time
 }
 
-func NewSender(summaryGenerator SummaryGenerator, notifiers []Notifier, logger *zap.Logger) *Sender {
+func NewSender(summaryGenerator SummaryGenerator, notifiers []Notifier, subscriptions SubscriptionRepository, statements StatementGenerator, preferences PreferenceRepository, sendLog SendLog, deliveries DeliveryRepository, logger *zap.Logger) *Sender {
 	return &Sender{
-		SummaryGenerator: summaryGenerator,
-		Notifier:         notifiers,
-		logger:           logger,
+		SummaryGenerator:       summaryGenerator,
+		Notifier:               notifiers,
+		SubscriptionRepository: subscriptions,
+		StatementGenerator:     statements,
+		PreferenceRepository:   preferences,
+		SendLog:                sendLog,
+		DeliveryRepository:     deliveries,
+		DeliveryLease:          DefaultDeliveryLease,
+		Workers:                DefaultWorkers,
+		MaxRetries:             DefaultMaxRetries,
+		RetryBackoff:           DefaultRetryBackoff,
+		PageSize:               DefaultPageSize,
+		logger:                 logger,
+		now:                    time.Now,
+	}
+}
+
+// Send sends the summaries that were not delivered yet, by default a summary is delivered once per day.
+// The error is returned when the summaries could not be generated, the failed notifications are counted in the report.
+func (s *Sender) Send(ctx context.Context, options ...SendOption) (*Report, error) {
+	opts := s.sendOptions(options)
+	key := notificationdtos.KindSummary + ":" + opts.idempotencyKey
+
+	pages := func(yield func(map[uint]*dtos.SummaryBalance) error) error {
+		return s.SummaryGenerator.EachSummaryPage(ctx, opts.selector, s.PageSize, func(summaries map[uint]*dtos.SummaryBalance) error {
+			s.addSubscriptions(ctx, summaries)
+			return yield(summaries)
+		})
 	}
+
+	return s.notify(ctx, notificationdtos.KindSummary, pages, func(*dtos.SummaryBalance) string { return key }, opts, &Report{})
+}
+
+// SendStatements sends the statement of the billing cycle n of every account instead of the all-time summary.
+// The statements that could not be generated are counted as failed, the cycles that have not ended as skipped.
+func (s *Sender) SendStatements(ctx context.Context, cycleDay, cycle int, options ...SendOption) (*Report, error) {
+	return s.sendStatements(ctx, func(accountID uint) (*statementdtos.Statement, error) {
+		return s.StatementGenerator.GenerateCycle(ctx, accountID, cycleDay, cycle)
+	}, options)
+}
+
+// SendPeriodStatements sends the statement of the period [from, to) of every account, e.g. a quarter,
+// instead of a billing cycle. The period must have ended.
+func (s *Sender) SendPeriodStatements(ctx context.Context, from, to time.Time, options ...SendOption) (*Report, error) {
+	return s.sendStatements(ctx, func(accountID uint) (*statementdtos.Statement, error) {
+		return s.StatementGenerator.Generate(ctx, accountID, from, to)
+	}, options)
 }
 
-func (s *Sender) Send(ctx context.Context) error {
-	summaries, err := s.SummaryGenerator.GetSummaryBalance(ctx)
+// sendStatements sends the statement generate returns for every account.
+func (s *Sender) sendStatements(ctx context.Context, generate func(accountID uint) (*statementdtos.Statement, error), options []SendOption) (*Report, error) {
+	opts := s.sendOptions(options)
+
+	if s.StatementGenerator == nil {
+		return nil, fmt.Errorf("statements are not configured")
+	}
+
+	if !opts.selector.All() {
+		return nil, fmt.Errorf("the accounts can only be selected for the summaries")
+	}
+
+	accountIDs, err := s.StatementGenerator.GetAccountIDs(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var wg sync.WaitGroup
+	report := &Report{}
 
-	for accountID, summary := range summaries {
-		for _, notifier := range s.Notifier {
-			// Increment the WaitGroup counter
-			wg.Add(1)
+	// the statements are generated a page at a time
+	pages := func(yield func(map[uint]*dtos.SummaryBalance) error) error {
+		pageSize := max(s.PageSize, 1)
+		for start := 0; start < len(accountIDs); start += pageSize {
+			page := accountIDs[start:min(start+pageSize, len(accountIDs))]
 
-			// Run the sending function as a goroutine
-			go func(notifier Notifier, accountID uint, summary *dtos.SummaryBalance) {
-				defer wg.Done() // Decrement the counter when done
-				err := notifier.Send(ctx, accountID, summary)
+			summaries := make(map[uint]*dtos.SummaryBalance, len(page))
+			for _, accountID := range page {
+				statement, err := generate(accountID)
+				if errors.Is(err, statements.ErrPeriodNotEnded) {
+					s.logger.Info("statement skipped", zap.Uint("account_id", accountID), zap.Error(err))
+					report.add(notificationdtos.StatusSkipped)
+					continue
+				}
 				if err != nil {
-					s.logger.Error("error sending email", zap.Error(err))
+					s.logger.Error("error generating statement", zap.Uint("account_id", accountID), zap.Error(err))
+					report.add(notificationdtos.StatusFailed)
+					continue
 				}
-			}(notifier, accountID, summary)
+
+				summaries[accountID] = &dtos.SummaryBalance{
+					Balance:   dtos.Balance{AccountID: accountID, TotalBalance: statement.ClosingBalance},
+					Statement: statement,
+				}
+			}
+
+			if err := yield(summaries); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+
+	key := func(summary *dtos.SummaryBalance) string {
+		return fmt.Sprintf("%s:%d", notificationdtos.KindStatement, summary.Statement.Sequence)
+	}
+
+	return s.notify(ctx, notificationdtos.KindStatement, pages, key, opts, report)
+}
+
+func (s *Sender) sendOptions(options []SendOption) sendOptions {
+	opts := sendOptions{idempotencyKey: s.now().UTC().Format(time.DateOnly)}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// notify sends every page of summaries through the worker pool, the next page is loaded while the workers are
+// busy so only a page of summaries is kept in memory. key returns the idempotency key of a summary,
+// the notifications are counted in report.
+func (s *Sender) notify(ctx context.Context, kind string, pages func(yield func(map[uint]*dtos.SummaryBalance) error) error, key func(*dtos.SummaryBalance) string, opts sendOptions, report *Report) (*Report, error) {
+	limiters := make(map[string]*rateLimiter, len(s.Notifier))
+	for _, notifier := range s.Notifier {
+		limiters[notifier.Channel()] = newRateLimiter(s.RateLimits[notifier.Channel()])
+	}
+
+	jobs := make(chan delivery)
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				report.add(s.deliver(ctx, job, limiters[job.notifier.Channel()], kind))
+			}
+		}()
 	}
 
-	// Wait for all goroutines to complete
+	err := pages(func(summaries map[uint]*dtos.SummaryBalance) error {
+		return s.notifyPage(ctx, kind, summaries, key, opts, report, jobs)
+	})
+
+	close(jobs)
 	wg.Wait()
 
+	s.logger.Info("notifications sent", zap.String("kind", kind), zap.Int("sent", report.Sent), zap.Int("failed", report.Failed), zap.Int("skipped", report.Skipped))
+
+	return report, err
+}
+
+// notifyPage queues the summaries for the notifiers the preferences of each account allow,
+// the accounts that opted out are recorded as skipped.
+func (s *Sender) notifyPage(ctx context.Context, kind string, summaries map[uint]*dtos.SummaryBalance, key func(*dtos.SummaryBalance) string, opts sendOptions, report *Report, jobs chan<- delivery) error {
+	keys := make(map[uint]string, len(summaries))
+	accountIDs := make([]uint, 0, len(summaries))
+	for accountID, summary := range summaries {
+		keys[accountID] = key(summary)
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	notifiers := s.Notifier
+
+	var failed map[string]map[uint]bool
+	if opts.resendFailed {
+		var err error
+		if failed, err = s.getFailed(ctx, keys); err != nil {
+			return err
+		}
+
+		// only the channels that failed are notified again
+		notifiers = make([]Notifier, 0, len(s.Notifier))
+		for _, notifier := range s.Notifier {
+			if len(failed[notifier.Channel()]) > 0 {
+				notifiers = append(notifiers, notifier)
+			}
+		}
+	}
+
+	preferences, err := s.getPreferences(ctx, accountIDs)
+	if err != nil {
+		return err
+	}
+
+	lastSent := make(map[string]map[uint]time.Time, len(notifiers))
+	recipients := make(map[string]map[uint]bool)
+	for _, notifier := range notifiers {
+		lastSent[notifier.Channel()], err = s.getLastSent(ctx, notifier.Channel(), kind, accountIDs)
+		if err != nil {
+			return err
+		}
+
+		if r, ok := notifier.(Recipients); ok {
+			if recipients[notifier.Channel()], err = r.HasRecipients(ctx, accountIDs); err != nil {
+				return fmt.Errorf("error getting %s recipients: %w", notifier.Channel(), err)
+			}
+		}
+	}
+
+	now := s.now()
+	for accountID, summary := range summaries {
+		for _, notifier := range notifiers {
+			channel := notifier.Channel()
+			if opts.resendFailed && !failed[channel][accountID] {
+				continue
+			}
+
+			reason := notifications.SkipReason(preferences[accountID], channel, now, lastSent[channel][accountID])
+			if reached, ok := recipients[channel]; ok && reason == "" && !reached[accountID] {
+				reason = notifications.ReasonNoRecipient
+			}
+			if reason != "" {
+				s.logger.Info("notification skipped", zap.Uint("account_id", accountID), zap.String("channel", channel), zap.String("reason", reason))
+				s.record(ctx, accountID, channel, kind, notificationdtos.StatusSkipped, reason)
+				report.add(notificationdtos.StatusSkipped)
+				continue
+			}
+
+			jobs <- delivery{notifier: notifier, accountID: accountID, summary: summary, key: keys[accountID]}
+		}
+	}
+
 	return nil
 }
+
+type delivery struct {
+	notifier  Notifier
+	accountID uint
+	summary   *dtos.SummaryBalance
+	key       string
+}
+
+// deliver sends the summary unless it was already delivered with the same key and returns the status recorded.
+func (s *Sender) deliver(ctx context.Context, d delivery, limiter *rateLimiter, kind string) string {
+	channel := d.notifier.Channel()
+
+	if s.DeliveryRepository != nil {
+		claimed, err := s.DeliveryRepository.Claim(ctx, d.accountID, channel, d.key, s.DeliveryLease)
+		if err != nil {
+			s.logger.Error("error claiming delivery", zap.Uint("account_id", d.accountID), zap.String("key", d.key), zap.Error(err))
+			s.record(ctx, d.accountID, channel, kind, notificationdtos.StatusFailed, err.Error())
+			return notificationdtos.StatusFailed
+		}
+		if !claimed {
+			s.logger.Info("notification already delivered", zap.Uint("account_id", d.accountID), zap.String("channel", channel), zap.String("key", d.key))
+			s.record(ctx, d.accountID, channel, kind, notificationdtos.StatusSkipped, notifications.ReasonAlreadyDelivered)
+			return notificationdtos.StatusSkipped
+		}
+	}
+
+	messageID, err := s.send(ctx, d, limiter)
+
+	if s.DeliveryRepository != nil {
+		if err := s.DeliveryRepository.Complete(ctx, d.accountID, channel, d.key, messageID, err); err != nil {
+			s.logger.Error("error completing delivery", zap.Uint("account_id", d.accountID), zap.String("key", d.key), zap.Error(err))
+		}
+	}
+
+	if err != nil {
+		s.logger.Error("error sending notification", zap.Uint("account_id", d.accountID), zap.String("channel", channel), zap.Error(err))
+		s.record(ctx, d.accountID, channel, kind, notificationdtos.StatusFailed, err.Error())
+		return notificationdtos.StatusFailed
+	}
+	s.record(ctx, d.accountID, channel, kind, notificationdtos.StatusSent, "")
+	return notificationdtos.StatusSent
+}
+
+// send retries the transient failures with exponential backoff, every attempt waits for the rate limit.
+func (s *Sender) send(ctx context.Context, d delivery, limiter *rateLimiter) (string, error) {
+	ctx = WithDeliveryKey(ctx, d.key)
+	backoff := s.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		messageID, err := d.notifier.Send(ctx, d.accountID, d.summary)
+		if err == nil || attempt >= s.MaxRetries || !IsTransient(err) {
+			return messageID, err
+		}
+
+		s.logger.Warn("retrying notification", zap.Uint("account_id", d.accountID), zap.String("channel", d.notifier.Channel()),
+			zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zap.Error(err))
+		if err := sleep(ctx, backoff); err != nil {
+			return "", err
+		}
+		backoff *= 2
+	}
+}
+
+// getFailed returns the accounts whose delivery failed indexed by channel.
+func (s *Sender) getFailed(ctx context.Context, keys map[uint]string) (map[string]map[uint]bool, error) {
+	if s.DeliveryRepository == nil {
+		return nil, fmt.Errorf("deliveries are not configured")
+	}
+
+	unique := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		unique[key] = true
+	}
+	distinct := make([]string, 0, len(unique))
+	for key := range unique {
+		distinct = append(distinct, key)
+	}
+
+	deliveries, err := s.DeliveryRepository.GetFailed(ctx, distinct)
+	if err != nil {
+		return nil, fmt.Errorf("error getting failed deliveries: %w", err)
+	}
+
+	failed := make(map[string]map[uint]bool)
+	for _, delivery := range deliveries {
+		if keys[delivery.AccountID] != delivery.IdempotencyKey {
+			continue
+		}
+		if failed[delivery.Channel] == nil {
+			failed[delivery.Channel] = make(map[uint]bool)
+		}
+		failed[delivery.Channel][delivery.AccountID] = true
+	}
+
+	return failed, nil
+}
+
+// getPreferences every account gets the default preferences when they are not configured.
+func (s *Sender) getPreferences(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.Preferences, error) {
+	if s.PreferenceRepository == nil {
+		preferences := make(map[uint]*notificationdtos.Preferences, len(accountIDs))
+		for _, accountID := range accountIDs {
+			preferences[accountID] = notificationdtos.DefaultPreferences(accountID)
+		}
+		return preferences, nil
+	}
+
+	preferences, err := s.PreferenceRepository.GetPreferences(ctx, accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting notification preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// getLastSent the frequency only applies to the summaries, statements are sent once per cycle.
+func (s *Sender) getLastSent(ctx context.Context, channel, kind string, accountIDs []uint) (map[uint]time.Time, error) {
+	if s.SendLog == nil || kind != notificationdtos.KindSummary {
+		return nil, nil
+	}
+
+	lastSent, err := s.SendLog.GetLastSent(ctx, channel, kind, accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting last sent notifications: %w", err)
+	}
+
+	return lastSent, nil
+}
+
+// record a failure to write the send log should not stop the notifications.
+func (s *Sender) record(ctx context.Context, accountID uint, channel, kind, status, reason string) {
+	if s.SendLog == nil {
+		return
+	}
+
+	entry := &notificationdtos.SendLogEntry{
+		AccountID: accountID,
+		Channel:   channel,
+		Kind:      kind,
+		Status:    status,
+		Reason:    reason,
+	}
+	if err := s.SendLog.Record(ctx, entry); err != nil {
+		s.logger.Error("error recording notification", zap.Uint("account_id", accountID), zap.Error(err))
+	}
+}
+
+// addSubscriptions attaches the detected subscriptions to each summary of the page,
+// a failure here should not prevent the summaries from being sent.
+func (s *Sender) addSubscriptions(ctx context.Context, summaries map[uint]*dtos.SummaryBalance) {
+	if s.SubscriptionRepository == nil {
+		return
+	}
+
+	accountIDs := make([]uint, 0, len(summaries))
+	for accountID := range summaries {
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	subscriptions, err := s.SubscriptionRepository.GetSubscriptionsByAccountIDs(ctx, accountIDs)
+	if err != nil {
+		s.logger.Error("error getting subscriptions", zap.Int("accounts", len(accountIDs)), zap.Error(err))
+		return
+	}
+
+	for accountID, summary := range summaries {
+		summary.Subscriptions = subscriptions[accountID]
+	}
+}
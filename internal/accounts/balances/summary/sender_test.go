@@ -0,0 +1,505 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	"github.com/juaguz/storid/internal/accounts/notifications"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockSummaryGenerator struct {
+	summaries map[uint]*dtos.SummaryBalance
+	selector  dtos.AccountSelector
+	pages     int
+}
+
+func (m *MockSummaryGenerator) EachSummaryPage(ctx context.Context, selector dtos.AccountSelector, pageSize int, fn func(summaries map[uint]*dtos.SummaryBalance) error) error {
+	m.selector = selector
+	m.pages = 0
+
+	accountIDs := make([]uint, 0, len(m.summaries))
+	for accountID := range m.summaries {
+		accountIDs = append(accountIDs, accountID)
+	}
+	slices.Sort(accountIDs)
+
+	for start := 0; start < len(accountIDs); start += pageSize {
+		page := make(map[uint]*dtos.SummaryBalance)
+		for _, accountID := range accountIDs[start:min(start+pageSize, len(accountIDs))] {
+			page[accountID] = m.summaries[accountID]
+		}
+		m.pages++
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type MockNotifier struct {
+	mu   sync.Mutex
+	sent []uint
+	err  error
+}
+
+func (m *MockNotifier) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, accountID)
+	return "message-id", m.err
+}
+
+func (m *MockNotifier) Channel() string {
+	return notificationdtos.ChannelEmail
+}
+
+type MockPreferenceRepository struct {
+	preferences map[uint]*notificationdtos.Preferences
+}
+
+func (m *MockPreferenceRepository) GetPreferences(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.Preferences, error) {
+	return m.preferences, nil
+}
+
+type MockSendLog struct {
+	mu       sync.Mutex
+	entries  map[uint]*notificationdtos.SendLogEntry
+	lastSent map[uint]time.Time
+}
+
+func (m *MockSendLog) Record(ctx context.Context, entry *notificationdtos.SendLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.AccountID] = entry
+	return nil
+}
+
+func (m *MockSendLog) GetLastSent(ctx context.Context, channel, kind string, accountIDs []uint) (map[uint]time.Time, error) {
+	return m.lastSent, nil
+}
+
+func TestSender_SendSkipsOptedOutAccounts(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	unsubscribedAt := now.AddDate(0, -1, 0)
+
+	weekly := notificationdtos.DefaultPreferences(3)
+	weekly.Frequency = notificationdtos.FrequencyWeekly
+
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}, 3: {}}}
+	notifier := &MockNotifier{}
+	preferences := &MockPreferenceRepository{preferences: map[uint]*notificationdtos.Preferences{
+		1: notificationdtos.DefaultPreferences(1),
+		2: {AccountID: 2, UnsubscribedAt: &unsubscribedAt},
+		3: weekly,
+	}}
+	sendLog := &MockSendLog{
+		entries:  map[uint]*notificationdtos.SendLogEntry{},
+		lastSent: map[uint]time.Time{3: now.AddDate(0, 0, -2)},
+	}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, preferences, sendLog, nil, zap.NewNop())
+	sender.now = func() time.Time { return now }
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	assert.Equal(t, 2, report.Skipped)
+
+	assert.Equal(t, []uint{1}, notifier.sent)
+	assert.Equal(t, notificationdtos.StatusSent, sendLog.entries[1].Status)
+	assert.Equal(t, notificationdtos.StatusSkipped, sendLog.entries[2].Status)
+	assert.Equal(t, notifications.ReasonUnsubscribed, sendLog.entries[2].Reason)
+	assert.Equal(t, notificationdtos.StatusSkipped, sendLog.entries[3].Status)
+	assert.Equal(t, notifications.ReasonFrequency, sendLog.entries[3].Reason)
+}
+
+func TestSender_SendRecordsFailures(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}}}
+	notifier := &MockNotifier{err: errors.New("smtp unavailable")}
+	sendLog := &MockSendLog{entries: map[uint]*notificationdtos.SendLogEntry{}}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, sendLog, nil, zap.NewNop())
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, report.HasFailures())
+	// the error is not transient so it is not retried
+	assert.Len(t, notifier.sent, 1)
+
+	assert.Equal(t, notificationdtos.StatusFailed, sendLog.entries[1].Status)
+	assert.Equal(t, "smtp unavailable", sendLog.entries[1].Reason)
+}
+
+type MockDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries map[string]*notificationdtos.Delivery
+}
+
+func (m *MockDeliveryRepository) id(accountID uint, channel, key string) string {
+	return fmt.Sprintf("%d/%s/%s", accountID, channel, key)
+}
+
+func (m *MockDeliveryRepository) Claim(ctx context.Context, accountID uint, channel, key string, lease time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery, ok := m.deliveries[m.id(accountID, channel, key)]
+	if ok && delivery.Status != notificationdtos.DeliveryFailed {
+		return false, nil
+	}
+	if !ok {
+		delivery = &notificationdtos.Delivery{AccountID: accountID, Channel: channel, IdempotencyKey: key}
+		m.deliveries[m.id(accountID, channel, key)] = delivery
+	}
+	delivery.Status = notificationdtos.DeliveryPending
+	delivery.Attempts++
+	return true, nil
+}
+
+func (m *MockDeliveryRepository) Complete(ctx context.Context, accountID uint, channel, key, messageID string, sendErr error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery := m.deliveries[m.id(accountID, channel, key)]
+	delivery.Status = notificationdtos.DeliverySent
+	delivery.ProviderMessageID = messageID
+	if sendErr != nil {
+		delivery.Status = notificationdtos.DeliveryFailed
+		delivery.LastError = sendErr.Error()
+	}
+	return nil
+}
+
+func (m *MockDeliveryRepository) GetFailed(ctx context.Context, keys []string) ([]*notificationdtos.Delivery, error) {
+	var failed []*notificationdtos.Delivery
+	for _, delivery := range m.deliveries {
+		for _, key := range keys {
+			if delivery.IdempotencyKey == key && delivery.Status == notificationdtos.DeliveryFailed {
+				failed = append(failed, delivery)
+			}
+		}
+	}
+	return failed, nil
+}
+
+type MockFailingNotifier struct {
+	MockNotifier
+	failing map[uint]bool
+}
+
+func (m *MockFailingNotifier) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	m.MockNotifier.Send(ctx, accountID, summary)
+	if m.failing[accountID] {
+		return "", errors.New("mailbox unavailable")
+	}
+	return fmt.Sprintf("<%d@storid.com>", accountID), nil
+}
+
+func TestSender_SendIsIdempotent(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}}}
+	notifier := &MockFailingNotifier{failing: map[uint]bool{2: true}}
+	deliveries := &MockDeliveryRepository{deliveries: map[string]*notificationdtos.Delivery{}}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, deliveries, zap.NewNop())
+	sender.now = func() time.Time { return time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC) }
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	assert.Equal(t, 1, report.Failed)
+	assert.ElementsMatch(t, []uint{1, 2}, notifier.sent)
+
+	delivered := deliveries.deliveries["1/email/summary:2024-03-10"]
+	assert.Equal(t, notificationdtos.DeliverySent, delivered.Status)
+	assert.Equal(t, "<1@storid.com>", delivered.ProviderMessageID)
+	assert.Equal(t, "mailbox unavailable", deliveries.deliveries["2/email/summary:2024-03-10"].LastError)
+
+	// the retried run only sends the failed summary
+	notifier.sent = nil
+	notifier.failing = nil
+	_, err = sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, notifier.sent)
+	assert.Equal(t, 2, deliveries.deliveries["2/email/summary:2024-03-10"].Attempts)
+
+	notifier.sent = nil
+	report, err = sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Empty(t, notifier.sent)
+
+	// another key is a new summary
+	notifier.sent = nil
+	_, err = sender.Send(context.Background(), WithIdempotencyKey("2024-03-11"))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uint{1, 2}, notifier.sent)
+}
+
+func TestSender_SendResendFailed(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}}}
+	notifier := &MockFailingNotifier{failing: map[uint]bool{2: true}}
+	deliveries := &MockDeliveryRepository{deliveries: map[string]*notificationdtos.Delivery{}}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, deliveries, zap.NewNop())
+
+	_, err := sender.Send(context.Background(), WithIdempotencyKey("run-1"))
+	assert.NoError(t, err)
+
+	// account 3 was never sent, only the failed account 2 is sent again
+	generator.summaries = map[uint]*dtos.SummaryBalance{1: {}, 2: {}, 3: {}}
+	notifier.sent = nil
+	notifier.failing = nil
+	_, err = sender.Send(context.Background(), WithIdempotencyKey("run-1"), WithResendFailed())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, notifier.sent)
+	assert.Equal(t, notificationdtos.DeliverySent, deliveries.deliveries["2/email/summary:run-1"].Status)
+
+	_, err = NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop()).Send(context.Background(), WithResendFailed())
+	assert.Error(t, err)
+}
+
+type MockFlakyNotifier struct {
+	MockNotifier
+	failures int
+	err      error
+	running  int
+	busiest  int
+}
+
+func (m *MockFlakyNotifier) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	m.mu.Lock()
+	m.running++
+	m.busiest = max(m.busiest, m.running)
+	m.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running--
+	m.sent = append(m.sent, accountID)
+	if len(m.sent) <= m.failures {
+		return "", m.err
+	}
+	return "message-id", nil
+}
+
+func TestSender_SendRetriesTransientFailures(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}}}
+	notifier := &MockFlakyNotifier{failures: 2, err: &textproto.Error{Code: 421, Msg: "try again later"}}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop())
+	sender.RetryBackoff = time.Millisecond
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	assert.Len(t, notifier.sent, 3)
+
+	// the retries are exhausted
+	notifier.sent = nil
+	notifier.failures = 10
+	sender.MaxRetries = 1
+
+	report, err = sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Len(t, notifier.sent, 2)
+
+	// a rejected address is not retried
+	notifier.sent = nil
+	notifier.err = &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+
+	report, err = sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Len(t, notifier.sent, 1)
+}
+
+func TestSender_SendLimitsWorkers(t *testing.T) {
+	summaries := make(map[uint]*dtos.SummaryBalance)
+	for accountID := uint(1); accountID <= 20; accountID++ {
+		summaries[accountID] = &dtos.SummaryBalance{}
+	}
+	notifier := &MockFlakyNotifier{}
+
+	sender := NewSender(&MockSummaryGenerator{summaries: summaries}, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop())
+	sender.Workers = 3
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 20, report.Sent)
+	assert.LessOrEqual(t, notifier.busiest, 3)
+}
+
+func TestSender_SendRateLimit(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}, 3: {}, 4: {}, 5: {}}}
+	notifier := &MockNotifier{}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop())
+	sender.RateLimits = map[string]float64{notificationdtos.ChannelEmail: 100}
+
+	started := time.Now()
+	_, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, notifier.sent, 5)
+	// the first notification is sent right away and the next ones every 10ms
+	assert.GreaterOrEqual(t, time.Since(started), 40*time.Millisecond)
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(fmt.Errorf("error sending email: %w", &textproto.Error{Code: 451})))
+	assert.True(t, IsTransient(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.True(t, IsTransient(Transient(errors.New("service unavailable"))))
+	assert.False(t, IsTransient(&textproto.Error{Code: 550}))
+	assert.False(t, IsTransient(errors.New("error parsing template")))
+	assert.False(t, IsTransient(context.Canceled))
+}
+
+func TestSender_SendSelectedAccounts(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{7: {}}}
+	notifier := &MockNotifier{}
+	selector := dtos.AccountSelector{AccountIDs: []uint{7}, ActiveSince: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}
+
+	sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop())
+
+	_, err := sender.Send(context.Background(), WithAccounts(selector))
+	assert.NoError(t, err)
+	assert.Equal(t, selector, generator.selector)
+	assert.Equal(t, []uint{7}, notifier.sent)
+
+	_, err = sender.SendStatements(context.Background(), 1, 1, WithAccounts(selector))
+	assert.Error(t, err)
+}
+
+func TestSender_SendStatementsCountsGenerationFailures(t *testing.T) {
+	generator := &MockStatementGenerator{
+		accountIDs: []uint{1, 2, 3},
+		errs: map[uint]error{
+			2: errors.New("connection refused"),
+			3: fmt.Errorf("period of account 3: %w", statements.ErrPeriodNotEnded),
+		},
+	}
+	notifier := &MockNotifier{}
+
+	sender := NewSender(nil, []Notifier{notifier}, nil, generator, nil, nil, nil, zap.NewNop())
+
+	report, err := sender.SendStatements(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1}, notifier.sent)
+	assert.Equal(t, 1, report.Sent)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+	assert.True(t, report.HasFailures())
+}
+
+func TestSender_SendPeriodStatements(t *testing.T) {
+	generator := &MockStatementGenerator{accountIDs: []uint{1}}
+	notifier := &MockNotifier{}
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	sender := NewSender(nil, []Notifier{notifier}, nil, generator, nil, nil, nil, zap.NewNop())
+
+	report, err := sender.SendPeriodStatements(context.Background(), from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	assert.Equal(t, []uint{1}, notifier.sent)
+}
+
+func TestSender_SendPages(t *testing.T) {
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}, 3: {}, 4: {}, 5: {}}}
+	notifier := &MockNotifier{}
+	subscriptions := &MockSubscriptionRepository{}
+
+	sender := NewSender(generator, []Notifier{notifier}, subscriptions, nil, nil, nil, nil, zap.NewNop())
+	sender.PageSize = 2
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, generator.pages)
+	// the subscriptions are loaded once per page
+	assert.Equal(t, 3, subscriptions.calls)
+	assert.Len(t, generator.summaries[5].Subscriptions, 1)
+	assert.Equal(t, 5, report.Sent)
+	assert.ElementsMatch(t, []uint{1, 2, 3, 4, 5}, notifier.sent)
+}
+
+// syntheticGenerator builds the summaries of the accounts a page at a time and keeps the highest heap in use.
+type syntheticGenerator struct {
+	accounts int
+	peakHeap uint64
+}
+
+func (g *syntheticGenerator) EachSummaryPage(ctx context.Context, selector dtos.AccountSelector, pageSize int, fn func(summaries map[uint]*dtos.SummaryBalance) error) error {
+	var stats runtime.MemStats
+	for start := 1; start <= g.accounts; start += pageSize {
+		page := make(map[uint]*dtos.SummaryBalance, pageSize)
+		for accountID := uint(start); accountID < uint(min(start+pageSize, g.accounts+1)); accountID++ {
+			page[accountID] = &dtos.SummaryBalance{
+				Balance: dtos.Balance{AccountID: accountID, TotalBalance: int(accountID) * 100, TransactionCount: 12},
+				MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+					months.January:  {Balance: dtos.Balance{AccountID: accountID, TotalBalance: 100}},
+					months.February: {Balance: dtos.Balance{AccountID: accountID, TotalBalance: -50}},
+				},
+			}
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+
+		runtime.ReadMemStats(&stats)
+		g.peakHeap = max(g.peakHeap, stats.HeapInuse)
+	}
+	return nil
+}
+
+type countingNotifier struct {
+	sent atomic.Int64
+}
+
+func (n *countingNotifier) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	n.sent.Add(1)
+	return "", nil
+}
+
+func (n *countingNotifier) Channel() string {
+	return notificationdtos.ChannelEmail
+}
+
+// BenchmarkSender_Send1MAccounts measures the memory held by the sender while it pages through 1M accounts,
+// the peak heap should stay the same as the accounts grow. The summaries are built in memory by
+// syntheticGenerator, so the queries and the scanning of BalancesDBRepository are not measured.
+func BenchmarkSender_Send1MAccounts(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		generator := &syntheticGenerator{accounts: 1_000_000}
+		notifier := &countingNotifier{}
+
+		sender := NewSender(generator, []Notifier{notifier}, nil, nil, nil, nil, nil, zap.NewNop())
+		report, err := sender.Send(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if report.Sent != generator.accounts {
+			b.Fatalf("sent %d summaries, want %d", report.Sent, generator.accounts)
+		}
+
+		b.ReportMetric(float64(generator.peakHeap)/(1<<20), "peak-heap-MB")
+	}
+}
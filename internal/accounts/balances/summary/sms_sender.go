@@ -0,0 +1,103 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/juaguz/storid/internal/platform/sms"
+)
+
+// DefaultSMSSegments the summaries fit in a single SMS.
+const DefaultSMSSegments = 1
+
+type PhoneRepository interface {
+	GetPhones(ctx context.Context, accountIDs []uint) (map[uint]string, error)
+}
+
+// SMSProvider Send returns the ID the provider gave to the message.
+type SMSProvider interface {
+	Send(ctx context.Context, to, body string) (string, error)
+}
+
+// SMSSender sends a short text version of the summary to the phone of the account.
+type SMSSender struct {
+	AccountRepository AccountRepository
+	PhoneRepository   PhoneRepository
+	Provider          SMSProvider
+	// MaxSegments longer messages are truncated
+	MaxSegments int
+	now         func() time.Time
+}
+
+func NewSMSSender(accounts AccountRepository, phones PhoneRepository, provider SMSProvider) *SMSSender {
+	return &SMSSender{
+		AccountRepository: accounts,
+		PhoneRepository:   phones,
+		Provider:          provider,
+		MaxSegments:       DefaultSMSSegments,
+		now:               time.Now,
+	}
+}
+
+func (ss *SMSSender) Channel() string {
+	return notificationdtos.ChannelSMS
+}
+
+// HasRecipients the accounts without a phone are skipped.
+func (ss *SMSSender) HasRecipients(ctx context.Context, accountIDs []uint) (map[uint]bool, error) {
+	phones, err := ss.PhoneRepository.GetPhones(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[uint]bool, len(phones))
+	for accountID := range phones {
+		recipients[accountID] = true
+	}
+	return recipients, nil
+}
+
+// Send returns the ID of the message, the provider errors that can be retried are transient.
+func (ss *SMSSender) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	act, err := ss.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return "", fmt.Errorf("error getting account by ID: %w", err)
+	}
+
+	if act.Phone == "" {
+		return "", fmt.Errorf("account %d has no phone", accountID)
+	}
+
+	body := sms.Fit(SMSText(act.Locale, summary, ss.now()), ss.MaxSegments)
+
+	id, err := ss.Provider.Send(ctx, act.Phone, body)
+	if err != nil {
+		return "", fmt.Errorf("error sending sms: %w", err)
+	}
+
+	return id, nil
+}
+
+// SMSText is the balance and the transactions of the last month, or the closing balance of the statement.
+func SMSText(locale string, summary *dtos.SummaryBalance, now time.Time) string {
+	switch {
+	case len(summary.Alerts) > 0:
+		return i18n.T(locale, "sms_alert")
+	case summary.Statement != nil:
+		return i18n.T(locale, "sms_statement", summary.Statement.Sequence, i18n.FormatCents(locale, summary.Statement.ClosingBalance))
+	}
+
+	lastMonth := months.Month(now.AddDate(0, 0, -now.Day()).Month())
+
+	count := 0
+	if monthly, ok := summary.MonthlyBalance[lastMonth]; ok {
+		count = monthly.TransactionCount
+	}
+
+	return i18n.T(locale, "sms_summary", i18n.FormatCents(locale, summary.TotalBalance), count, i18n.MonthName(locale, lastMonth))
+}
@@ -0,0 +1,93 @@
+package summary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	analysisdtos "github.com/juaguz/storid/internal/accounts/analysis/dtos"
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	"github.com/juaguz/storid/internal/accounts/notifications"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/juaguz/storid/internal/platform/sms"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockPhoneAccountRepository struct {
+	phones map[uint]string
+	locale string
+}
+
+func (m *MockPhoneAccountRepository) GetAccountByID(accountID uint) (*accountdtos.Account, error) {
+	return &accountdtos.Account{Email: "test@storid.com", Locale: m.locale, Phone: m.phones[accountID]}, nil
+}
+
+func (m *MockPhoneAccountRepository) GetPhones(ctx context.Context, accountIDs []uint) (map[uint]string, error) {
+	return m.phones, nil
+}
+
+func TestSMSSender_Send(t *testing.T) {
+	accounts := &MockPhoneAccountRepository{phones: map[uint]string{1: "+5491122334455"}, locale: "en"}
+	provider := sms.NewFakeProvider(zap.NewNop())
+
+	sender := NewSMSSender(accounts, accounts, provider)
+	sender.now = func() time.Time { return time.Date(2024, time.April, 10, 12, 0, 0, 0, time.UTC) }
+
+	summary := &dtos.SummaryBalance{
+		Balance: dtos.Balance{AccountID: 1, TotalBalance: 150000},
+		MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{
+			months.March: {Balance: dtos.Balance{TransactionCount: 12}},
+			months.April: {Balance: dtos.Balance{TransactionCount: 3}},
+		},
+	}
+
+	id, err := sender.Send(context.Background(), 1, summary)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-1", id)
+	assert.Equal(t, []sms.Message{{To: "+5491122334455", Body: "Storid: your balance is $1,500.00, 12 transactions in March."}}, provider.Sent())
+
+	_, err = sender.Send(context.Background(), 2, summary)
+	assert.Error(t, err)
+}
+
+func TestSMSText(t *testing.T) {
+	now := time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)
+	summary := &dtos.SummaryBalance{
+		Balance:        dtos.Balance{TotalBalance: -2550},
+		MonthlyBalance: map[months.Month]*dtos.MonthlyBalance{months.December: {Balance: dtos.Balance{TransactionCount: 4}}},
+	}
+
+	text := SMSText("en", summary, now)
+	assert.Equal(t, "Storid: your balance is -$25.50, 4 transactions in December.", text)
+	assert.Equal(t, sms.GSM7, sms.Encoding(text))
+
+	// the accents of the spanish texts are not in the GSM 7 bit alphabet
+	text = SMSText("es", &dtos.SummaryBalance{Statement: &statementdtos.Statement{Sequence: 2, ClosingBalance: 100000}}, now)
+	assert.Equal(t, "Storid: tu extracto N.º 2 está listo, saldo final $ 1.000,00.", text)
+	assert.Equal(t, sms.UCS2, sms.Encoding(text))
+	assert.Equal(t, 1, sms.Segments(text))
+
+	for _, locale := range []string{"en", "es"} {
+		text = SMSText(locale, &dtos.SummaryBalance{Alerts: []*analysisdtos.Alert{{}}}, now)
+		assert.Equal(t, 1, sms.Segments(text), locale)
+	}
+}
+
+func TestSender_SendSkipsAccountsWithoutPhone(t *testing.T) {
+	accounts := &MockPhoneAccountRepository{phones: map[uint]string{1: "+5491122334455"}}
+	provider := sms.NewFakeProvider(zap.NewNop())
+	generator := &MockSummaryGenerator{summaries: map[uint]*dtos.SummaryBalance{1: {}, 2: {}}}
+	sendLog := &MockSendLog{entries: map[uint]*notificationdtos.SendLogEntry{}}
+
+	sender := NewSender(generator, []Notifier{NewSMSSender(accounts, accounts, provider)}, nil, nil, nil, sendLog, nil, zap.NewNop())
+
+	report, err := sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	assert.Len(t, provider.Sent(), 1)
+	assert.Equal(t, notifications.ReasonNoRecipient, sendLog.entries[2].Reason)
+}
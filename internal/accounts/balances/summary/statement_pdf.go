@@ -0,0 +1,109 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/juaguz/storid/internal/platform/notifications"
+	"github.com/juaguz/storid/internal/platform/pdf"
+)
+
+type TransactionRepository interface {
+	FindByAccountID(ctx context.Context, accountID uint) ([]*transactiondto.Transaction, error)
+	FindByAccountIDAndPeriod(ctx context.Context, accountID uint, from, to time.Time) ([]*transactiondto.Transaction, error)
+}
+
+var (
+	summaryColumns     = []float64{200, 150}
+	transactionColumns = []float64{100, 80, 180, 100}
+)
+
+// StatementPDF attaches a downloadable statement to the summary email, when the summary
+// is a cycle statement only its transactions are listed, otherwise the whole history.
+type StatementPDF struct {
+	TransactionRepository TransactionRepository
+}
+
+func NewStatementPDF(transactions TransactionRepository) *StatementPDF {
+	return &StatementPDF{
+		TransactionRepository: transactions,
+	}
+}
+
+// Build writes the statement in the locale of the account.
+func (sp *StatementPDF) Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error) {
+	transactions, err := sp.transactions(ctx, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := account.Locale
+	doc := pdf.New()
+	doc.Heading(i18n.T(locale, "account_statement"))
+	doc.Text(account.FullName())
+	doc.Text(account.Email)
+
+	filename := "statement.pdf"
+	if st := summary.Statement; st != nil {
+		filename = fmt.Sprintf("statement-%d.pdf", st.Sequence)
+		doc.Text(i18n.T(locale, "statement_period", st.Sequence, st.PeriodStart.Format(time.DateOnly), st.LastDay().Format(time.DateOnly)))
+		doc.Space(pdf.TextSize)
+		doc.Row(true, summaryColumns, i18n.T(locale, "opening_balance"), i18n.FormatCents(locale, st.OpeningBalance))
+		doc.Row(false, summaryColumns, i18n.T(locale, "credits_count", st.CreditCount), i18n.FormatCents(locale, st.TotalCredits))
+		doc.Row(false, summaryColumns, i18n.T(locale, "debits_count", st.DebitCount), i18n.FormatCents(locale, st.TotalDebits))
+		doc.Row(true, summaryColumns, i18n.T(locale, "closing_balance"), i18n.FormatCents(locale, st.ClosingBalance))
+	} else {
+		doc.Space(pdf.TextSize)
+		doc.Row(true, summaryColumns, i18n.T(locale, "total_balance"), i18n.FormatCents(locale, summary.TotalBalance))
+		doc.Row(false, summaryColumns, i18n.T(locale, "avg_debit_amount"), i18n.FormatCents(locale, summary.AvrDebitAmount))
+		doc.Row(false, summaryColumns, i18n.T(locale, "avg_credit_amount"), i18n.FormatCents(locale, summary.AvrCreditAmount))
+		doc.Row(false, summaryColumns, i18n.T(locale, "transactions"), strconv.Itoa(summary.TransactionCount))
+	}
+
+	if len(summary.MonthlyBalance) > 0 {
+		doc.Space(pdf.TextSize)
+		doc.Heading(i18n.T(locale, "monthly_transactions"))
+		doc.Row(true, summaryColumns, i18n.T(locale, "month"), i18n.T(locale, "transaction_count"))
+		for _, month := range months.OrderMonths {
+			if balance, ok := summary.MonthlyBalance[month]; ok {
+				doc.Row(false, summaryColumns, i18n.MonthName(locale, month), strconv.Itoa(balance.TransactionCount))
+			}
+		}
+	}
+
+	doc.Space(pdf.TextSize)
+	doc.Heading(i18n.T(locale, "transactions"))
+	doc.Row(true, transactionColumns, i18n.T(locale, "date"), i18n.T(locale, "type"), i18n.T(locale, "category"), i18n.T(locale, "amount"))
+	for _, t := range transactions {
+		doc.Row(false, transactionColumns, t.Date.Format(time.DateOnly), i18n.T(locale, string(t.Type)), t.Category, i18n.FormatCents(locale, t.Amount))
+	}
+	if len(transactions) == 0 {
+		doc.Text(i18n.T(locale, "no_transactions"))
+	}
+
+	return &notifications.Attachment{
+		Filename:    filename,
+		ContentType: "application/pdf",
+		Content:     doc.Bytes(),
+	}, nil
+}
+
+func (sp *StatementPDF) transactions(ctx context.Context, summary *dtos.SummaryBalance) ([]*transactiondto.Transaction, error) {
+	if summary.Statement != nil {
+		return summary.Statement.Transactions, nil
+	}
+
+	transactions, err := sp.TransactionRepository.FindByAccountID(ctx, summary.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions for the statement: %w", err)
+	}
+
+	return transactions, nil
+}
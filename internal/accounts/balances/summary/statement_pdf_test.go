@@ -0,0 +1,39 @@
+package summary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	statementdtos "github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementPDF_BuildLocalized(t *testing.T) {
+	statement := &statementdtos.Statement{
+		Sequence:       2,
+		PeriodStart:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:      time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 123456,
+		Transactions: []*transactiondto.Transaction{
+			{Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), Type: transactiondto.Debit, Category: "food", Amount: -1050},
+		},
+	}
+	account := &accountdtos.Account{Name: "Ana", Email: "ana@storid.com", Locale: "es-AR"}
+
+	attachment, err := NewStatementPDF(nil).Build(context.Background(), account, &dtos.SummaryBalance{Statement: statement})
+	assert.NoError(t, err)
+	assert.Equal(t, "statement-2.pdf", attachment.Filename)
+
+	content := string(attachment.Content)
+	assert.Contains(t, content, "Extracto de cuenta")
+	assert.Contains(t, content, `Extracto N.\272 2 \(2024-03-01 - 2024-03-31\)`)
+	assert.Contains(t, content, "Saldo inicial")
+	assert.Contains(t, content, "$ 1.234,56")
+	assert.Contains(t, content, `D\351bito`)
+	assert.Contains(t, content, "-$ 10,50")
+	assert.NotContains(t, content, "Opening Balance")
+}
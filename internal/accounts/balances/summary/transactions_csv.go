@@ -0,0 +1,79 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	accountdtos "github.com/juaguz/storid/internal/accounts/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/juaguz/storid/internal/platform/currencies"
+	"github.com/juaguz/storid/internal/platform/notifications"
+)
+
+const (
+	// CSVDeliveryAttachment the CSV is attached to the email
+	CSVDeliveryAttachment = "attachment"
+	// CSVDeliveryS3 the CSV is uploaded to S3 and linked in the email body
+	CSVDeliveryS3 = "s3"
+)
+
+var csvHeader = []string{"external_id", "date", "type", "category", "amount"}
+
+// TransactionsCSV exports the transactions that make up the summary, the statement
+// transactions for a cycle statement or every transaction up to the moment of the export otherwise.
+type TransactionsCSV struct {
+	TransactionRepository TransactionRepository
+}
+
+func NewTransactionsCSV(transactions TransactionRepository) *TransactionsCSV {
+	return &TransactionsCSV{
+		TransactionRepository: transactions,
+	}
+}
+
+func (tc *TransactionsCSV) Build(ctx context.Context, account *accountdtos.Account, summary *dtos.SummaryBalance) (*notifications.Attachment, error) {
+	filename := "transactions.csv"
+	var transactions []*transactiondto.Transaction
+	if st := summary.Statement; st != nil {
+		filename = fmt.Sprintf("transactions-%d.csv", st.Sequence)
+		transactions = st.Transactions
+	} else {
+		var err error
+		transactions, err = tc.TransactionRepository.FindByAccountIDAndPeriod(ctx, summary.AccountID, time.Time{}, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("error getting transactions for the export: %w", err)
+		}
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		err := writer.Write([]string{
+			t.ExternalID,
+			t.Date.Format(time.DateOnly),
+			string(t.Type),
+			t.Category,
+			currencies.CentsToString(t.Amount),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("error writing transactions csv: %w", err)
+	}
+
+	return &notifications.Attachment{
+		Filename:    filename,
+		ContentType: "text/csv",
+		Content:     buffer.Bytes(),
+	}, nil
+}
@@ -0,0 +1,263 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/balances/dtos"
+	notificationdtos "github.com/juaguz/storid/internal/accounts/notifications/dtos"
+)
+
+const (
+	// WebhookIDHeader identifies the notification, it is the same on every retry so the partners can ignore duplicates
+	WebhookIDHeader = "X-Storid-Webhook-Id"
+	// WebhookTimestampHeader unix time the payload was signed at, the partners should reject the old ones
+	WebhookTimestampHeader = "X-Storid-Timestamp"
+	// WebhookSignatureHeader "v1=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>"
+	WebhookSignatureHeader = "X-Storid-Signature"
+
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+type WebhookRepository interface {
+	GetWebhooks(ctx context.Context, accountIDs []uint) (map[uint]*notificationdtos.Webhook, error)
+}
+
+// WebhookPayload is posted as JSON, Event is "summary", "statement" or "alert".
+type WebhookPayload struct {
+	ID        string               `json:"id"`
+	Event     string               `json:"event"`
+	AccountID uint                 `json:"account_id"`
+	CreatedAt time.Time            `json:"created_at"`
+	Summary   *dtos.SummaryBalance `json:"summary"`
+}
+
+// WebhookSender posts the summaries to the webhook of the account, DefaultURL and DefaultSecret
+// are used for the accounts without one e.g. a partner that receives the summaries of every account.
+type WebhookSender struct {
+	WebhookRepository WebhookRepository
+	Client            *http.Client
+	DefaultURL        string
+	DefaultSecret     string
+	now               func() time.Time
+}
+
+func NewWebhookSender(repository WebhookRepository, defaultURL, defaultSecret string, timeout time.Duration) *WebhookSender {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+
+	return &WebhookSender{
+		WebhookRepository: repository,
+		Client:            newWebhookClient(timeout),
+		DefaultURL:        defaultURL,
+		DefaultSecret:     defaultSecret,
+		now:               time.Now,
+	}
+}
+
+func (ws *WebhookSender) Channel() string {
+	return notificationdtos.ChannelWebhook
+}
+
+// HasRecipients the accounts without a webhook are skipped unless there is a default one.
+func (ws *WebhookSender) HasRecipients(ctx context.Context, accountIDs []uint) (map[uint]bool, error) {
+	recipients := make(map[uint]bool, len(accountIDs))
+	if ws.DefaultURL != "" {
+		for _, accountID := range accountIDs {
+			recipients[accountID] = true
+		}
+		return recipients, nil
+	}
+
+	webhooks, err := ws.WebhookRepository.GetWebhooks(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	for accountID := range webhooks {
+		recipients[accountID] = true
+	}
+
+	return recipients, nil
+}
+
+// Send returns the ID of the webhook, the 429 and 5xx responses are transient so the sender retries them.
+func (ws *WebhookSender) Send(ctx context.Context, accountID uint, summary *dtos.SummaryBalance) (string, error) {
+	webhook, err := ws.webhook(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ValidateWebhookURL(webhook.URL); err != nil {
+		return "", err
+	}
+
+	id, err := notificationID(ctx, accountID, ws.Channel())
+	if err != nil {
+		return "", err
+	}
+
+	now := ws.now()
+	body, err := json.Marshal(&WebhookPayload{
+		ID:        id,
+		Event:     webhookEvent(summary),
+		AccountID: accountID,
+		CreatedAt: now.UTC(),
+		Summary:   summary,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating webhook request: %w", err)
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, id)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook([]byte(webhook.Secret), timestamp, body))
+
+	resp, err := ws.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error posting webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return id, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return "", Transient(fmt.Errorf("webhook responded %d", resp.StatusCode))
+	default:
+		return "", fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+}
+
+// SignWebhook the timestamp is part of the signature so a captured payload can not be replayed later.
+func SignWebhook(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ws *WebhookSender) webhook(ctx context.Context, accountID uint) (*notificationdtos.Webhook, error) {
+	webhooks, err := ws.WebhookRepository.GetWebhooks(ctx, []uint{accountID})
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, ok := webhooks[accountID]
+	if !ok {
+		if ws.DefaultURL == "" {
+			return nil, fmt.Errorf("account %d has no webhook", accountID)
+		}
+		webhook = &notificationdtos.Webhook{AccountID: accountID, URL: ws.DefaultURL}
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret = ws.DefaultSecret
+	}
+	if webhook.Secret == "" {
+		return nil, fmt.Errorf("webhook of account %d has no secret", accountID)
+	}
+
+	return webhook, nil
+}
+
+func webhookEvent(summary *dtos.SummaryBalance) string {
+	switch {
+	case len(summary.Alerts) > 0:
+		return "alert"
+	case summary.Statement != nil:
+		return notificationdtos.KindStatement
+	default:
+		return notificationdtos.KindSummary
+	}
+}
+
+// notificationID is derived from the account, the channel and the idempotency key of the delivery so it is the
+// same on every retry, the notifications sent outside of the Sender e.g. the alerts get a random one.
+func notificationID(ctx context.Context, accountID uint, channel string) (string, error) {
+	if key := DeliveryKey(ctx); key != "" {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s", accountID, channel, key)))
+		return hex.EncodeToString(sum[:16]), nil
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating webhook ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// ValidateWebhookURL only http and https URLs with a host are posted to. The hosts that are loopback,
+// private, link-local (e.g. the cloud metadata endpoint) or unspecified IPs are rejected so a webhook
+// can not reach the internal network, the names are checked when they are resolved, see newWebhookClient.
+func ValidateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("invalid webhook URL scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("invalid webhook URL host %q", u.Host)
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !webhookIPAllowed(ip) {
+		return fmt.Errorf("webhook URL host %s is not allowed", ip)
+	}
+
+	return nil
+}
+
+// webhookIPAllowed the tests allow the loopback IPs of their servers.
+var webhookIPAllowed = func(ip net.IP) bool {
+	return !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast()
+}
+
+// newWebhookClient checks the address every connection is made to, a public name could resolve to an
+// internal IP. The proxies are not used because the address dialed would be the one of the proxy.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !webhookIPAllowed(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -1,5 +1,37 @@
 package dtos
 
+import (
+	"net/mail"
+	"strings"
+)
+
+// DefaultTier is used for the accounts without a tier.
+const DefaultTier = "standard"
+
 type Account struct {
-	Email string `json:"email"`
+	Name     string `json:"name"`
+	LastName string `json:"last_name"`
+	Email    string `json:"email"`
+	Tier     string `json:"tier"`
+	Locale   string `json:"locale"`
+	// Phone in E.164 format e.g. +5491122334455, empty when the account has none
+	Phone string `json:"phone"`
+}
+
+// Recipient returns the email address with the name of the account, e.g. "Jane Doe <jane@storid.com>".
+func (a *Account) Recipient() string {
+	name := strings.TrimSpace(a.Name + " " + a.LastName)
+	if name == "" {
+		return a.Email
+	}
+	return (&mail.Address{Name: name, Address: a.Email}).String()
+}
+
+// FullName returns the name and last name, or the email when the account has no name.
+func (a *Account) FullName() string {
+	name := strings.TrimSpace(a.Name + " " + a.LastName)
+	if name == "" {
+		return a.Email
+	}
+	return name
 }
@@ -17,11 +17,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/docker/docker/api/types/container"
+	"github.com/juaguz/storid/internal/accounts/analysis"
+	analysisrepositories "github.com/juaguz/storid/internal/accounts/analysis/repositories"
 	"github.com/juaguz/storid/internal/accounts/balances"
+	balancedtos "github.com/juaguz/storid/internal/accounts/balances/dtos"
 	"github.com/juaguz/storid/internal/accounts/balances/repositories"
 	"github.com/juaguz/storid/internal/accounts/balances/summary"
 	"github.com/juaguz/storid/internal/accounts/models"
+	notificationrepo "github.com/juaguz/storid/internal/accounts/notifications/repositories"
 	accountrepository "github.com/juaguz/storid/internal/accounts/repositories"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	statementrepo "github.com/juaguz/storid/internal/accounts/statements/repositories"
 	"github.com/juaguz/storid/internal/accounts/transactions/importer"
 	transactionrepo "github.com/juaguz/storid/internal/accounts/transactions/repositories"
 	"github.com/juaguz/storid/internal/platform/currencies"
@@ -237,6 +243,11 @@ func TestIntegration(t *testing.T) {
 	eventDispatcher.Register(context.Background(), importer.EventImported, refresher)
 
 	transactionRepository := transactionrepo.NewTransactionRepository(gormDb)
+	subscriptionRepository := analysisrepositories.NewSubscriptionRepository(gormDb)
+
+	detector := analysis.NewSubscriptionDetector(transactionRepository, subscriptionRepository, logger)
+
+	eventDispatcher.Register(context.Background(), importer.EventImported, detector)
 
 	s3Reader := filereaders.NewS3FileReader(s3Client, bucketName)
 
@@ -279,28 +290,107 @@ func TestIntegration(t *testing.T) {
 	assert.Equal(t, 393, monthlyBalance[months.November].TransactionCount)
 	assert.Equal(t, 400, monthlyBalance[months.December].TransactionCount)
 
-	res, err := balanceRepository.GetSummaryBalance(ctx)
+	year := time.Now().Year()
+	dailyBalances, err := balanceRepository.GetDailyBalances(ctx, 8,
+		time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, dailyBalances, time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay())
+	assert.Equal(t, balance.TotalBalance, dailyBalances[len(dailyBalances)-1].Balance)
+
+	res, err := balanceRepository.GetSummaryBalance(ctx, balancedtos.AccountSelector{})
 	assert.NoError(t, err)
 	assert.Equal(t, 20, len(res))
 
+	selected, err := balanceRepository.GetSummaryBalance(ctx, balancedtos.AccountSelector{
+		AccountIDs: []uint{1},
+		Ranges:     []balancedtos.IDRange{{From: 5, To: 7}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, selected, 4)
+	assert.Contains(t, selected, uint(6))
+
+	var pages, paged int
+	err = balanceRepository.EachSummaryPage(ctx, balancedtos.AccountSelector{}, 7, func(summaries map[uint]*balancedtos.SummaryBalance) error {
+		pages++
+		paged += len(summaries)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, len(res), paged)
+
+	incremental := balances.NewIncrementalBalances(gormDb, logger)
+	assert.NoError(t, incremental.Rebuild(ctx))
+
+	mismatches, err := incremental.Check(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	incrementalBalance, err := repositories.NewIncrementalBalancesRepository(gormDb).GetBalanceByAccountID(ctx, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, balance.TotalBalance, incrementalBalance.TotalBalance)
+
 	accountRepository := accountrepository.NewAccountRepository(gormDb)
 
-	emailService := notifications.NewSMTPService(&notifications.SMTPConfig{
+	emailService, err := notifications.NewSMTPService(&notifications.SMTPConfig{
 		Host:     mailHost,
 		Port:     mailPort.Port(),
 		Username: "testuser",
 		Password: "testpass",
+		TLSMode:  notifications.TLSModeNone,
 	}, zap.NewExample())
+	assert.NoError(t, err)
 
 	notifiers := []summary.Notifier{
-		summary.NewEmailSender(accountRepository, emailService),
+		summary.NewEmailSender(accountRepository, emailService, []summary.AttachmentBuilder{summary.NewStatementPDF(transactionRepository)}, nil),
 	}
 
-	sender := summary.NewSender(balanceRepository, notifiers, zap.NewExample())
+	statementGenerator := statements.NewGenerator(transactionRepository, statementrepo.NewStatementRepository(gormDb), logger)
+
+	statement, err := statementGenerator.GenerateCycle(ctx, 8, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, statement.Sequence)
+	assert.Equal(t, statement.OpeningBalance+statement.TotalCredits+statement.TotalDebits, statement.ClosingBalance)
+
+	again, err := statementGenerator.GenerateCycle(ctx, 8, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, statement.Sequence, again.Sequence)
+	assert.Error(t, gormDb.Exec("UPDATE statements SET closing_balance = 0").Error)
+
+	sender := summary.NewSender(balanceRepository, notifiers, subscriptionRepository, statementGenerator, notificationrepo.NewPreferenceRepository(gormDb), notificationrepo.NewSendLogRepository(gormDb), notificationrepo.NewDeliveryRepository(gormDb), zap.NewExample())
+
+	assert.NoError(t, gormDb.Exec("INSERT INTO notification_preferences (account_id, unsubscribed_at) VALUES (1, NOW())").Error)
 
-	err = sender.Send(context.Background())
+	report, err := sender.Send(context.Background())
 	assert.NoError(t, err)
+	assert.False(t, report.HasFailures())
 	verifyEmailReceived(t, fmt.Sprintf("http://%s:%s", mailHost, webPort.Port()), "Summary Balance")
+
+	var skipped int64
+	assert.NoError(t, gormDb.Table("notification_log").Where("account_id = 1 AND status = 'skipped'").Count(&skipped).Error)
+	assert.Equal(t, int64(1), skipped)
+
+	// a retried sender does not send the summaries again
+	report, err = sender.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, len(res), report.Skipped)
+	var delivered int64
+	assert.NoError(t, gormDb.Table("notification_log").Where("reason = 'already_delivered'").Count(&delivered).Error)
+	assert.Equal(t, int64(len(res)-1), delivered)
+
+	report, err = sender.SendStatements(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.False(t, report.HasFailures())
+	verifyEmailReceived(t, fmt.Sprintf("http://%s:%s", mailHost, webPort.Port()), "Account Statement #1")
+
+	// a transaction in the afternoon of the ActiveUntil day selects the account
+	endDay := time.Date(2000, time.January, 15, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, gormDb.Create(&models.Transaction{ExternalID: "end-day", Date: endDay.Add(15 * time.Hour), Amount: 100, Type: "credit", AccountID: 3}).Error)
+	active, err := balanceRepository.GetSummaryBalance(ctx, balancedtos.AccountSelector{ActiveSince: endDay, ActiveUntil: endDay})
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+	assert.Contains(t, active, uint(3))
 }
 
 type Smtp4DevMessage struct {
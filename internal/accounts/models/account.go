@@ -9,5 +9,8 @@ type Account struct {
 	Name         string        `json:"name"`
 	LastName     string        `json:"last_name"`
 	Email        string        `json:"email"`
+	Tier         string        `json:"tier"`
+	Locale       string        `json:"locale"`
+	Phone        string        `json:"phone"`
 	Transactions []Transaction `json:"transactions"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type balanceMixin struct {
 	TotalBalance     int     `json:"total_balance"`
 	TransactionCount int     `json:"transaction_count"`
@@ -42,3 +44,19 @@ type MonthlyBalance struct {
 }
 
 const MonthlyBalanceTable = "monthly_balances"
+
+// DailyBalance Balance is the end of day balance, days without transactions are not stored.
+type DailyBalance struct {
+	AccountID        uint      `json:"account_id"`
+	Day              time.Time `json:"day"`
+	Credits          int       `json:"credits"`
+	Debits           int       `json:"debits"`
+	TransactionCount int       `json:"transaction_count"`
+	Balance          int       `json:"balance"`
+}
+
+const DailyBalanceTable = "daily_balances"
+
+func (DailyBalance) TableName() string {
+	return DailyBalanceTable
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type NotificationPreference struct {
+	AccountID       uint       `json:"account_id" gorm:"primaryKey"`
+	Frequency       string     `json:"frequency"`
+	QuietHoursStart int        `json:"quiet_hours_start"`
+	QuietHoursEnd   int        `json:"quiet_hours_end"`
+	Timezone        string     `json:"timezone"`
+	UnsubscribedAt  *time.Time `json:"unsubscribed_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type NotificationChannelPreference struct {
+	AccountID uint   `json:"account_id" gorm:"primaryKey"`
+	Channel   string `json:"channel" gorm:"primaryKey"`
+	Enabled   bool   `json:"enabled"`
+}
+
+type NotificationLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	AccountID uint      `json:"account_id"`
+	Channel   string    `json:"channel"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+}
+
+const NotificationLogTable = "notification_log"
+
+func (NotificationLog) TableName() string {
+	return NotificationLogTable
+}
+
+type NotificationDelivery struct {
+	AccountID         uint      `json:"account_id" gorm:"primaryKey"`
+	Channel           string    `json:"channel" gorm:"primaryKey"`
+	IdempotencyKey    string    `json:"idempotency_key" gorm:"primaryKey"`
+	Status            string    `json:"status"`
+	Attempts          int       `json:"attempts"`
+	ProviderMessageID string    `json:"provider_message_id"`
+	LastError         string    `json:"last_error"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type NotificationWebhook struct {
+	AccountID uint      `json:"account_id" gorm:"primaryKey"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NotificationChatChannel struct {
+	AccountID uint      `json:"account_id" gorm:"primaryKey"`
+	URL       string    `json:"url"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type RefreshHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	View       string    `json:"view"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+}
+
+const RefreshHistoryTable = "balance_refresh_history"
+
+func (RefreshHistory) TableName() string {
+	return RefreshHistoryTable
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Statement the table rejects updates and deletes, Transactions is the JSON snapshot of the period.
+type Statement struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time `json:"created_at"`
+	AccountID      uint      `json:"account_id"`
+	Sequence       int       `json:"sequence"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	OpeningBalance int       `json:"opening_balance"`
+	ClosingBalance int       `json:"closing_balance"`
+	TotalCredits   int       `json:"total_credits"`
+	TotalDebits    int       `json:"total_debits"`
+	CreditCount    int       `json:"credit_count"`
+	DebitCount     int       `json:"debit_count"`
+	Transactions   string    `json:"transactions"`
+}
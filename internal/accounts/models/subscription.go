@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Subscription struct {
+	gorm.Model
+	AccountID        uint      `json:"account_id"`
+	Merchant         string    `json:"merchant"`
+	Interval         string    `json:"interval"`
+	Amount           int       `json:"amount"`
+	PreviousAmount   int       `json:"previous_amount"`
+	Occurrences      int       `json:"occurrences"`
+	LastDate         time.Time `json:"last_date"`
+	NextExpectedDate time.Time `json:"next_expected_date"`
+	PriceIncreased   bool      `json:"price_increased"`
+}
@@ -12,6 +12,9 @@ type Transaction struct {
 	Date       time.Time `json:"date"`
 	Amount     int       `json:"amount"`
 	Type       string    `json:"type"`
+	Category   string    `json:"category"`
+	// ImportID the import that created the transaction
+	ImportID string `json:"import_id"`
 
 	//To simplify the example I will asume that the accountid in the file is the same as the account id in the database
 	AccountID uint `json:"account_id"`
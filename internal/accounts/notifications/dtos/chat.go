@@ -0,0 +1,15 @@
+package dtos
+
+const (
+	// ChatFormatSlack messages use the Slack Block Kit format
+	ChatFormatSlack = "slack"
+	// ChatFormatTeams messages are Adaptive Cards, the format of the Teams incoming webhooks and workflows
+	ChatFormatTeams = "teams"
+)
+
+// ChatChannel the summaries of the account are posted to the incoming webhook URL of a chat channel.
+type ChatChannel struct {
+	AccountID uint   `json:"account_id"`
+	URL       string `json:"url"`
+	Format    string `json:"format"`
+}
@@ -0,0 +1,24 @@
+package dtos
+
+import "time"
+
+const (
+	// DeliveryPending the notification is being sent, a pending delivery older than the lease can be claimed again
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+// Delivery of a notification, IdempotencyKey identifies what is sent e.g. the summary of a day or a statement,
+// an account receives a key once per channel.
+type Delivery struct {
+	AccountID         uint      `json:"account_id"`
+	Channel           string    `json:"channel"`
+	IdempotencyKey    string    `json:"idempotency_key"`
+	Status            string    `json:"status"`
+	Attempts          int       `json:"attempts"`
+	ProviderMessageID string    `json:"provider_message_id"`
+	LastError         string    `json:"last_error"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package dtos
+
+import "time"
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelSMS     = "sms"
+	ChannelChat    = "chat"
+	// ChannelAll is used to unsubscribe from every channel
+	ChannelAll = "all"
+
+	// FrequencyAlways the summary is sent every time the sender runs
+	FrequencyAlways  = "always"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Preferences of an account, channels missing in Channels are enabled.
+// QuietHoursStart and QuietHoursEnd are hours of the day in Timezone, equal hours mean no quiet hours.
+type Preferences struct {
+	AccountID       uint            `json:"account_id"`
+	Channels        map[string]bool `json:"channels"`
+	Frequency       string          `json:"frequency"`
+	QuietHoursStart int             `json:"quiet_hours_start"`
+	QuietHoursEnd   int             `json:"quiet_hours_end"`
+	Timezone        string          `json:"timezone"`
+	UnsubscribedAt  *time.Time      `json:"unsubscribed_at"`
+}
+
+// DefaultPreferences are used for the accounts without preferences.
+func DefaultPreferences(accountID uint) *Preferences {
+	return &Preferences{
+		AccountID: accountID,
+		Channels:  map[string]bool{},
+		Frequency: FrequencyAlways,
+		Timezone:  "UTC",
+	}
+}
+
+// ChannelEnabled reports whether the account receives notifications through channel.
+func (p *Preferences) ChannelEnabled(channel string) bool {
+	enabled, ok := p.Channels[channel]
+	return !ok || enabled
+}
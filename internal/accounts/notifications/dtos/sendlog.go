@@ -0,0 +1,23 @@
+package dtos
+
+import "time"
+
+const (
+	KindSummary   = "summary"
+	KindStatement = "statement"
+
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// SendLogEntry records a notification sent, failed or skipped for an account and channel,
+// Reason is the skip reason or the error of the failure.
+type SendLogEntry struct {
+	AccountID uint      `json:"account_id"`
+	Channel   string    `json:"channel"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package dtos
+
+// Webhook the summaries of the account are posted to URL signed with Secret.
+type Webhook struct {
+	AccountID uint   `json:"account_id"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret"`
+}
@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"time"
+	// the lambda runtime has no zoneinfo to load the account timezones
+	_ "time/tzdata"
+
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+)
+
+const (
+	ReasonUnsubscribed    = "unsubscribed"
+	ReasonChannelDisabled = "channel_disabled"
+	ReasonFrequency       = "frequency"
+	ReasonQuietHours      = "quiet_hours"
+	// ReasonAlreadyDelivered the idempotency key was already delivered, or it is being delivered
+	ReasonAlreadyDelivered = "already_delivered"
+	// ReasonNoRecipient the account has nowhere to be notified through the channel e.g. no webhook
+	ReasonNoRecipient = "no_recipient"
+)
+
+// SkipReason returns why the notification must not be sent through channel, empty when it can be sent.
+// lastSent is the last time the summary was sent through the channel, zero skips the frequency check.
+// The frequency is counted in calendar days of the account timezone, so a weekly summary sent at 12:05 is
+// sent again a week later at 12:00. The notifications are not deferred during the quiet hours, they are
+// skipped and the next run of the sender outside of them sends the summary.
+func SkipReason(preferences *dtos.Preferences, channel string, now, lastSent time.Time) string {
+	location := accountLocation(preferences)
+
+	switch {
+	case preferences.UnsubscribedAt != nil:
+		return ReasonUnsubscribed
+	case !preferences.ChannelEnabled(channel):
+		return ReasonChannelDisabled
+	case !lastSent.IsZero() && now.Before(nextSend(preferences.Frequency, lastSent.In(location))):
+		return ReasonFrequency
+	case inQuietHours(preferences, now.In(location)):
+		return ReasonQuietHours
+	}
+
+	return ""
+}
+
+// nextSend the start of the day the summary can be sent again.
+func nextSend(frequency string, lastSent time.Time) time.Time {
+	day := time.Date(lastSent.Year(), lastSent.Month(), lastSent.Day(), 0, 0, 0, 0, lastSent.Location())
+	switch frequency {
+	case dtos.FrequencyWeekly:
+		return day.AddDate(0, 0, 7)
+	case dtos.FrequencyMonthly:
+		return day.AddDate(0, 1, 0)
+	}
+	return lastSent
+}
+
+// inQuietHours the quiet hours can wrap midnight e.g. from 22 to 7.
+func inQuietHours(preferences *dtos.Preferences, now time.Time) bool {
+	start, end := preferences.QuietHoursStart, preferences.QuietHoursEnd
+	if start == end {
+		return false
+	}
+
+	hour := now.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func accountLocation(preferences *dtos.Preferences) *time.Location {
+	location, err := time.LoadLocation(preferences.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
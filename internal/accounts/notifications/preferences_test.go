@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipReason(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	unsubscribedAt := now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name        string
+		preferences *dtos.Preferences
+		channel     string
+		lastSent    time.Time
+		expected    string
+	}{
+		{
+			name:        "default preferences",
+			preferences: dtos.DefaultPreferences(1),
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.Add(-time.Hour),
+		},
+		{
+			name:        "unsubscribed",
+			preferences: &dtos.Preferences{UnsubscribedAt: &unsubscribedAt},
+			channel:     dtos.ChannelEmail,
+			expected:    ReasonUnsubscribed,
+		},
+		{
+			name:        "channel disabled",
+			preferences: &dtos.Preferences{Channels: map[string]bool{dtos.ChannelEmail: false}},
+			channel:     dtos.ChannelEmail,
+			expected:    ReasonChannelDisabled,
+		},
+		{
+			name:        "other channel disabled",
+			preferences: &dtos.Preferences{Channels: map[string]bool{"sms": false}},
+			channel:     dtos.ChannelEmail,
+		},
+		{
+			name:        "weekly sent this week",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyWeekly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.AddDate(0, 0, -6),
+			expected:    ReasonFrequency,
+		},
+		{
+			name:        "weekly sent last week",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyWeekly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.AddDate(0, 0, -7),
+		},
+		{
+			name:        "weekly sent one week earlier later in the day",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyWeekly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.AddDate(0, 0, -7).Add(5 * time.Minute),
+		},
+		{
+			name:        "weekly sent six days earlier at midnight",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyWeekly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+			expected:    ReasonFrequency,
+		},
+		{
+			name:        "weekly sent one week earlier in the account timezone",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyWeekly, Timezone: "America/Argentina/Buenos_Aires"},
+			channel:     dtos.ChannelEmail,
+			// March 4 at 01:00 in UTC is still March 3 in Buenos Aires
+			lastSent: time.Date(2024, time.March, 4, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "monthly sent one month earlier later in the day",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyMonthly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.AddDate(0, -1, 0).Add(time.Hour),
+		},
+		{
+			name:        "monthly sent this month",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyMonthly},
+			channel:     dtos.ChannelEmail,
+			lastSent:    now.AddDate(0, 0, -20),
+			expected:    ReasonFrequency,
+		},
+		{
+			name:        "monthly never sent",
+			preferences: &dtos.Preferences{Frequency: dtos.FrequencyMonthly},
+			channel:     dtos.ChannelEmail,
+		},
+		{
+			name:        "quiet hours",
+			preferences: &dtos.Preferences{QuietHoursStart: 11, QuietHoursEnd: 13, Timezone: "UTC"},
+			channel:     dtos.ChannelEmail,
+			expected:    ReasonQuietHours,
+		},
+		{
+			name:        "quiet hours over midnight in the account timezone",
+			preferences: &dtos.Preferences{QuietHoursStart: 22, QuietHoursEnd: 7, Timezone: "Pacific/Auckland"},
+			channel:     dtos.ChannelEmail,
+			expected:    ReasonQuietHours,
+		},
+		{
+			name:        "outside quiet hours",
+			preferences: &dtos.Preferences{QuietHoursStart: 22, QuietHoursEnd: 7, Timezone: "UTC"},
+			channel:     dtos.ChannelEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SkipReason(tt.preferences, tt.channel, now, tt.lastSent))
+		})
+	}
+}
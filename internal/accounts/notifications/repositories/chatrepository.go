@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"gorm.io/gorm"
+)
+
+type ChatChannelDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewChatChannelRepository(db *gorm.DB) *ChatChannelDBRepository {
+	return &ChatChannelDBRepository{
+		DB: db,
+	}
+}
+
+// GetChatChannels returns the chat channels indexed by account, the accounts without one are missing.
+func (cr *ChatChannelDBRepository) GetChatChannels(ctx context.Context, accountIDs []uint) (map[uint]*dtos.ChatChannel, error) {
+	var records []models.NotificationChatChannel
+	if err := cr.DB.WithContext(ctx).Where("account_id IN ?", accountIDs).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("error getting chat channels: %w", err)
+	}
+
+	channels := make(map[uint]*dtos.ChatChannel, len(records))
+	for _, record := range records {
+		channels[record.AccountID] = &dtos.ChatChannel{
+			AccountID: record.AccountID,
+			URL:       record.URL,
+			Format:    record.Format,
+		}
+	}
+
+	return channels, nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"gorm.io/gorm"
+)
+
+// claimDelivery inserts the delivery as pending, an existing delivery is only claimed again when it
+// failed or when it is pending for longer than the lease (the sender that claimed it died).
+const claimDelivery = `
+INSERT INTO notification_deliveries (account_id, channel, idempotency_key, status, attempts, created_at, updated_at)
+VALUES (?, ?, ?, 'pending', 1, NOW(), NOW())
+ON CONFLICT (account_id, channel, idempotency_key) DO UPDATE SET
+    status     = 'pending',
+    attempts   = notification_deliveries.attempts + 1,
+    updated_at = NOW()
+WHERE notification_deliveries.status = 'failed'
+   OR (notification_deliveries.status = 'pending' AND notification_deliveries.updated_at < ?)`
+
+type DeliveryDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryDBRepository {
+	return &DeliveryDBRepository{
+		DB: db,
+	}
+}
+
+// Claim returns false when the delivery was already sent or another sender is sending it.
+func (dr *DeliveryDBRepository) Claim(ctx context.Context, accountID uint, channel, key string, lease time.Duration) (bool, error) {
+	result := dr.DB.WithContext(ctx).Exec(claimDelivery, accountID, channel, key, time.Now().Add(-lease))
+	if result.Error != nil {
+		return false, fmt.Errorf("error claiming delivery %s of account %d: %w", key, accountID, result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Complete marks the claimed delivery as sent, or as failed when sendErr is not nil.
+func (dr *DeliveryDBRepository) Complete(ctx context.Context, accountID uint, channel, key, messageID string, sendErr error) error {
+	updates := map[string]interface{}{
+		"status":              dtos.DeliverySent,
+		"provider_message_id": messageID,
+		"last_error":          "",
+		"updated_at":          time.Now(),
+	}
+	if sendErr != nil {
+		updates["status"] = dtos.DeliveryFailed
+		updates["last_error"] = sendErr.Error()
+	}
+
+	err := dr.DB.WithContext(ctx).Model(&models.NotificationDelivery{}).
+		Where("account_id = ? AND channel = ? AND idempotency_key = ?", accountID, channel, key).
+		Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("error completing delivery %s of account %d: %w", key, accountID, err)
+	}
+
+	return nil
+}
+
+// GetFailed returns the failed deliveries of the keys.
+func (dr *DeliveryDBRepository) GetFailed(ctx context.Context, keys []string) ([]*dtos.Delivery, error) {
+	var records []models.NotificationDelivery
+	err := dr.DB.WithContext(ctx).
+		Where("idempotency_key IN ? AND status = ?", keys, dtos.DeliveryFailed).
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting failed deliveries: %w", err)
+	}
+
+	deliveries := make([]*dtos.Delivery, 0, len(records))
+	for _, record := range records {
+		deliveries = append(deliveries, &dtos.Delivery{
+			AccountID:         record.AccountID,
+			Channel:           record.Channel,
+			IdempotencyKey:    record.IdempotencyKey,
+			Status:            record.Status,
+			Attempts:          record.Attempts,
+			ProviderMessageID: record.ProviderMessageID,
+			LastError:         record.LastError,
+			CreatedAt:         record.CreatedAt,
+			UpdatedAt:         record.UpdatedAt,
+		})
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreferenceDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewPreferenceRepository(db *gorm.DB) *PreferenceDBRepository {
+	return &PreferenceDBRepository{
+		DB: db,
+	}
+}
+
+// GetPreferences returns the preferences of every account, the accounts without preferences get the defaults.
+func (pr *PreferenceDBRepository) GetPreferences(ctx context.Context, accountIDs []uint) (map[uint]*dtos.Preferences, error) {
+	var records []models.NotificationPreference
+	if err := pr.DB.WithContext(ctx).Where("account_id IN ?", accountIDs).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("error getting notification preferences: %w", err)
+	}
+
+	var channels []models.NotificationChannelPreference
+	if err := pr.DB.WithContext(ctx).Where("account_id IN ?", accountIDs).Find(&channels).Error; err != nil {
+		return nil, fmt.Errorf("error getting notification channel preferences: %w", err)
+	}
+
+	preferences := make(map[uint]*dtos.Preferences, len(accountIDs))
+	for _, accountID := range accountIDs {
+		preferences[accountID] = dtos.DefaultPreferences(accountID)
+	}
+
+	for _, record := range records {
+		p := preferences[record.AccountID]
+		p.Frequency = record.Frequency
+		p.QuietHoursStart = record.QuietHoursStart
+		p.QuietHoursEnd = record.QuietHoursEnd
+		p.Timezone = record.Timezone
+		p.UnsubscribedAt = record.UnsubscribedAt
+	}
+
+	for _, channel := range channels {
+		preferences[channel.AccountID].Channels[channel.Channel] = channel.Enabled
+	}
+
+	return preferences, nil
+}
+
+// Unsubscribe disables the channel of the account, dtos.ChannelAll unsubscribes it from every channel.
+func (pr *PreferenceDBRepository) Unsubscribe(ctx context.Context, accountID uint, channel string) error {
+	if channel == dtos.ChannelAll {
+		now := time.Now()
+		preference := models.NotificationPreference{
+			AccountID:      accountID,
+			Frequency:      dtos.FrequencyAlways,
+			Timezone:       "UTC",
+			UnsubscribedAt: &now,
+		}
+		err := pr.DB.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"unsubscribed_at", "updated_at"}),
+		}).Create(&preference).Error
+		if err != nil {
+			return fmt.Errorf("error unsubscribing account %d: %w", accountID, err)
+		}
+		return nil
+	}
+
+	preference := models.NotificationChannelPreference{
+		AccountID: accountID,
+		Channel:   channel,
+		Enabled:   false,
+	}
+	err := pr.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&preference).Error
+	if err != nil {
+		return fmt.Errorf("error disabling channel %s of account %d: %w", channel, accountID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"gorm.io/gorm"
+)
+
+type SendLogDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewSendLogRepository(db *gorm.DB) *SendLogDBRepository {
+	return &SendLogDBRepository{
+		DB: db,
+	}
+}
+
+func (sr *SendLogDBRepository) Record(ctx context.Context, entry *dtos.SendLogEntry) error {
+	record := models.NotificationLog{
+		AccountID: entry.AccountID,
+		Channel:   entry.Channel,
+		Kind:      entry.Kind,
+		Status:    entry.Status,
+		Reason:    entry.Reason,
+	}
+	if err := sr.DB.WithContext(ctx).Create(&record).Error; err != nil {
+		return fmt.Errorf("error recording notification: %w", err)
+	}
+
+	entry.CreatedAt = record.CreatedAt
+	return nil
+}
+
+// GetLastSent returns when the kind of notification was last sent through channel, accounts never notified are missing.
+func (sr *SendLogDBRepository) GetLastSent(ctx context.Context, channel, kind string, accountIDs []uint) (map[uint]time.Time, error) {
+	var rows []struct {
+		AccountID uint
+		LastSent  time.Time
+	}
+
+	err := sr.DB.WithContext(ctx).Model(&models.NotificationLog{}).
+		Select("account_id, MAX(created_at) AS last_sent").
+		Where("channel = ? AND kind = ? AND status = ? AND account_id IN ?", channel, kind, dtos.StatusSent, accountIDs).
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting last sent notifications: %w", err)
+	}
+
+	lastSent := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		lastSent[row.AccountID] = row.LastSent
+	}
+
+	return lastSent, nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"gorm.io/gorm"
+)
+
+type WebhookDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookDBRepository {
+	return &WebhookDBRepository{
+		DB: db,
+	}
+}
+
+// GetWebhooks returns the webhooks indexed by account, the accounts without a webhook are missing.
+func (wr *WebhookDBRepository) GetWebhooks(ctx context.Context, accountIDs []uint) (map[uint]*dtos.Webhook, error) {
+	var records []models.NotificationWebhook
+	if err := wr.DB.WithContext(ctx).Where("account_id IN ?", accountIDs).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("error getting webhooks: %w", err)
+	}
+
+	webhooks := make(map[uint]*dtos.Webhook, len(records))
+	for _, record := range records {
+		webhooks[record.AccountID] = &dtos.Webhook{
+			AccountID: record.AccountID,
+			URL:       record.URL,
+			Secret:    record.Secret,
+		}
+	}
+
+	return webhooks, nil
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid unsubscribe token")
+	ErrExpiredToken = errors.New("expired unsubscribe token")
+)
+
+// UnsubscribeTokens signs the account ID and channel of the unsubscribe links with HMAC-SHA256,
+// the token is "<account ID>.<channel>.<expiration>.<signature>" and it is valid until the expiration.
+type UnsubscribeTokens struct {
+	Secret []byte
+	TTL    time.Duration
+	// BaseURL of the unsubscribe handler, the token is added as the token query parameter
+	BaseURL string
+	now     func() time.Time
+}
+
+func NewUnsubscribeTokens(secret []byte, ttl time.Duration, baseURL string) *UnsubscribeTokens {
+	return &UnsubscribeTokens{
+		Secret:  secret,
+		TTL:     ttl,
+		BaseURL: baseURL,
+		now:     time.Now,
+	}
+}
+
+func (ut *UnsubscribeTokens) Sign(accountID uint, channel string) string {
+	payload := fmt.Sprintf("%d.%s.%d", accountID, channel, ut.now().Add(ut.TTL).Unix())
+	return payload + "." + ut.signature(payload)
+}
+
+// Verify returns the account ID and channel of the token.
+func (ut *UnsubscribeTokens) Verify(token string) (uint, string, error) {
+	// anyone can sign the tokens with an empty secret
+	if len(ut.Secret) == 0 {
+		return 0, "", ErrInvalidToken
+	}
+
+	payload, signature, found := cutLast(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(ut.signature(payload))) {
+		return 0, "", ErrInvalidToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return 0, "", ErrInvalidToken
+	}
+
+	accountID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+
+	expiration, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+	if ut.now().After(time.Unix(expiration, 0)) {
+		return 0, "", ErrExpiredToken
+	}
+
+	return uint(accountID), parts[1], nil
+}
+
+// URL returns the unsubscribe link of the account, empty when the links are not configured.
+func (ut *UnsubscribeTokens) URL(accountID uint, channel string) string {
+	if ut.BaseURL == "" || len(ut.Secret) == 0 {
+		return ""
+	}
+
+	return ut.BaseURL + "?token=" + url.QueryEscape(ut.Sign(accountID, channel))
+}
+
+func (ut *UnsubscribeTokens) signature(payload string) string {
+	mac := hmac.New(sha256.New, ut.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/juaguz/storid/internal/platform/i18n"
+	"go.uber.org/zap"
+)
+
+type PreferenceRepository interface {
+	Unsubscribe(ctx context.Context, accountID uint, channel string) error
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>{{.Title}}</title></head>
+<body style="font-family: Arial, sans-serif; margin: 40px;">
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Token}}<form method="post" action="?token={{.Token}}"><button type="submit">{{.Title}}</button></form>{{end}}
+</body>
+</html>
+`))
+
+type unsubscribeView struct {
+	Title   string
+	Message string
+	Token   string
+}
+
+// UnsubscribeHandler GET shows a confirmation page so link scanners do not unsubscribe the account,
+// POST unsubscribes it, it is the one-click request mailbox providers send for List-Unsubscribe-Post.
+type UnsubscribeHandler struct {
+	Tokens               *UnsubscribeTokens
+	PreferenceRepository PreferenceRepository
+	Log                  *zap.Logger
+}
+
+func NewUnsubscribeHandler(tokens *UnsubscribeTokens, preferences PreferenceRepository, log *zap.Logger) *UnsubscribeHandler {
+	return &UnsubscribeHandler{
+		Tokens:               tokens,
+		PreferenceRepository: preferences,
+		Log:                  log,
+	}
+}
+
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	locale := acceptedLocale(r)
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	accountID, channel, err := h.Tokens.Verify(token)
+	if err != nil {
+		h.Log.Warn("invalid unsubscribe token", zap.Error(err))
+		h.render(w, http.StatusBadRequest, unsubscribeView{Title: i18n.T(locale, "unsubscribe_title"), Message: i18n.T(locale, "unsubscribe_invalid")})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.render(w, http.StatusOK, unsubscribeView{Title: i18n.T(locale, "unsubscribe_title"), Message: i18n.T(locale, "unsubscribe_confirm"), Token: token})
+		return
+	}
+
+	if err := h.PreferenceRepository.Unsubscribe(r.Context(), accountID, channel); err != nil {
+		h.Log.Error("error unsubscribing account", zap.Uint("account_id", accountID), zap.String("channel", channel), zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	h.Log.Info("account unsubscribed", zap.Uint("account_id", accountID), zap.String("channel", channel))
+	h.render(w, http.StatusOK, unsubscribeView{Title: i18n.T(locale, "unsubscribe_title"), Message: i18n.T(locale, "unsubscribe_done")})
+}
+
+func (h *UnsubscribeHandler) render(w http.ResponseWriter, status int, view unsubscribeView) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := unsubscribePage.Execute(w, view); err != nil {
+		h.Log.Error("error rendering unsubscribe page", zap.Error(err))
+	}
+}
+
+// acceptedLocale returns the first language of the Accept-Language header.
+func acceptedLocale(r *http.Request) string {
+	locale, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.TrimSpace(locale)
+}
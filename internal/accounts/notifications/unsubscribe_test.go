@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/notifications/dtos"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockPreferenceRepository struct {
+	accountID uint
+	channel   string
+}
+
+func (m *MockPreferenceRepository) Unsubscribe(ctx context.Context, accountID uint, channel string) error {
+	m.accountID = accountID
+	m.channel = channel
+	return nil
+}
+
+func TestUnsubscribeTokens(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tokens := NewUnsubscribeTokens([]byte("secret"), time.Hour, "")
+	tokens.now = func() time.Time { return now }
+
+	token := tokens.Sign(42, dtos.ChannelEmail)
+
+	accountID, channel, err := tokens.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), accountID)
+	assert.Equal(t, dtos.ChannelEmail, channel)
+
+	_, _, err = tokens.Verify(strings.Replace(token, "42.", "43.", 1))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, _, err = NewUnsubscribeTokens([]byte("other"), time.Hour, "").Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, _, err = tokens.Verify("garbage")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	tokens.now = func() time.Time { return now.Add(2 * time.Hour) }
+	_, _, err = tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestUnsubscribeTokens_EmptySecret(t *testing.T) {
+	tokens := NewUnsubscribeTokens(nil, time.Hour, "")
+
+	_, _, err := tokens.Verify(tokens.Sign(42, dtos.ChannelEmail))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUnsubscribeTokens_URL(t *testing.T) {
+	assert.Empty(t, NewUnsubscribeTokens([]byte("secret"), time.Hour, "").URL(1, dtos.ChannelEmail))
+	assert.Empty(t, NewUnsubscribeTokens(nil, time.Hour, "https://api.storid.com/unsubscribe").URL(1, dtos.ChannelEmail))
+	assert.True(t, strings.HasPrefix(NewUnsubscribeTokens([]byte("secret"), time.Hour, "https://api.storid.com/unsubscribe").URL(1, dtos.ChannelEmail), "https://api.storid.com/unsubscribe?token=1.email."))
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	tokens := NewUnsubscribeTokens([]byte("secret"), time.Hour, "")
+	preferences := &MockPreferenceRepository{}
+	handler := NewUnsubscribeHandler(tokens, preferences, zap.NewNop())
+	target := "/unsubscribe?token=" + url.QueryEscape(tokens.Sign(7, dtos.ChannelEmail))
+
+	// opening the link only asks for confirmation
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `<form method="post"`)
+	assert.Zero(t, preferences.accountID)
+
+	// one-click unsubscribe of RFC 8058
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept-Language", "es-AR,es;q=0.9")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Te diste de baja correctamente.")
+	assert.Equal(t, uint(7), preferences.accountID)
+	assert.Equal(t, dtos.ChannelEmail, preferences.channel)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/unsubscribe?token=invalid", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, target, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -1,18 +1,25 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/juaguz/storid/internal/accounts/dtos"
 	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/platform/i18n"
 	"gorm.io/gorm"
 )
 
 type AccountRepository struct {
 	DB *gorm.DB
+	// DefaultLocale is used for the accounts without a locale
+	DefaultLocale string
 }
 
 func NewAccountRepository(db *gorm.DB) *AccountRepository {
 	return &AccountRepository{
-		DB: db,
+		DB:            db,
+		DefaultLocale: i18n.DefaultLocale,
 	}
 }
 
@@ -24,7 +31,40 @@ func (ar *AccountRepository) GetAccountByID(accountID uint) (*dtos.Account, erro
 		return nil, err
 	}
 
+	tier := account.Tier
+	if tier == "" {
+		tier = dtos.DefaultTier
+	}
+
+	locale := account.Locale
+	if locale == "" {
+		locale = ar.DefaultLocale
+	}
+
 	return &dtos.Account{
-		Email: account.Email,
+		Name:     account.Name,
+		LastName: account.LastName,
+		Email:    account.Email,
+		Tier:     tier,
+		Locale:   locale,
+		Phone:    account.Phone,
 	}, nil
 }
+
+// GetPhones returns the phone of the accounts indexed by ID, the accounts without a phone are missing.
+func (ar *AccountRepository) GetPhones(ctx context.Context, accountIDs []uint) (map[uint]string, error) {
+	var accounts []models.Account
+	err := ar.DB.WithContext(ctx).Select("id", "phone").
+		Where("id IN ? AND phone IS NOT NULL AND phone <> ''", accountIDs).
+		Find(&accounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting phones: %w", err)
+	}
+
+	phones := make(map[uint]string, len(accounts))
+	for _, account := range accounts {
+		phones[account.ID] = account.Phone
+	}
+
+	return phones, nil
+}
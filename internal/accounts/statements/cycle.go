@@ -0,0 +1,31 @@
+package statements
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxCycleDay keeps the cycle start inside every month.
+const MaxCycleDay = 28
+
+// CycleStart returns the start of the billing cycle that contains t, cycles start on cycleDay of each month.
+func CycleStart(t time.Time, cycleDay int) time.Time {
+	start := time.Date(t.Year(), t.Month(), cycleDay, 0, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// CyclePeriod returns the period [from, to) of the cycle n, the first cycle is the one containing first.
+func CyclePeriod(first time.Time, cycleDay, n int) (time.Time, time.Time, error) {
+	if cycleDay < 1 || cycleDay > MaxCycleDay {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid cycle day %d, it must be between 1 and %d", cycleDay, MaxCycleDay)
+	}
+	if n < 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid cycle %d, cycles start at 1", n)
+	}
+
+	from := CycleStart(first, cycleDay).AddDate(0, n-1, 0)
+	return from, from.AddDate(0, 1, 0), nil
+}
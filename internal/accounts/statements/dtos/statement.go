@@ -0,0 +1,48 @@
+package dtos
+
+import (
+	"fmt"
+	"time"
+
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+)
+
+// Statement of an account for the period [PeriodStart, PeriodEnd), once generated it never changes.
+type Statement struct {
+	AccountID      uint                          `json:"account_id"`
+	Sequence       int                           `json:"sequence"`
+	PeriodStart    time.Time                     `json:"period_start"`
+	PeriodEnd      time.Time                     `json:"period_end"`
+	OpeningBalance int                           `json:"opening_balance"`
+	ClosingBalance int                           `json:"closing_balance"`
+	TotalCredits   int                           `json:"total_credits"`
+	TotalDebits    int                           `json:"total_debits"`
+	CreditCount    int                           `json:"credit_count"`
+	DebitCount     int                           `json:"debit_count"`
+	Transactions   []*transactiondto.Transaction `json:"transactions"`
+	CreatedAt      time.Time                     `json:"created_at"`
+}
+
+// LastDay returns the last day included in the statement.
+func (s *Statement) LastDay() time.Time {
+	return s.PeriodEnd.AddDate(0, 0, -1)
+}
+
+// ParsePeriod reads the first and last day of a period formatted as YYYY-MM-DD,
+// the period returned ends at the start of the day after last.
+func ParsePeriod(first, last string) (time.Time, time.Time, error) {
+	from, err := time.Parse(time.DateOnly, first)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period start %q: %w", first, err)
+	}
+
+	to, err := time.Parse(time.DateOnly, last)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period end %q: %w", last, err)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period %s - %s, it ends before it starts", first, last)
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
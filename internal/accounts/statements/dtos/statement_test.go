@@ -0,0 +1,26 @@
+package dtos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePeriod(t *testing.T) {
+	from, to, err := ParsePeriod("2024-01-01", "2024-03-31")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), to)
+
+	// a single day
+	from, to, err = ParsePeriod("2024-02-29", "2024-02-29")
+	assert.NoError(t, err)
+	assert.Equal(t, from.AddDate(0, 0, 1), to)
+
+	_, _, err = ParsePeriod("2024-03-31", "2024-01-01")
+	assert.Error(t, err)
+
+	_, _, err = ParsePeriod("2024-01-01", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package statements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFound is returned by the repository when the statement was not generated yet.
+	ErrNotFound = errors.New("statement not found")
+	// ErrPeriodNotEnded the statements are immutable, a period is only generated once all its transactions arrived.
+	ErrPeriodNotEnded = errors.New("statement period has not ended")
+)
+
+type TransactionRepository interface {
+	GetAccountIDs(ctx context.Context) ([]uint, error)
+	FindByAccountIDAndPeriod(ctx context.Context, accountID uint, from, to time.Time) ([]*transactiondto.Transaction, error)
+	GetBalanceBefore(ctx context.Context, accountID uint, before time.Time) (int, error)
+	GetFirstTransactionDate(ctx context.Context, accountID uint) (time.Time, error)
+}
+
+type StatementRepository interface {
+	// Create stores the statement assigning the next sequence of the account, the period already stored is loaded instead.
+	Create(ctx context.Context, statement *dtos.Statement) error
+	GetByPeriod(ctx context.Context, accountID uint, from, to time.Time) (*dtos.Statement, error)
+}
+
+// Generator builds the statements of the accounts, a statement is generated once
+// and the stored copy is returned afterwards even if new transactions arrive for the period.
+type Generator struct {
+	TransactionRepository TransactionRepository
+	StatementRepository   StatementRepository
+	Log                   *zap.Logger
+	now                   func() time.Time
+}
+
+func NewGenerator(transactions TransactionRepository, statements StatementRepository, log *zap.Logger) *Generator {
+	return &Generator{
+		TransactionRepository: transactions,
+		StatementRepository:   statements,
+		Log:                   log,
+		now:                   time.Now,
+	}
+}
+
+func (g *Generator) GetAccountIDs(ctx context.Context) ([]uint, error) {
+	return g.TransactionRepository.GetAccountIDs(ctx)
+}
+
+// GenerateCycle returns the statement of the billing cycle n of the account.
+func (g *Generator) GenerateCycle(ctx context.Context, accountID uint, cycleDay, n int) (*dtos.Statement, error) {
+	first, err := g.TransactionRepository.GetFirstTransactionDate(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := CyclePeriod(first, cycleDay, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Generate(ctx, accountID, from, to)
+}
+
+// Generate returns the statement of the account for the period [from, to), the period must have
+// ended because the stored statement is never recalculated. The statements are numbered in the order
+// they are generated, the cycle day of an account can change between them.
+func (g *Generator) Generate(ctx context.Context, accountID uint, from, to time.Time) (*dtos.Statement, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid statement period %s - %s", from, to)
+	}
+	if to.After(g.now()) {
+		return nil, fmt.Errorf("period %s - %s of account %d: %w", from, to, accountID, ErrPeriodNotEnded)
+	}
+
+	existing, err := g.StatementRepository.GetByPeriod(ctx, accountID, from, to)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	opening, err := g.TransactionRepository.GetBalanceBefore(ctx, accountID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := g.TransactionRepository.FindByAccountIDAndPeriod(ctx, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := Build(accountID, opening, transactions, from, to)
+	if err := g.StatementRepository.Create(ctx, statement); err != nil {
+		return nil, fmt.Errorf("error saving statement for account %d: %w", accountID, err)
+	}
+
+	g.Log.Info("statement generated",
+		zap.Uint("account_id", accountID),
+		zap.Int("sequence", statement.Sequence),
+		zap.Time("from", from),
+		zap.Time("to", to))
+
+	return statement, nil
+}
+
+// Build calculates the totals and closing balance of the period, debit amounts are negative.
+func Build(accountID uint, opening int, transactions []*transactiondto.Transaction, from, to time.Time) *dtos.Statement {
+	statement := &dtos.Statement{
+		AccountID:      accountID,
+		PeriodStart:    from,
+		PeriodEnd:      to,
+		OpeningBalance: opening,
+		Transactions:   transactions,
+	}
+
+	for _, t := range transactions {
+		switch t.Type {
+		case transactiondto.Credit:
+			statement.TotalCredits += t.Amount
+			statement.CreditCount++
+		case transactiondto.Debit:
+			statement.TotalDebits += t.Amount
+			statement.DebitCount++
+		}
+	}
+
+	statement.ClosingBalance = opening + statement.TotalCredits + statement.TotalDebits
+
+	return statement
+}
@@ -0,0 +1,176 @@
+package statements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockTransactionRepository struct {
+	transactions []*transactiondto.Transaction
+}
+
+func (m *MockTransactionRepository) GetAccountIDs(ctx context.Context) ([]uint, error) {
+	return []uint{1}, nil
+}
+
+func (m *MockTransactionRepository) FindByAccountIDAndPeriod(ctx context.Context, accountID uint, from, to time.Time) ([]*transactiondto.Transaction, error) {
+	var result []*transactiondto.Transaction
+	for _, t := range m.transactions {
+		if !t.Date.Before(from) && t.Date.Before(to) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTransactionRepository) GetBalanceBefore(ctx context.Context, accountID uint, before time.Time) (int, error) {
+	balance := 0
+	for _, t := range m.transactions {
+		if t.Date.Before(before) {
+			balance += t.Amount
+		}
+	}
+	return balance, nil
+}
+
+func (m *MockTransactionRepository) GetFirstTransactionDate(ctx context.Context, accountID uint) (time.Time, error) {
+	return m.transactions[0].Date, nil
+}
+
+type MockStatementRepository struct {
+	statements []*dtos.Statement
+}
+
+func (m *MockStatementRepository) Create(ctx context.Context, statement *dtos.Statement) error {
+	statement.Sequence = 1
+	for _, s := range m.statements {
+		if s.AccountID == statement.AccountID {
+			statement.Sequence = max(statement.Sequence, s.Sequence+1)
+		}
+	}
+	m.statements = append(m.statements, statement)
+	return nil
+}
+
+func (m *MockStatementRepository) GetByPeriod(ctx context.Context, accountID uint, from, to time.Time) (*dtos.Statement, error) {
+	for _, s := range m.statements {
+		if s.AccountID == accountID && s.PeriodStart.Equal(from) && s.PeriodEnd.Equal(to) {
+			return s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func transaction(date time.Time, amount int) *transactiondto.Transaction {
+	t := &transactiondto.Transaction{Date: date, Amount: amount, AccountID: 1, Type: transactiondto.Credit}
+	if amount < 0 {
+		t.Type = transactiondto.Debit
+	}
+	return t
+}
+
+func TestCyclePeriod(t *testing.T) {
+	first := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	from, to, err := CyclePeriod(first, 15, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, time.December, 15, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC), to)
+
+	from, to, err = CyclePeriod(first, 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = CyclePeriod(first, 31, 1)
+	assert.Error(t, err)
+
+	_, _, err = CyclePeriod(first, 1, 0)
+	assert.Error(t, err)
+}
+
+func TestGenerator_GenerateCycle(t *testing.T) {
+	transactions := &MockTransactionRepository{transactions: []*transactiondto.Transaction{
+		transaction(time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC), 10000),
+		transaction(time.Date(2024, time.February, 16, 0, 0, 0, 0, time.UTC), 5000),
+		transaction(time.Date(2024, time.February, 20, 0, 0, 0, 0, time.UTC), -2500),
+		transaction(time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), -500),
+		transaction(time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), 700),
+	}}
+	repository := &MockStatementRepository{}
+	generator := NewGenerator(transactions, repository, zap.NewNop())
+	generator.now = func() time.Time { return time.Date(2024, time.April, 15, 0, 0, 0, 0, time.UTC) }
+
+	// the statements are numbered in the order they are generated
+	later, err := generator.GenerateCycle(context.Background(), 1, 15, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, later.Sequence)
+	assert.Equal(t, 12000, later.OpeningBalance)
+
+	statement, err := generator.GenerateCycle(context.Background(), 1, 15, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, statement.Sequence)
+	assert.Equal(t, time.Date(2024, time.February, 15, 0, 0, 0, 0, time.UTC), statement.PeriodStart)
+	assert.Equal(t, time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), statement.LastDay())
+	assert.Equal(t, 10000, statement.OpeningBalance)
+	assert.Equal(t, 5000, statement.TotalCredits)
+	assert.Equal(t, -3000, statement.TotalDebits)
+	assert.Equal(t, 1, statement.CreditCount)
+	assert.Equal(t, 2, statement.DebitCount)
+	assert.Equal(t, 12000, statement.ClosingBalance)
+	assert.Len(t, statement.Transactions, 3)
+
+	// a generated statement is never recalculated
+	transactions.transactions = append(transactions.transactions, transaction(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), 100))
+	again, err := generator.GenerateCycle(context.Background(), 1, 15, 2)
+	assert.NoError(t, err)
+	assert.Same(t, statement, again)
+	assert.Len(t, repository.statements, 2)
+}
+
+func TestGenerator_GenerateCycleNotEnded(t *testing.T) {
+	transactions := &MockTransactionRepository{transactions: []*transactiondto.Transaction{
+		transaction(time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC), 10000),
+	}}
+	repository := &MockStatementRepository{}
+	generator := NewGenerator(transactions, repository, zap.NewNop())
+	generator.now = func() time.Time { return time.Date(2024, time.March, 14, 23, 0, 0, 0, time.UTC) }
+
+	// the cycle 2 ends on March 15, it would miss the transactions of its last day
+	_, err := generator.GenerateCycle(context.Background(), 1, 15, 2)
+	assert.ErrorIs(t, err, ErrPeriodNotEnded)
+
+	_, err = generator.GenerateCycle(context.Background(), 1, 15, 5)
+	assert.ErrorIs(t, err, ErrPeriodNotEnded)
+	assert.Empty(t, repository.statements)
+
+	generator.now = func() time.Time { return time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC) }
+	statement, err := generator.GenerateCycle(context.Background(), 1, 15, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, statement.Sequence)
+}
+
+func TestGenerator_GenerateCycleDayChanged(t *testing.T) {
+	transactions := &MockTransactionRepository{transactions: []*transactiondto.Transaction{
+		transaction(time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC), 10000),
+	}}
+	repository := &MockStatementRepository{}
+	generator := NewGenerator(transactions, repository, zap.NewNop())
+	generator.now = func() time.Time { return time.Date(2024, time.April, 15, 0, 0, 0, 0, time.UTC) }
+
+	statement, err := generator.GenerateCycle(context.Background(), 1, 15, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, statement.Sequence)
+
+	// the cycle 2 starting on the 1st is another period, it gets the next sequence instead of the cycle
+	statement, err = generator.GenerateCycle(context.Background(), 1, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), statement.PeriodStart)
+	assert.Equal(t, 2, statement.Sequence)
+}
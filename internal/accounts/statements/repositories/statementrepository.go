@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/statements"
+	"github.com/juaguz/storid/internal/accounts/statements/dtos"
+	transactiondto "github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"gorm.io/gorm"
+)
+
+type StatementDBRepository struct {
+	DB *gorm.DB
+}
+
+func NewStatementRepository(db *gorm.DB) *StatementDBRepository {
+	return &StatementDBRepository{
+		DB: db,
+	}
+}
+
+// Create stores the statement with the next sequence of the account, the account is locked
+// so concurrent generations get consecutive sequences. If the period was already generated
+// the stored statement is loaded into statement instead.
+func (sr *StatementDBRepository) Create(ctx context.Context, statement *dtos.Statement) error {
+	transactions, err := json.Marshal(statement.Transactions)
+	if err != nil {
+		return fmt.Errorf("error encoding statement transactions: %w", err)
+	}
+
+	return sr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", statement.AccountID).Error; err != nil {
+			return fmt.Errorf("error locking account statements: %w", err)
+		}
+
+		var existing models.Statement
+		err := tx.Where("account_id = ? AND period_start = ? AND period_end = ?", statement.AccountID, statement.PeriodStart, statement.PeriodEnd).
+			Take(&existing).Error
+		if err == nil {
+			stored, err := toDTO(existing)
+			if err != nil {
+				return err
+			}
+			*statement = *stored
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error getting statement by period: %w", err)
+		}
+
+		var sequence int
+		err = tx.Model(&models.Statement{}).Select("COALESCE(MAX(sequence), 0) + 1").Where("account_id = ?", statement.AccountID).Scan(&sequence).Error
+		if err != nil {
+			return fmt.Errorf("error getting next statement sequence: %w", err)
+		}
+
+		record := models.Statement{
+			AccountID:      statement.AccountID,
+			Sequence:       sequence,
+			PeriodStart:    statement.PeriodStart,
+			PeriodEnd:      statement.PeriodEnd,
+			OpeningBalance: statement.OpeningBalance,
+			ClosingBalance: statement.ClosingBalance,
+			TotalCredits:   statement.TotalCredits,
+			TotalDebits:    statement.TotalDebits,
+			CreditCount:    statement.CreditCount,
+			DebitCount:     statement.DebitCount,
+			Transactions:   string(transactions),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("error creating statement: %w", err)
+		}
+
+		statement.Sequence = record.Sequence
+		statement.CreatedAt = record.CreatedAt
+		return nil
+	})
+}
+
+// GetByPeriod returns statements.ErrNotFound when the period was not generated.
+func (sr *StatementDBRepository) GetByPeriod(ctx context.Context, accountID uint, from, to time.Time) (*dtos.Statement, error) {
+	return sr.find(ctx, "account_id = ? AND period_start = ? AND period_end = ?", accountID, from, to)
+}
+
+// GetBySequence returns statements.ErrNotFound when the sequence does not exist.
+func (sr *StatementDBRepository) GetBySequence(ctx context.Context, accountID uint, sequence int) (*dtos.Statement, error) {
+	return sr.find(ctx, "account_id = ? AND sequence = ?", accountID, sequence)
+}
+
+func (sr *StatementDBRepository) find(ctx context.Context, query string, args ...interface{}) (*dtos.Statement, error) {
+	var record models.Statement
+	err := sr.DB.WithContext(ctx).Where(query, args...).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, statements.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting statement: %w", err)
+	}
+
+	return toDTO(record)
+}
+
+func toDTO(record models.Statement) (*dtos.Statement, error) {
+	var transactions []*transactiondto.Transaction
+	if err := json.Unmarshal([]byte(record.Transactions), &transactions); err != nil {
+		return nil, fmt.Errorf("error decoding statement transactions: %w", err)
+	}
+
+	return &dtos.Statement{
+		AccountID:      record.AccountID,
+		Sequence:       record.Sequence,
+		PeriodStart:    record.PeriodStart,
+		PeriodEnd:      record.PeriodEnd,
+		OpeningBalance: record.OpeningBalance,
+		ClosingBalance: record.ClosingBalance,
+		TotalCredits:   record.TotalCredits,
+		TotalDebits:    record.TotalDebits,
+		CreditCount:    record.CreditCount,
+		DebitCount:     record.DebitCount,
+		Transactions:   transactions,
+		CreatedAt:      record.CreatedAt,
+	}, nil
+}
@@ -11,6 +11,9 @@ const (
 	Debit  TransactionType = "debit"
 )
 
+// Uncategorized is used when the imported file does not provide a category.
+const Uncategorized = "uncategorized"
+
 type Transaction struct {
 	ID         uint            `json:"id" gorm:"primary_key"`
 	ExternalID string          `json:"external_id"`
@@ -18,4 +21,6 @@ type Transaction struct {
 	Amount     int             `json:"amount"`
 	AccountID  uint            `json:"account_id"`
 	Type       TransactionType `json:"type"`
+	Category   string          `json:"category"`
+	ImportID   string          `json:"-"`
 }
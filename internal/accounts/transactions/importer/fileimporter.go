@@ -2,10 +2,14 @@ package importer
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,8 +30,16 @@ const (
 	EventImported = "Imported"
 )
 
+// ImportedPayload is sent along with EventImported,
+// handlers can use ImportID to find the transactions created by the import.
+type ImportedPayload struct {
+	ImportID  string    `json:"import_id"`
+	FilePath  string    `json:"file_path"`
+	StartedAt time.Time `json:"started_at"`
+}
+
 type EventDispatcher interface {
-	Dispatch(ctx context.Context, event string, payload []byte)
+	Dispatch(ctx context.Context, event string, payload []byte) error
 }
 
 type TransactionRepository interface {
@@ -60,6 +72,13 @@ func (fi *FileImporter) Import(ctx context.Context, filePath string) error {
 
 // process file can be a standalone function to be used in other places
 func (fi *FileImporter) processFile(ctx context.Context, filePath string) error {
+	startedAt := time.Now()
+
+	importID, err := newImportID()
+	if err != nil {
+		return err
+	}
+
 	f, err := fi.FileReader.Open(ctx, filePath)
 	if err != nil {
 		return fmt.Errorf("error opening file: %w", err)
@@ -82,7 +101,7 @@ func (fi *FileImporter) processFile(ctx context.Context, filePath string) error
 		go func() {
 			defer wg.Done()
 			for chunk := range chunkChan {
-				if err := fi.createRecords(chunk); err != nil {
+				if err := fi.createRecords(importID, chunk); err != nil {
 					fmt.Printf("error creating records: %v\n", err)
 				}
 			}
@@ -114,12 +133,19 @@ func (fi *FileImporter) processFile(ctx context.Context, filePath string) error
 	close(chunkChan)
 	wg.Wait()
 
-	fi.Dispatcher.Dispatch(ctx, EventImported, nil)
+	payload, err := json.Marshal(ImportedPayload{ImportID: importID, FilePath: filePath, StartedAt: startedAt})
+	if err != nil {
+		return fmt.Errorf("error encoding event payload: %w", err)
+	}
+
+	if err := fi.Dispatcher.Dispatch(ctx, EventImported, payload); err != nil {
+		return fmt.Errorf("error dispatching %s: %w", EventImported, err)
+	}
 
 	return nil
 }
 
-func (fi *FileImporter) createRecords(records [][]string) error {
+func (fi *FileImporter) createRecords(importID string, records [][]string) error {
 	transactions := make([]*dto.Transaction, 0, len(records))
 
 	for _, record := range records {
@@ -127,6 +153,7 @@ func (fi *FileImporter) createRecords(records [][]string) error {
 		if err != nil {
 			return err
 		}
+		transaction.ImportID = importID
 		transactions = append(transactions, transaction)
 	}
 
@@ -134,7 +161,8 @@ func (fi *FileImporter) createRecords(records [][]string) error {
 }
 
 func (fi *FileImporter) parseRecord(record []string) (*dto.Transaction, error) {
-	if len(record) != 4 {
+	// the category column is optional, older files only have 4 columns
+	if len(record) != 4 && len(record) != 5 {
 		return nil, fmt.Errorf("invalid record: %v", record)
 	}
 
@@ -162,12 +190,18 @@ func (fi *FileImporter) parseRecord(record []string) (*dto.Transaction, error) {
 		operationType = dto.Debit
 	}
 
+	category := dto.Uncategorized
+	if len(record) == 5 && strings.TrimSpace(record[4]) != "" {
+		category = strings.ToLower(strings.TrimSpace(record[4]))
+	}
+
 	transaction := &dto.Transaction{
 		ExternalID: id,
 		Date:       date,
 		Amount:     amount,
 		AccountID:  uint(accountID),
 		Type:       operationType,
+		Category:   category,
 	}
 	return transaction, nil
 }
@@ -182,3 +216,11 @@ func (fi *FileImporter) parseDate(currentYear int, dateStr string) (time.Time, e
 	}
 	return date, nil
 }
+
+func newImportID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating import ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
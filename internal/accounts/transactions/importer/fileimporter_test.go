@@ -2,6 +2,7 @@ package importer
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"testing"
 
@@ -27,11 +28,14 @@ func (t *TransactionRepositoryMock) Create(transaction []*dto.Transaction) error
 }
 
 type EventDispatcherMock struct {
-	Event string
+	Event   string
+	Payload []byte
 }
 
-func (e *EventDispatcherMock) Dispatch(ctx context.Context, event string, payload []byte) {
+func (e *EventDispatcherMock) Dispatch(ctx context.Context, event string, payload []byte) error {
 	e.Event = event
+	e.Payload = payload
+	return nil
 }
 
 func TestFileImporter_Import(t *testing.T) {
@@ -52,5 +56,14 @@ func TestFileImporter_Import(t *testing.T) {
 
 	assert.Equal(t, EventImported, eventDispatcher.Event)
 
+	var payload ImportedPayload
+	assert.NoError(t, json.Unmarshal(eventDispatcher.Payload, &payload))
+	assert.Equal(t, "./fixtures/random_transactions.csv", payload.FilePath)
+	assert.False(t, payload.StartedAt.IsZero())
+	assert.NotEmpty(t, payload.ImportID)
+
 	assert.Len(t, transactionRepository.transactions, 100000)
+	for _, transaction := range transactionRepository.transactions {
+		assert.Equal(t, payload.ImportID, transaction.ImportID)
+	}
 }
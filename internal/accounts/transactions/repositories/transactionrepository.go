@@ -1,14 +1,24 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/juaguz/storid/internal/accounts/models"
 	"github.com/juaguz/storid/internal/accounts/transactions/dto"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// BalanceUpdater updates the balances with the transactions inserted by tx.
+type BalanceUpdater interface {
+	Apply(tx *gorm.DB, externalIDs []string) error
+}
+
 type TransactionDBRepository struct {
-	DB *gorm.DB
+	DB             *gorm.DB
+	BalanceUpdater BalanceUpdater
 }
 
 func NewTransactionRepository(db *gorm.DB) *TransactionDBRepository {
@@ -17,24 +27,160 @@ func NewTransactionRepository(db *gorm.DB) *TransactionDBRepository {
 	}
 }
 
+// NewIncrementalTransactionRepository updates the balances in the same DB transaction as the insert.
+func NewIncrementalTransactionRepository(db *gorm.DB, updater BalanceUpdater) *TransactionDBRepository {
+	return &TransactionDBRepository{
+		DB:             db,
+		BalanceUpdater: updater,
+	}
+}
+
 func (tr *TransactionDBRepository) Create(transactions []*dto.Transaction) error {
 	var transactionsToCreate []models.Transaction
+	externalIDs := make([]string, 0, len(transactions))
 	for _, t := range transactions {
+		externalIDs = append(externalIDs, t.ExternalID)
 
 		transaction := models.Transaction{
 			ExternalID: t.ExternalID,
 			Date:       t.Date,
 			Amount:     t.Amount,
 			Type:       string(t.Type),
+			Category:   t.Category,
+			ImportID:   t.ImportID,
 			AccountID:  t.AccountID,
 		}
 
 		transactionsToCreate = append(transactionsToCreate, transaction)
 	}
 
-	tr.DB.Clauses(clause.OnConflict{
+	if tr.BalanceUpdater == nil {
+		return tr.create(tr.DB, transactionsToCreate)
+	}
+
+	return tr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tr.create(tx, transactionsToCreate); err != nil {
+			return err
+		}
+		return tr.BalanceUpdater.Apply(tx, externalIDs)
+	})
+}
+
+func (tr *TransactionDBRepository) create(db *gorm.DB, transactions []models.Transaction) error {
+	err := db.Clauses(clause.OnConflict{
 		DoNothing: true,
-	}).Create(transactions)
+	}).Create(&transactions).Error
+	if err != nil {
+		return fmt.Errorf("error creating transactions: %w", err)
+	}
 
 	return nil
 }
+
+// GetAccountIDs returns the IDs of the accounts with at least one transaction.
+func (tr *TransactionDBRepository) GetAccountIDs(ctx context.Context) ([]uint, error) {
+	var accountIDs []uint
+	err := tr.DB.WithContext(ctx).Model(&models.Transaction{}).Distinct("account_id").Order("account_id").Pluck("account_id", &accountIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting account IDs: %w", err)
+	}
+
+	return accountIDs, nil
+}
+
+// GetAccountIDsByImportID returns the IDs of the accounts with transactions created by the import.
+func (tr *TransactionDBRepository) GetAccountIDsByImportID(ctx context.Context, importID string) ([]uint, error) {
+	var accountIDs []uint
+	err := tr.DB.WithContext(ctx).Model(&models.Transaction{}).Where("import_id = ?", importID).Distinct("account_id").Order("account_id").Pluck("account_id", &accountIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting account IDs of import %s: %w", importID, err)
+	}
+
+	return accountIDs, nil
+}
+
+// FindByImportID returns the transactions created by the import ordered by account and date, the
+// rows of the file that were already stored belong to the import that created them.
+func (tr *TransactionDBRepository) FindByImportID(ctx context.Context, importID string) ([]*dto.Transaction, error) {
+	var records []models.Transaction
+	err := tr.DB.WithContext(ctx).Where("import_id = ?", importID).Order("account_id, date").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions of import %s: %w", importID, err)
+	}
+
+	return toDTOs(records), nil
+}
+
+// FindByAccountID returns the transactions of the account ordered by date.
+func (tr *TransactionDBRepository) FindByAccountID(ctx context.Context, accountID uint) ([]*dto.Transaction, error) {
+	var records []models.Transaction
+	err := tr.DB.WithContext(ctx).Where("account_id = ?", accountID).Order("date").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions by account ID: %w", err)
+	}
+
+	return toDTOs(records), nil
+}
+
+// FindByAccountIDAndPeriod returns the transactions of the account in [from, to) ordered by date.
+func (tr *TransactionDBRepository) FindByAccountIDAndPeriod(ctx context.Context, accountID uint, from, to time.Time) ([]*dto.Transaction, error) {
+	var records []models.Transaction
+	err := tr.DB.WithContext(ctx).
+		Where("account_id = ? AND date >= ? AND date < ?", accountID, from, to).
+		Order("date, id").
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions by account ID and period: %w", err)
+	}
+
+	return toDTOs(records), nil
+}
+
+// GetBalanceBefore returns the balance of the account with the transactions dated before the given time.
+func (tr *TransactionDBRepository) GetBalanceBefore(ctx context.Context, accountID uint, before time.Time) (int, error) {
+	var balance int
+	err := tr.DB.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("account_id = ? AND date < ?", accountID, before).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, fmt.Errorf("error getting balance before %s: %w", before, err)
+	}
+
+	return balance, nil
+}
+
+// GetFirstTransactionDate returns the date of the oldest transaction of the account.
+func (tr *TransactionDBRepository) GetFirstTransactionDate(ctx context.Context, accountID uint) (time.Time, error) {
+	var first *time.Time
+	err := tr.DB.WithContext(ctx).Model(&models.Transaction{}).
+		Select("MIN(date)").
+		Where("account_id = ?", accountID).
+		Scan(&first).Error
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting first transaction date: %w", err)
+	}
+	if first == nil {
+		return time.Time{}, fmt.Errorf("account %d has no transactions", accountID)
+	}
+
+	return *first, nil
+}
+
+func toDTOs(records []models.Transaction) []*dto.Transaction {
+	transactions := make([]*dto.Transaction, 0, len(records))
+	for _, r := range records {
+		transactions = append(transactions, &dto.Transaction{
+			ID:         r.ID,
+			ExternalID: r.ExternalID,
+			Date:       r.Date,
+			Amount:     r.Amount,
+			AccountID:  r.AccountID,
+			Type:       dto.TransactionType(r.Type),
+			Category:   r.Category,
+			ImportID:   r.ImportID,
+		})
+	}
+
+	return transactions
+}
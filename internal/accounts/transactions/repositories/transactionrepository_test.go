@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juaguz/storid/internal/accounts/models"
+	"github.com/juaguz/storid/internal/accounts/transactions/dto"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB nothing listens on the port, the statements fail unless the session is a dry run.
+func openTestDB(t *testing.T, dryRun bool) *gorm.DB {
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 user=test dbname=test sslmode=disable connect_timeout=1"), &gorm.Config{
+		DisableAutomaticPing:   true,
+		DryRun:                 dryRun,
+		SkipDefaultTransaction: dryRun,
+		Logger:                 logger.Discard,
+	})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestTransactionDBRepository_Create(t *testing.T) {
+	transactions := []*dto.Transaction{
+		{ExternalID: "1", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: -1500, AccountID: 8, Type: dto.Debit, Category: "groceries"},
+	}
+
+	// the models are inserted, not the DTOs, so gorm fills the timestamps
+	db := openTestDB(t, true)
+	var created interface{}
+	assert.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		created = tx.Statement.Dest
+	}))
+	assert.NoError(t, NewTransactionRepository(db).Create(transactions))
+	if assert.IsType(t, &[]models.Transaction{}, created) {
+		records := *created.(*[]models.Transaction)
+		assert.Len(t, records, 1)
+		assert.Equal(t, "1", records[0].ExternalID)
+		assert.Equal(t, "groceries", records[0].Category)
+		assert.Equal(t, uint(8), records[0].AccountID)
+	}
+
+	// the insert errors are returned instead of being dropped
+	err := NewTransactionRepository(openTestDB(t, false)).Create(transactions)
+	assert.ErrorContains(t, err, "error creating transactions")
+}
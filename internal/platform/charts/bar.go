@@ -0,0 +1,109 @@
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+var (
+	Blue  = color.RGBA{R: 37, G: 150, B: 190, A: 255}
+	Green = color.RGBA{R: 46, G: 160, B: 67, A: 255}
+	Red   = color.RGBA{R: 207, G: 34, B: 46, A: 255}
+
+	background = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	axis       = color.RGBA{R: 170, G: 170, B: 170, A: 255}
+)
+
+const padding = 8
+
+// Series is drawn as one bar per value, NegativeColor is used for the negative values when it is set.
+type Series struct {
+	Values        []int
+	Color         color.RGBA
+	NegativeColor color.RGBA
+}
+
+// BarChart draws the series side by side in one slot per value, the positive values go up from the
+// zero line and the negative ones down. The charts have no text so they do not need fonts, the
+// labels are written next to them by whoever embeds the image.
+type BarChart struct {
+	Width  int
+	Height int
+	Series []Series
+}
+
+func NewBarChart(width, height int, series ...Series) *BarChart {
+	return &BarChart{
+		Width:  width,
+		Height: height,
+		Series: series,
+	}
+}
+
+// Image renders the chart, the values are scaled so the highest bar takes the whole height.
+func (c *BarChart) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	slots, highest, lowest := 0, 0, 0
+	for _, s := range c.Series {
+		slots = max(slots, len(s.Values))
+		for _, v := range s.Values {
+			highest = max(highest, v)
+			lowest = min(lowest, v)
+		}
+	}
+
+	plotWidth, plotHeight := c.Width-2*padding, c.Height-2*padding
+	if plotWidth <= 0 || plotHeight <= 0 {
+		return img
+	}
+
+	// y returns the row of the value, the zero line is at the middle when there are no values
+	y := func(v int) int {
+		if highest == lowest {
+			return padding + plotHeight/2
+		}
+		return padding + (highest-v)*plotHeight/(highest-lowest)
+	}
+
+	zero := y(0)
+	if slots > 0 {
+		slotWidth := plotWidth / slots
+		// a fifth of the slot separates the bars of the next value
+		barWidth := max(1, slotWidth*4/5/len(c.Series))
+
+		for i := 0; i < slots; i++ {
+			x := padding + i*slotWidth + slotWidth/10
+			for _, s := range c.Series {
+				if i < len(s.Values) {
+					v := s.Values[i]
+					col := s.Color
+					if v < 0 && s.NegativeColor.A > 0 {
+						col = s.NegativeColor
+					}
+					top, bottom := min(y(v), zero), max(y(v), zero)
+					draw.Draw(img, image.Rect(x, top, x+barWidth, bottom), image.NewUniform(col), image.Point{}, draw.Src)
+				}
+				x += barWidth
+			}
+		}
+	}
+
+	draw.Draw(img, image.Rect(padding, zero, c.Width-padding, zero+1), image.NewUniform(axis), image.Point{}, draw.Src)
+
+	return img
+}
+
+// PNG returns the chart encoded as PNG, the image type the email clients show inline.
+func (c *BarChart) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image()); err != nil {
+		return nil, fmt.Errorf("error encoding chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
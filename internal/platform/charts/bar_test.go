@@ -0,0 +1,57 @@
+package charts
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarChart_Image(t *testing.T) {
+	chart := NewBarChart(116, 116, Series{Values: []int{100, -50}, Color: Green, NegativeColor: Red})
+	img := chart.Image()
+
+	// 100 px of plot, the zero line is two thirds down
+	zero := padding + 100*100/150
+	assert.Equal(t, axis, img.RGBAAt(padding, zero))
+
+	// the first slot goes up to the top, the second one down to the bottom
+	assert.Equal(t, Green, img.RGBAAt(padding+10, padding))
+	assert.Equal(t, Green, img.RGBAAt(padding+10, zero-1))
+	assert.Equal(t, background, img.RGBAAt(padding+10, zero+1))
+	assert.Equal(t, Red, img.RGBAAt(padding+60, zero+1))
+	assert.Equal(t, Red, img.RGBAAt(padding+60, 116-padding-1))
+	assert.Equal(t, background, img.RGBAAt(padding+60, zero-1))
+
+	// the gap between the slots
+	assert.Equal(t, background, img.RGBAAt(padding+1, padding))
+}
+
+func TestBarChart_ImageSeries(t *testing.T) {
+	chart := NewBarChart(116, 116,
+		Series{Values: []int{10}, Color: Green},
+		Series{Values: []int{-10}, Color: Red},
+	)
+	img := chart.Image()
+
+	zero := padding + 50
+	assert.Equal(t, Green, img.RGBAAt(padding+10, zero-1))
+	assert.Equal(t, Red, img.RGBAAt(padding+10+40, zero+1))
+	assert.Equal(t, background, img.RGBAAt(padding+10+40, zero-1))
+}
+
+func TestBarChart_PNG(t *testing.T) {
+	content, err := NewBarChart(560, 200, Series{Values: []int{1, 2, 3}, Color: Blue}).PNG()
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, 560, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+
+	// without values only the zero line is drawn
+	empty := NewBarChart(100, 100).Image()
+	assert.Equal(t, axis, empty.RGBAAt(50, padding+42))
+	assert.Equal(t, background, empty.RGBAAt(50, padding))
+}
@@ -1,7 +1,16 @@
 package config
 
 import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/juaguz/storid/internal/accounts/balances"
+	"github.com/juaguz/storid/internal/platform/notifications"
+	"github.com/juaguz/storid/internal/platform/sms"
+	"go.uber.org/zap"
 )
 
 type DBConfig struct {
@@ -17,15 +26,395 @@ type S3Config struct {
 	Client *s3.Client
 }
 
-type SMTPConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
+type AlertThresholds struct {
+	ZScore     float64 `json:"z_score"`
+	LargeDebit int     `json:"large_debit"`
+	DailyBurst int     `json:"daily_burst"`
+	MinHistory int     `json:"min_history"`
+}
+
+// BalanceConfig Mode is either "refresh" (default) or "incremental",
+// DailyBalanceDays amount of days of daily balances shown in the summary, 0 hides them.
+type BalanceConfig struct {
+	Mode             string
+	DailyBalanceDays int
+}
+
+// SummaryConfig AttachPDF attaches a PDF statement to the summary emails, Charts embeds the monthly
+// balance and the credits and debits charts in them,
+// CSVDelivery sends the transactions as a CSV "attachment" or as a link to a file uploaded to "s3",
+// empty disables the export.
+type SummaryConfig struct {
+	AttachPDF     bool
+	Charts        bool
+	CSVDelivery   string
+	ExportBucket  string
+	ExportLinkTTL time.Duration
+}
+
+// LocaleConfig DefaultLocale is used for the accounts without a locale.
+type LocaleConfig struct {
+	DefaultLocale string
+}
+
+// UnsubscribeConfig Secret signs the unsubscribe tokens, the links are disabled without Secret or BaseURL.
+type UnsubscribeConfig struct {
+	Secret   string
+	BaseURL  string
+	TokenTTL time.Duration
+}
+
+// WebhookConfig Enabled adds the webhook notifier, the accounts without a webhook use DefaultURL
+// and the webhooks without a secret are signed with Secret.
+type WebhookConfig struct {
+	Enabled    bool
+	DefaultURL string
+	Secret     string
+	Timeout    time.Duration
+}
+
+// SMSConfig Enabled adds the SMS notifier, Provider is "http" (default) to send them through the
+// gateway at URL or "fake" to only log them.
+type SMSConfig struct {
+	Enabled  bool
+	Provider string
+	URL      string
+	APIKey   string
+	From     string
+	Timeout  time.Duration
+}
+
+// ChatConfig Enabled adds the notifier that posts the summaries to the Slack or Teams channel of the accounts.
+type ChatConfig struct {
+	Enabled bool
+	Timeout time.Duration
+}
+
+// SenderConfig Workers amount of notifications sent at the same time, RateLimits notifications per second
+// indexed by channel, the transient failures are retried MaxRetries times doubling RetryBackoff.
+// PageSize amount of accounts loaded at a time. The zero values, and a nil MaxRetries, keep the defaults of
+// summary.NewSender.
+type SenderConfig struct {
+	Workers      int
+	RateLimits   map[string]float64
+	MaxRetries   *int
+	RetryBackoff time.Duration
+	PageSize     int
+}
+
+// AlertConfig thresholds used by the anomaly alerts, indexed by account tier.
+type AlertConfig struct {
+	Thresholds map[string]AlertThresholds
 }
 
 type Config struct {
-	DBConfig   *DBConfig
-	S3Config   *S3Config
-	SMTPConfig *SMTPConfig
+	DBConfig          *DBConfig
+	S3Config          *S3Config
+	SMTPConfig        *notifications.SMTPConfig
+	AlertConfig       *AlertConfig
+	BalanceConfig     *BalanceConfig
+	SummaryConfig     *SummaryConfig
+	TemplateConfig    *notifications.TemplateConfig
+	LocaleConfig      *LocaleConfig
+	UnsubscribeConfig *UnsubscribeConfig
+	SenderConfig      *SenderConfig
+	WebhookConfig     *WebhookConfig
+	SMSConfig         *SMSConfig
+	ChatConfig        *ChatConfig
+}
+
+// loadSMTPConfig the server and the credentials come from the environment locally and from a secret in production.
+// The timeouts left unset get the defaults of NewSMTPTransport, SMTP_POOL_SIZE=0 disables the pool.
+func loadSMTPConfig(logger *zap.Logger, host, port, username, password string) *notifications.SMTPConfig {
+	smtpConfig := &notifications.SMTPConfig{
+		Host:          host,
+		Port:          port,
+		Username:      username,
+		Password:      password,
+		HeloHostname:  os.Getenv("SMTP_HELO_HOSTNAME"),
+		TLSMode:       os.Getenv("SMTP_TLS_MODE"),
+		AuthMechanism: os.Getenv("SMTP_AUTH"),
+		PoolSize:      notifications.DefaultSMTPPoolSize,
+	}
+
+	if skip := os.Getenv("SMTP_TLS_SKIP_VERIFY"); skip != "" {
+		var err error
+		if smtpConfig.TLSSkipVerify, err = strconv.ParseBool(skip); err != nil {
+			logger.Error("Invalid SMTP_TLS_SKIP_VERIFY", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("SMTP_DIAL_TIMEOUT"); timeout != "" {
+		var err error
+		if smtpConfig.DialTimeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid SMTP_DIAL_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("SMTP_TIMEOUT"); timeout != "" {
+		var err error
+		if smtpConfig.Timeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid SMTP_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	if poolSize := os.Getenv("SMTP_POOL_SIZE"); poolSize != "" {
+		var err error
+		if smtpConfig.PoolSize, err = strconv.Atoi(poolSize); err != nil {
+			logger.Error("Invalid SMTP_POOL_SIZE", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("SMTP_IDLE_TIMEOUT"); timeout != "" {
+		var err error
+		if smtpConfig.IdleTimeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid SMTP_IDLE_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	return smtpConfig
+}
+
+func loadBalanceConfig(logger *zap.Logger) *BalanceConfig {
+	balanceConfig := &BalanceConfig{
+		Mode: balances.ModeRefresh,
+	}
+
+	switch mode := os.Getenv("BALANCE_MODE"); mode {
+	case "":
+	case balances.ModeRefresh, balances.ModeIncremental:
+		balanceConfig.Mode = mode
+	default:
+		logger.Fatal("Invalid BALANCE_MODE, it must be refresh or incremental", zap.String("mode", mode))
+	}
+
+	if days := os.Getenv("SUMMARY_DAILY_BALANCE_DAYS"); days != "" {
+		var err error
+		if balanceConfig.DailyBalanceDays, err = strconv.Atoi(days); err != nil {
+			logger.Error("Invalid SUMMARY_DAILY_BALANCE_DAYS", zap.Error(err))
+		}
+	}
+
+	return balanceConfig
+}
+
+func loadSummaryConfig(logger *zap.Logger) *SummaryConfig {
+	summaryConfig := &SummaryConfig{
+		CSVDelivery:   os.Getenv("SUMMARY_CSV_DELIVERY"),
+		ExportBucket:  os.Getenv("SUMMARY_EXPORT_BUCKET"),
+		ExportLinkTTL: 7 * 24 * time.Hour,
+	}
+
+	if attach := os.Getenv("SUMMARY_ATTACH_PDF"); attach != "" {
+		var err error
+		if summaryConfig.AttachPDF, err = strconv.ParseBool(attach); err != nil {
+			logger.Error("Invalid SUMMARY_ATTACH_PDF", zap.Error(err))
+		}
+	}
+
+	if charts := os.Getenv("SUMMARY_CHARTS"); charts != "" {
+		var err error
+		if summaryConfig.Charts, err = strconv.ParseBool(charts); err != nil {
+			logger.Error("Invalid SUMMARY_CHARTS", zap.Error(err))
+		}
+	}
+
+	if ttl := os.Getenv("SUMMARY_EXPORT_LINK_TTL"); ttl != "" {
+		var err error
+		if summaryConfig.ExportLinkTTL, err = time.ParseDuration(ttl); err != nil {
+			logger.Error("Invalid SUMMARY_EXPORT_LINK_TTL", zap.Error(err))
+		}
+	}
+
+	return summaryConfig
+}
+
+// loadTemplateConfig TEMPLATE_VERSIONS is a JSON object indexed by template e.g. {"summary": "v2"}
+func loadTemplateConfig(logger *zap.Logger) *notifications.TemplateConfig {
+	templateConfig := &notifications.TemplateConfig{
+		Source:   os.Getenv("TEMPLATE_SOURCE"),
+		Dir:      os.Getenv("TEMPLATE_DIR"),
+		Bucket:   os.Getenv("TEMPLATE_BUCKET"),
+		Prefix:   os.Getenv("TEMPLATE_PREFIX"),
+		CacheTTL: 5 * time.Minute,
+		Versions: map[string]string{},
+	}
+
+	if ttl := os.Getenv("TEMPLATE_CACHE_TTL"); ttl != "" {
+		var err error
+		if templateConfig.CacheTTL, err = time.ParseDuration(ttl); err != nil {
+			logger.Error("Invalid TEMPLATE_CACHE_TTL", zap.Error(err))
+		}
+	}
+
+	if versions := os.Getenv("TEMPLATE_VERSIONS"); versions != "" {
+		if err := json.Unmarshal([]byte(versions), &templateConfig.Versions); err != nil {
+			logger.Error("Invalid TEMPLATE_VERSIONS", zap.Error(err))
+		}
+	}
+
+	return templateConfig
+}
+
+func loadLocaleConfig() *LocaleConfig {
+	locale := os.Getenv("DEFAULT_LOCALE")
+	if locale == "" {
+		locale = "en"
+	}
+	return &LocaleConfig{DefaultLocale: locale}
+}
+
+func loadUnsubscribeConfig(logger *zap.Logger, secret string) *UnsubscribeConfig {
+	unsubscribeConfig := &UnsubscribeConfig{
+		Secret:   secret,
+		BaseURL:  os.Getenv("UNSUBSCRIBE_BASE_URL"),
+		TokenTTL: 30 * 24 * time.Hour,
+	}
+
+	if ttl := os.Getenv("UNSUBSCRIBE_TOKEN_TTL"); ttl != "" {
+		var err error
+		if unsubscribeConfig.TokenTTL, err = time.ParseDuration(ttl); err != nil {
+			logger.Error("Invalid UNSUBSCRIBE_TOKEN_TTL", zap.Error(err))
+		}
+	}
+
+	return unsubscribeConfig
+}
+
+// loadSenderConfig SENDER_RATE_LIMITS is a JSON object indexed by channel e.g. {"email": 10}
+func loadSenderConfig(logger *zap.Logger) *SenderConfig {
+	senderConfig := &SenderConfig{
+		RateLimits: map[string]float64{},
+	}
+
+	if workers := os.Getenv("SENDER_WORKERS"); workers != "" {
+		var err error
+		if senderConfig.Workers, err = strconv.Atoi(workers); err != nil {
+			logger.Error("Invalid SENDER_WORKERS", zap.Error(err))
+		}
+	}
+
+	if limits := os.Getenv("SENDER_RATE_LIMITS"); limits != "" {
+		if err := json.Unmarshal([]byte(limits), &senderConfig.RateLimits); err != nil {
+			logger.Error("Invalid SENDER_RATE_LIMITS", zap.Error(err))
+		}
+	}
+
+	if retries := os.Getenv("SENDER_MAX_RETRIES"); retries != "" {
+		maxRetries, err := strconv.Atoi(retries)
+		if err != nil {
+			logger.Error("Invalid SENDER_MAX_RETRIES", zap.Error(err))
+		} else {
+			senderConfig.MaxRetries = &maxRetries
+		}
+	}
+
+	if backoff := os.Getenv("SENDER_RETRY_BACKOFF"); backoff != "" {
+		var err error
+		if senderConfig.RetryBackoff, err = time.ParseDuration(backoff); err != nil {
+			logger.Error("Invalid SENDER_RETRY_BACKOFF", zap.Error(err))
+		}
+	}
+
+	if pageSize := os.Getenv("SENDER_PAGE_SIZE"); pageSize != "" {
+		var err error
+		if senderConfig.PageSize, err = strconv.Atoi(pageSize); err != nil {
+			logger.Error("Invalid SENDER_PAGE_SIZE", zap.Error(err))
+		}
+	}
+
+	return senderConfig
+}
+
+func loadWebhookConfig(logger *zap.Logger, secret string) *WebhookConfig {
+	webhookConfig := &WebhookConfig{
+		DefaultURL: os.Getenv("WEBHOOK_DEFAULT_URL"),
+		Secret:     secret,
+		Timeout:    10 * time.Second,
+	}
+
+	if enabled := os.Getenv("WEBHOOKS_ENABLED"); enabled != "" {
+		var err error
+		if webhookConfig.Enabled, err = strconv.ParseBool(enabled); err != nil {
+			logger.Error("Invalid WEBHOOKS_ENABLED", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("WEBHOOK_TIMEOUT"); timeout != "" {
+		var err error
+		if webhookConfig.Timeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid WEBHOOK_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	return webhookConfig
+}
+
+func loadChatConfig(logger *zap.Logger) *ChatConfig {
+	chatConfig := &ChatConfig{
+		Timeout: 10 * time.Second,
+	}
+
+	if enabled := os.Getenv("CHAT_ENABLED"); enabled != "" {
+		var err error
+		if chatConfig.Enabled, err = strconv.ParseBool(enabled); err != nil {
+			logger.Error("Invalid CHAT_ENABLED", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("CHAT_TIMEOUT"); timeout != "" {
+		var err error
+		if chatConfig.Timeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid CHAT_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	return chatConfig
+}
+
+func loadSMSConfig(logger *zap.Logger, apiKey string) *SMSConfig {
+	smsConfig := &SMSConfig{
+		Provider: os.Getenv("SMS_PROVIDER"),
+		URL:      os.Getenv("SMS_API_URL"),
+		APIKey:   apiKey,
+		From:     os.Getenv("SMS_FROM"),
+		Timeout:  10 * time.Second,
+	}
+
+	if smsConfig.Provider == "" {
+		smsConfig.Provider = sms.ProviderHTTP
+	}
+
+	if enabled := os.Getenv("SMS_ENABLED"); enabled != "" {
+		var err error
+		if smsConfig.Enabled, err = strconv.ParseBool(enabled); err != nil {
+			logger.Error("Invalid SMS_ENABLED", zap.Error(err))
+		}
+	}
+
+	if timeout := os.Getenv("SMS_TIMEOUT"); timeout != "" {
+		var err error
+		if smsConfig.Timeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Error("Invalid SMS_TIMEOUT", zap.Error(err))
+		}
+	}
+
+	return smsConfig
+}
+
+// loadAlertConfig reads the thresholds from ALERT_THRESHOLDS, a JSON object indexed by tier e.g.
+// {"premium": {"z_score": 4, "large_debit": 500000, "daily_burst": 50, "min_history": 20}}
+func loadAlertConfig(logger *zap.Logger) *AlertConfig {
+	alertConfig := &AlertConfig{Thresholds: map[string]AlertThresholds{}}
+
+	raw := os.Getenv("ALERT_THRESHOLDS")
+	if raw == "" {
+		return alertConfig
+	}
+
+	if err := json.Unmarshal([]byte(raw), &alertConfig.Thresholds); err != nil {
+		logger.Error("Invalid ALERT_THRESHOLDS", zap.Error(err))
+	}
+
+	return alertConfig
 }
@@ -50,16 +50,19 @@ func LoadConfig(logger *zap.Logger) *Config {
 		}),
 	}
 
-	smtpConfig := &SMTPConfig{
-		Host:     os.Getenv("SMTP_HOST"),
-		Port:     os.Getenv("SMTP_PORT"),
-		Username: os.Getenv("SMTP_USERNAME"),
-		Password: os.Getenv("SMTP_PASSWORD"),
-	}
-
 	return &Config{
-		DBConfig:   dbConfig,
-		S3Config:   s3Config,
-		SMTPConfig: smtpConfig,
+		DBConfig:          dbConfig,
+		S3Config:          s3Config,
+		SMTPConfig:        loadSMTPConfig(logger, os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")),
+		AlertConfig:       loadAlertConfig(logger),
+		BalanceConfig:     loadBalanceConfig(logger),
+		SummaryConfig:     loadSummaryConfig(logger),
+		TemplateConfig:    loadTemplateConfig(logger),
+		LocaleConfig:      loadLocaleConfig(),
+		UnsubscribeConfig: loadUnsubscribeConfig(logger, os.Getenv("UNSUBSCRIBE_SECRET")),
+		SenderConfig:      loadSenderConfig(logger),
+		WebhookConfig:     loadWebhookConfig(logger, os.Getenv("WEBHOOK_SECRET")),
+		SMSConfig:         loadSMSConfig(logger, os.Getenv("SMS_API_KEY")),
+		ChatConfig:        loadChatConfig(logger),
 	}
 }
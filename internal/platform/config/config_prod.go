@@ -97,16 +97,62 @@ func LoadConfig(logger *zap.Logger) *Config {
 		Client: s3.NewFromConfig(awsCfg),
 	}
 
-	smtpConfig := &SMTPConfig{
-		Host:     smtpSecretData["SMTP_HOST"],
-		Port:     smtpSecretData["SMTP_PORT"],
-		Username: smtpUsername,
-		Password: smtpPassword,
+	// the apps don't start without the unsubscribe secret
+	var unsubscribeSecret string
+	if unsubscribeSecretID := os.Getenv("UNSUBSCRIBE_SECRET_ID"); unsubscribeSecretID != "" {
+		unsubscribeSecretValue, err := fetchSecretFromAWS(unsubscribeSecretID)
+		if err != nil {
+			logger.Fatal("Failed to fetch unsubscribe secret from AWS Secrets Manager", zap.Error(err))
+		}
+
+		var unsubscribeSecretData map[string]string
+		if err := json.Unmarshal([]byte(unsubscribeSecretValue), &unsubscribeSecretData); err != nil {
+			logger.Error("Failed to parse unsubscribe secret", zap.Error(err))
+		}
+		unsubscribeSecret = unsubscribeSecretData["UNSUBSCRIBE_SECRET"]
+	}
+
+	var webhookSecret string
+	if webhookSecretID := os.Getenv("WEBHOOK_SECRET_ID"); webhookSecretID != "" {
+		webhookSecretValue, err := fetchSecretFromAWS(webhookSecretID)
+		if err != nil {
+			logger.Fatal("Failed to fetch webhook secret from AWS Secrets Manager", zap.Error(err))
+		}
+
+		var webhookSecretData map[string]string
+		if err := json.Unmarshal([]byte(webhookSecretValue), &webhookSecretData); err != nil {
+			logger.Error("Failed to parse webhook secret", zap.Error(err))
+		}
+		webhookSecret = webhookSecretData["WEBHOOK_SECRET"]
+	}
+
+	var smsAPIKey string
+	if smsSecretID := os.Getenv("SMS_API_KEY_SECRET_ID"); smsSecretID != "" {
+		smsSecretValue, err := fetchSecretFromAWS(smsSecretID)
+		if err != nil {
+			logger.Fatal("Failed to fetch SMS secret from AWS Secrets Manager", zap.Error(err))
+		}
+
+		var smsSecretData map[string]string
+		if err := json.Unmarshal([]byte(smsSecretValue), &smsSecretData); err != nil {
+			logger.Error("Failed to parse SMS secret", zap.Error(err))
+		}
+		smsAPIKey = smsSecretData["SMS_API_KEY"]
 	}
 
 	return &Config{
-		DBConfig:   dbConfig,
-		S3Config:   s3Config,
-		SMTPConfig: smtpConfig,
+		DBConfig:          dbConfig,
+		S3Config:          s3Config,
+		SMTPConfig:        loadSMTPConfig(logger, smtpSecretData["SMTP_HOST"], smtpSecretData["SMTP_PORT"], smtpUsername, smtpPassword),
+		AlertConfig:       loadAlertConfig(logger),
+		BalanceConfig:     loadBalanceConfig(logger),
+		SummaryConfig:     loadSummaryConfig(logger),
+		TemplateConfig:    loadTemplateConfig(logger),
+		LocaleConfig:      loadLocaleConfig(),
+		UnsubscribeConfig: loadUnsubscribeConfig(logger, unsubscribeSecret),
+		SenderConfig:      loadSenderConfig(logger),
+		WebhookConfig:     loadWebhookConfig(logger, webhookSecret),
+		SMSConfig:         loadSMSConfig(logger, smsAPIKey),
+		ChatConfig:        loadChatConfig(logger),
 	}
 }
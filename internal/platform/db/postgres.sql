@@ -31,6 +31,18 @@ create table if not exists accounts
 create index if not exists idx_accounts_deleted_at
     on accounts (deleted_at);
 
+-- tier is used to pick the anomaly alert thresholds
+alter table accounts
+    add column if not exists tier text default 'standard';
+
+-- locale of the emails e.g. es-AR, empty uses the configured default
+alter table accounts
+    add column if not exists locale text;
+
+-- phone in E.164 format, the accounts without a phone do not receive SMS
+alter table accounts
+    add column if not exists phone text;
+
 create table if not exists transactions
 (
     id
@@ -64,6 +76,8 @@ create table if not exists transactions
     bigint,
     type
     text,
+    category
+    text,
     account_id
     bigint
     constraint
@@ -72,9 +86,24 @@ create table if not exists transactions
     accounts
 );
 
+-- the summaries are computed a page of accounts at a time
+create index if not exists idx_transactions_account_id
+    on transactions (account_id);
+
 create index if not exists idx_transactions_deleted_at
     on transactions (deleted_at);
 
+-- category was added after the first release, keep existing databases in sync
+alter table transactions
+    add column if not exists category text;
+
+-- the handlers of the Imported event only read the transactions created by that import
+alter table transactions
+    add column if not exists import_id text;
+
+create index if not exists idx_transactions_import_id
+    on transactions (import_id);
+
 
 CREATE
 MATERIALIZED VIEW IF NOT EXISTS monthly_balances AS
@@ -107,3 +136,222 @@ GROUP BY account_id;
 
 CREATE UNIQUE INDEX IF NOT EXISTS idx_balances_account ON balances (account_id);
 
+CREATE
+MATERIALIZED VIEW IF NOT EXISTS daily_balances AS
+SELECT account_id,
+       day,
+       credits,
+       debits,
+       transaction_count,
+       SUM(credits + debits) OVER (PARTITION BY account_id ORDER BY day) AS balance
+FROM (SELECT account_id,
+             DATE(date)                                            AS day,
+             SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END) AS credits,
+             SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END)  AS debits,
+             COUNT(*)                                              AS transaction_count
+      FROM transactions
+      GROUP BY account_id, DATE(date)) AS days;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_daily_balances_account_day ON daily_balances (account_id, day);
+
+
+create table if not exists subscriptions
+(
+    id                 bigserial primary key,
+    created_at         timestamp with time zone,
+    updated_at         timestamp with time zone,
+    deleted_at         timestamp with time zone,
+    account_id         bigint
+        constraint fk_accounts_subscriptions references accounts,
+    merchant           text,
+    "interval"         text,
+    amount             bigint,
+    previous_amount    bigint,
+    occurrences        integer,
+    last_date          timestamp with time zone,
+    next_expected_date timestamp with time zone,
+    price_increased    boolean default false
+);
+
+create index if not exists idx_subscriptions_account_id
+    on subscriptions (account_id);
+
+-- balance_totals is maintained incrementally by the importer when BALANCE_MODE=incremental,
+-- sums and counts are stored so the averages can be recalculated without reading the transactions.
+create table if not exists balance_totals
+(
+    account_id   bigint not null
+        constraint fk_accounts_balance_totals references accounts,
+    month        text   not null,
+    credit_sum   bigint not null default 0,
+    credit_count bigint not null default 0,
+    debit_sum    bigint not null default 0,
+    debit_count  bigint not null default 0,
+    primary key (account_id, month)
+);
+
+CREATE OR REPLACE VIEW incremental_monthly_balances AS
+SELECT account_id,
+       credit_sum + debit_sum                                   AS total_balance,
+       credit_count + debit_count                               AS transaction_count,
+       CAST(credit_sum / NULLIF(credit_count, 0) AS bigint)     AS avg_credit_amount,
+       CAST(debit_sum / NULLIF(debit_count, 0) AS bigint)       AS avg_debit_amount,
+       month
+FROM balance_totals;
+
+CREATE OR REPLACE VIEW incremental_balances AS
+SELECT account_id,
+       SUM(credit_sum) + SUM(debit_sum)                                     AS total_balance,
+       SUM(credit_count) + SUM(debit_count)                                 AS transaction_count,
+       CAST(SUM(credit_sum) / NULLIF(SUM(credit_count), 0) AS bigint)       AS avg_credit_amount,
+       CAST(SUM(debit_sum) / NULLIF(SUM(debit_count), 0) AS bigint)         AS avg_debit_amount
+FROM balance_totals
+GROUP BY account_id;
+
+-- daily_totals is maintained with balance_totals, the running balance is calculated when the view is read.
+create table if not exists daily_totals
+(
+    account_id        bigint not null
+        constraint fk_accounts_daily_totals references accounts,
+    day               date   not null,
+    credits           bigint not null default 0,
+    debits            bigint not null default 0,
+    transaction_count bigint not null default 0,
+    primary key (account_id, day)
+);
+
+CREATE OR REPLACE VIEW incremental_daily_balances AS
+SELECT account_id,
+       day,
+       credits,
+       debits,
+       transaction_count,
+       SUM(credits + debits) OVER (PARTITION BY account_id ORDER BY day) AS balance
+FROM daily_totals;
+
+create table if not exists balance_refresh_history
+(
+    id          bigserial primary key,
+    view        text,
+    started_at  timestamp with time zone,
+    duration_ms bigint,
+    status      text,
+    error       text
+);
+
+create index if not exists idx_balance_refresh_history_view_started_at
+    on balance_refresh_history (view, started_at);
+
+create table if not exists statements
+(
+    id              bigserial primary key,
+    created_at      timestamp with time zone,
+    account_id      bigint                   not null
+        constraint fk_accounts_statements references accounts,
+    sequence        integer                  not null,
+    period_start    timestamp with time zone not null,
+    period_end      timestamp with time zone not null,
+    opening_balance bigint                   not null,
+    closing_balance bigint                   not null,
+    total_credits   bigint                   not null,
+    total_debits    bigint                   not null,
+    credit_count    integer                  not null,
+    debit_count     integer                  not null,
+    transactions    text                     not null,
+    unique (account_id, sequence),
+    unique (account_id, period_start, period_end)
+);
+
+-- statements are immutable once generated
+CREATE OR REPLACE FUNCTION reject_statement_changes() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'statements are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_statements_immutable ON statements;
+CREATE TRIGGER trg_statements_immutable
+    BEFORE UPDATE OR DELETE
+    ON statements
+    FOR EACH ROW
+EXECUTE FUNCTION reject_statement_changes();
+
+-- accounts without preferences receive every notification
+create table if not exists notification_preferences
+(
+    account_id        bigint primary key
+        constraint fk_accounts_notification_preferences references accounts,
+    frequency         text     not null default 'always',
+    quiet_hours_start smallint not null default 0,
+    quiet_hours_end   smallint not null default 0,
+    timezone          text     not null default 'UTC',
+    unsubscribed_at   timestamp with time zone,
+    updated_at        timestamp with time zone
+);
+
+-- channels without a row are enabled
+create table if not exists notification_channel_preferences
+(
+    account_id bigint  not null
+        constraint fk_accounts_notification_channel_preferences references accounts,
+    channel    text    not null,
+    enabled    boolean not null,
+    primary key (account_id, channel)
+);
+
+create table if not exists notification_log
+(
+    id         bigserial primary key,
+    created_at timestamp with time zone,
+    account_id bigint not null,
+    channel    text   not null,
+    kind       text   not null,
+    status     text   not null,
+    reason     text
+);
+
+create index if not exists idx_notification_log_account_channel_kind
+    on notification_log (account_id, channel, kind, created_at);
+
+-- an account receives an idempotency key (e.g. summary:2024-03-10) once per channel,
+-- retried senders skip the sent deliveries
+create table if not exists notification_deliveries
+(
+    account_id          bigint  not null
+        constraint fk_accounts_notification_deliveries references accounts,
+    channel             text    not null,
+    idempotency_key     text    not null,
+    status              text    not null,
+    attempts            integer not null default 0,
+    provider_message_id text,
+    last_error          text,
+    created_at          timestamp with time zone,
+    updated_at          timestamp with time zone,
+    primary key (account_id, channel, idempotency_key)
+);
+
+create index if not exists idx_notification_deliveries_key_status
+    on notification_deliveries (idempotency_key, status);
+
+-- the summaries of the account are posted to its webhook, an empty secret uses the configured one
+create table if not exists notification_webhooks
+(
+    account_id bigint primary key
+        constraint fk_accounts_notification_webhooks references accounts,
+    url        text not null,
+    secret     text,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone
+);
+
+-- the summaries of the account (e.g. the internal treasury ones) are posted to a slack or teams channel
+create table if not exists notification_chat_channels
+(
+    account_id bigint primary key
+        constraint fk_accounts_notification_chat_channels references accounts,
+    url        text not null,
+    format     text not null default 'slack',
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone
+);
@@ -1,19 +1,28 @@
 package dispatcher
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 type EventHandler interface {
-	Handle(ctx context.Context, event string, payload []byte)
+	Handle(ctx context.Context, event string, payload []byte) error
 }
 
 type EventDispatcher interface {
 	Register(ctx context.Context, eventName string, handler EventHandler)
-	Dispatch(ctx context.Context, event string, payload []byte)
+	Dispatch(ctx context.Context, event string, payload []byte) error
 }
 
+// ErrorHandler receives the errors of the handlers executed asynchronously.
+type ErrorHandler func(event string, err error)
+
 type Dispatcher struct {
 	handlers map[string][]EventHandler
 	async    bool
+	// OnError is called with the errors of the async handlers, the sync ones are returned by Dispatch
+	OnError ErrorHandler
 }
 
 func NewSimpleEventDispatcher(async bool) *Dispatcher {
@@ -30,14 +39,23 @@ func (d *Dispatcher) Register(ctx context.Context, eventName string, handler Eve
 	d.handlers[eventName] = append(d.handlers[eventName], handler)
 }
 
-func (d *Dispatcher) Dispatch(ctx context.Context, event string, payload []byte) {
+// Dispatch runs every handler registered for the event, a failing handler does not stop the others.
+func (d *Dispatcher) Dispatch(ctx context.Context, event string, payload []byte) error {
+	var errs []error
 	if handlers, exists := d.handlers[event]; exists {
 		for _, handler := range handlers {
 			if d.async {
-				go handler.Handle(ctx, event, payload)
+				go func(handler EventHandler) {
+					if err := handler.Handle(ctx, event, payload); err != nil && d.OnError != nil {
+						d.OnError(event, err)
+					}
+				}(handler)
 				continue
 			}
-			handler.Handle(ctx, event, payload)
+			if err := handler.Handle(ctx, event, payload); err != nil {
+				errs = append(errs, fmt.Errorf("error handling event %s: %w", event, err))
+			}
 		}
 	}
+	return errors.Join(errs...)
 }
@@ -2,6 +2,7 @@ package dispatcher
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 
@@ -13,9 +14,10 @@ type MockHandler struct {
 	callCount int
 	mu        sync.Mutex
 	wg        *sync.WaitGroup
+	err       error
 }
 
-func (m *MockHandler) Handle(_ context.Context, event string, payload []byte) {
+func (m *MockHandler) Handle(_ context.Context, event string, payload []byte) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.called = true
@@ -23,6 +25,7 @@ func (m *MockHandler) Handle(_ context.Context, event string, payload []byte) {
 	if m.wg != nil {
 		m.wg.Done() // Signal that this handler has completed
 	}
+	return m.err
 }
 
 func TestEventDispatcher_RegisterAndDispatch(t *testing.T) {
@@ -111,3 +114,36 @@ func TestEventDispatcher_MultipleDispatches(t *testing.T) {
 	// Check that the handler was called twice
 	assert.Equal(t, 2, handler.callCount)
 }
+
+func TestEventDispatcher_Errors(t *testing.T) {
+	dispatcher := NewSimpleEventDispatcher(false)
+
+	failing := &MockHandler{err: errors.New("refresh failed")}
+	handler := &MockHandler{}
+
+	dispatcher.Register(context.Background(), "testEvent", failing)
+	dispatcher.Register(context.Background(), "testEvent", handler)
+
+	err := dispatcher.Dispatch(context.Background(), "testEvent", nil)
+
+	assert.ErrorIs(t, err, failing.err)
+	// the handlers after the failing one are still called
+	assert.Equal(t, 1, handler.callCount)
+
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), "otherEvent", nil))
+}
+
+func TestEventDispatcher_AsyncErrors(t *testing.T) {
+	dispatcher := NewSimpleEventDispatcher(true)
+
+	errs := make(chan error, 1)
+	dispatcher.OnError = func(event string, err error) {
+		errs <- err
+	}
+
+	failing := &MockHandler{err: errors.New("refresh failed")}
+	dispatcher.Register(context.Background(), "testEvent", failing)
+
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), "testEvent", nil))
+	assert.ErrorIs(t, <-errs, failing.err)
+}
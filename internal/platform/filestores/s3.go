@@ -0,0 +1,48 @@
+package filestores
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3FileStore uploads files and returns presigned links valid for LinkTTL.
+type S3FileStore struct {
+	S3Client *s3.Client
+	Bucket   string
+	LinkTTL  time.Duration
+}
+
+func NewS3FileStore(client *s3.Client, bucket string, linkTTL time.Duration) *S3FileStore {
+	return &S3FileStore{
+		S3Client: client,
+		Bucket:   bucket,
+		LinkTTL:  linkTTL,
+	}
+}
+
+func (s *S3FileStore) Upload(ctx context.Context, key, contentType string, content []byte) (string, error) {
+	_, err := s.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        bytes.NewReader(content),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error uploading %s: %w", key, err)
+	}
+
+	presigned, err := s3.NewPresignClient(s.S3Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(s.LinkTTL))
+	if err != nil {
+		return "", fmt.Errorf("error presigning %s: %w", key, err)
+	}
+
+	return presigned.URL, nil
+}
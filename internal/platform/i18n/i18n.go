@@ -0,0 +1,137 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juaguz/storid/internal/platform/months"
+)
+
+// DefaultLocale is used when the locale, or its language, is not supported.
+const DefaultLocale = "en"
+
+// NumberFormat how amounts are written in a locale, e.g. "$ 1.234,56" for es.
+type NumberFormat struct {
+	Symbol      string
+	SymbolSpace bool
+	Decimal     string
+	Thousands   string
+}
+
+var numberFormats = map[string]NumberFormat{
+	"en": {Symbol: "$", Decimal: ".", Thousands: ","},
+	"es": {Symbol: "$", SymbolSpace: true, Decimal: ",", Thousands: "."},
+}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogs translations indexed by language and key, loaded from locales/<language>.json
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		content, err := locales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		catalog := map[string]string{}
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			panic(fmt.Sprintf("invalid catalog %s: %v", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+
+	return result
+}
+
+// Languages returns the supported languages sorted.
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Language returns the supported language of the locale, e.g. "es" for "es-AR" or "es_AR".
+func Language(locale string) string {
+	language, _, _ := strings.Cut(strings.ReplaceAll(strings.ToLower(locale), "_", "-"), "-")
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	return DefaultLocale
+}
+
+// T translates the key, args are applied with fmt.Sprintf. Missing keys fall back to
+// DefaultLocale and then to the key itself.
+func T(locale, key string, args ...interface{}) string {
+	text, ok := catalogs[Language(locale)][key]
+	if !ok {
+		if text, ok = catalogs[DefaultLocale][key]; !ok {
+			text = key
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+func MonthName(locale string, month months.Month) string {
+	return T(locale, "month_"+strconv.Itoa(int(month)))
+}
+
+// FormatDecimal writes the number with the decimal separator of the locale, e.g. "3,5" for es.
+func FormatDecimal(locale string, value float64, precision int) string {
+	format, ok := numberFormats[Language(locale)]
+	if !ok {
+		format = numberFormats[DefaultLocale]
+	}
+
+	return strings.Replace(strconv.FormatFloat(value, 'f', precision, 64), ".", format.Decimal, 1)
+}
+
+// FormatCents writes the amount with the currency symbol and separators of the locale.
+func FormatCents(locale string, cents int) string {
+	format, ok := numberFormats[Language(locale)]
+	if !ok {
+		format = numberFormats[DefaultLocale]
+	}
+
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	units := strconv.Itoa(cents / 100)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteString(format.Thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	symbol := format.Symbol
+	if format.SymbolSpace {
+		symbol += " "
+	}
+
+	return fmt.Sprintf("%s%s%s%s%02d", sign, symbol, grouped.String(), format.Decimal, cents%100)
+}
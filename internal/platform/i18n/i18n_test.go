@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/juaguz/storid/internal/platform/months"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatCents(t *testing.T) {
+	tests := []struct {
+		locale   string
+		cents    int
+		expected string
+	}{
+		{"en", 123456, "$1,234.56"},
+		{"es-AR", 123456, "$ 1.234,56"},
+		{"es_AR", -123456789, "-$ 1.234.567,89"},
+		{"en-US", 5, "$0.05"},
+		{"es", 100000, "$ 1.000,00"},
+		{"fr", 99900, "$999.00"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, FormatCents(tt.locale, tt.cents), tt.locale)
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	assert.Equal(t, "3.5", FormatDecimal("en", 3.46, 1))
+	assert.Equal(t, "3,5", FormatDecimal("es-AR", 3.46, 1))
+	assert.Equal(t, "12", FormatDecimal("fr", 12, 0))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Resumen de saldo", T("es-AR", "subject_summary"))
+	assert.Equal(t, "Summary Balance", T("de", "subject_summary"))
+	assert.Equal(t, "Extracto N.º 3 (2024-02-15 - 2024-03-14)", T("es", "statement_period", 3, "2024-02-15", "2024-03-14"))
+	assert.Equal(t, "missing_key", T("es", "missing_key"))
+	assert.Equal(t, "Marzo", MonthName("es", months.March))
+	assert.Equal(t, "March", MonthName("", months.March))
+}
+
+func TestLanguages(t *testing.T) {
+	assert.Equal(t, []string{"en", "es"}, Languages())
+}
+
+func TestCatalogs_HaveTheSameKeys(t *testing.T) {
+	for language, catalog := range catalogs {
+		for key := range catalogs[DefaultLocale] {
+			assert.Contains(t, catalog, key, language)
+		}
+		for key := range catalog {
+			assert.Contains(t, catalogs[DefaultLocale], key, language)
+		}
+	}
+}
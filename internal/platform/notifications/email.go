@@ -1,80 +1,213 @@
 package notifications
 
 import (
-	_ "embed"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
-	"net/smtp"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
 	"strings"
+	texttemplate "text/template"
+	"time"
 
+	"github.com/juaguz/storid/internal/platform/i18n"
 	"go.uber.org/zap"
 )
 
+// UnsubscribeURLVariable when the variables have an unsubscribe link the email carries the
+// List-Unsubscribe headers, the link must accept the one-click POST of RFC 8058.
+const UnsubscribeURLVariable = "UnsubscribeURL"
+
+// SMTPConfig TLSMode is "none", "starttls" (default) or "implicit" and AuthMechanism "plain" (default),
+// "login" or "cram-md5", both case-insensitive. Up to PoolSize connections are kept open between the
+// emails for IdleTimeout, see SMTPTransport.
 type SMTPConfig struct {
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Host          string        `json:"host"`
+	Port          string        `json:"port"`
+	Username      string        `json:"username"`
+	Password      string        `json:"password"`
+	HeloHostname  string        `json:"helo_hostname"`
+	TLSMode       string        `json:"tls_mode"`
+	TLSSkipVerify bool          `json:"tls_skip_verify"`
+	AuthMechanism string        `json:"auth_mechanism"`
+	DialTimeout   time.Duration `json:"dial_timeout"`
+	Timeout       time.Duration `json:"timeout"`
+	PoolSize      int           `json:"pool_size"`
+	IdleTimeout   time.Duration `json:"idle_timeout"`
+}
+
+// Attachment is sent as a base64 encoded part of a multipart/mixed message,
+// when URL is set the file was uploaded and it is linked in the body instead.
+// When ContentID is set the file is shown inline, the HTML body references it as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	URL         string
+	ContentID   string
 }
 
+// SMTPService the emails are sent from the username of the transport.
 type SMTPService struct {
-	Host      string
-	Port      string
-	Username  string
-	Password  string
 	logger    *zap.Logger
-	templates map[string]string
+	Transport *SMTPTransport
+	Templates TemplateStore
+	// Versions template versions, see TemplateConfig
+	Versions map[string]string
 }
 
-//go:embed template/summary.html
-var summaryTemplate string
+type SMTPOption func(*SMTPService)
 
-func NewSMTPService(config *SMTPConfig, logger *zap.Logger) *SMTPService {
-	return &SMTPService{
-		Host:     config.Host,
-		Port:     config.Port,
-		Username: config.Username,
-		Password: config.Password,
-		logger:   logger,
-		templates: map[string]string{
-			"summary": summaryTemplate,
-		},
+// WithTemplateStore replaces the embedded templates.
+func WithTemplateStore(store TemplateStore) SMTPOption {
+	return func(s *SMTPService) {
+		s.Templates = store
+	}
+}
+
+func WithTemplateVersions(versions map[string]string) SMTPOption {
+	return func(s *SMTPService) {
+		s.Versions = versions
+	}
+}
+
+func NewSMTPService(config *SMTPConfig, logger *zap.Logger, options ...SMTPOption) (*SMTPService, error) {
+	transport, err := NewSMTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTPService{
+		logger:    logger,
+		Transport: transport,
+		Templates: NewEmbeddedTemplateStore(),
+	}
+
+	for _, opt := range options {
+		opt(s)
 	}
+
+	return s, nil
 }
 
-func (s *SMTPService) Send(to, subject, templateName string, variables map[string]interface{}) error {
-	emailTemplate, ok := s.templates[templateName]
-	if !ok {
-		s.logger.Error("template not found", zap.String("template", templateName))
-		return fmt.Errorf("template %s not found", templateName)
+// Send returns the Message-ID of the email.
+func (s *SMTPService) Send(to, subject, templateName string, variables map[string]interface{}, attachments ...Attachment) (string, error) {
+	htmlBody, textBody, err := s.Render(context.Background(), templateName, variables)
+	if err != nil {
+		return "", err
 	}
 	s.logger.Info("Sending email", zap.String("to", to), zap.String("subject", subject))
-	renderedContent, err := s.parseTemplate(emailTemplate, variables)
+
+	messageID, message, err := s.compose(to, subject, htmlBody, textBody, variables, attachments)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Transport.Send(addressOnly(s.Transport.Username), []string{addressOnly(to)}, []byte(message))
 	if err != nil {
-		return fmt.Errorf("error parsing template: %w", err)
+		return "", fmt.Errorf("error sending email: %w", err)
 	}
 
-	// Build the email message
-	message := s.buildMessage(s.Username, to, subject, renderedContent)
+	return messageID, nil
+}
 
-	// SMTP address and authentication
-	smtpAddr := fmt.Sprintf("%s:%s", s.Host, s.Port)
-	s.logger.Info("Sending email", zap.String("SmtpAddr", smtpAddr))
-	auth := NewEmailAuth("", s.Username, s.Password, s.Host)
+// Close closes the SMTP connections kept open between the emails.
+func (s *SMTPService) Close() error {
+	return s.Transport.Close()
+}
 
-	// Send the email
-	err = smtp.SendMail(smtpAddr, auth, s.Username, []string{to}, []byte(message))
+// Render returns the HTML and the text bodies of the template.
+func (s *SMTPService) Render(ctx context.Context, templateName string, variables map[string]interface{}) (string, string, error) {
+	emailTemplate, err := s.loadTemplate(ctx, templateName, ".html")
 	if err != nil {
-		return fmt.Errorf("error sending email: %w", err)
+		if s.logger != nil {
+			s.logger.Error("template not found", zap.String("template", templateName), zap.Error(err))
+		}
+		return "", "", fmt.Errorf("error loading template %s: %w", templateName, err)
 	}
 
-	fmt.Printf("Email sent to %s with subject: %s\n", to, subject)
-	return nil
+	htmlBody, err := s.parseTemplate(emailTemplate, variables)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing template: %w", err)
+	}
+
+	textBody, err := s.renderText(ctx, templateName, htmlBody, variables)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing text template: %w", err)
+	}
+
+	return htmlBody, textBody, nil
+}
+
+// compose returns the Message-ID and the whole message with the rendered bodies.
+func (s *SMTPService) compose(to, subject, htmlBody, textBody string, variables map[string]interface{}, attachments []Attachment) (string, string, error) {
+	unsubscribeURL, _ := variables[UnsubscribeURLVariable].(string)
+	message, err := s.buildMessage(s.Transport.Username, to, subject, unsubscribeURL, htmlBody, textBody, attachments...)
+	if err != nil {
+		return "", "", fmt.Errorf("error building message: %w", err)
+	}
+
+	messageID, err := newMessageID(s.Transport.Username)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating message ID: %w", err)
+	}
+
+	return messageID, fmt.Sprintf("Message-ID: %s\r\n%s", messageID, message), nil
+}
+
+// templateFuncs t translates the template strings to the Locale of the variables.
+func templateFuncs(variables map[string]interface{}) map[string]interface{} {
+	locale, _ := variables["Locale"].(string)
+	return map[string]interface{}{
+		"t": func(key string, args ...interface{}) string {
+			return i18n.T(locale, key, args...)
+		},
+	}
+}
+
+// loadTemplate returns the pinned version of the template, or the unversioned one when it does not exist.
+func (s *SMTPService) loadTemplate(ctx context.Context, templateName, extension string) (string, error) {
+	if version, ok := s.Versions[templateName]; ok {
+		source, err := s.Templates.Get(ctx, templateName+"@"+version+extension)
+		if !errors.Is(err, ErrTemplateNotFound) {
+			return source, err
+		}
+	}
+
+	return s.Templates.Get(ctx, templateName+extension)
 }
 
 // parseTemplate replaces variables in the template string
 func (s *SMTPService) parseTemplate(templateString string, variables map[string]interface{}) (string, error) {
-	tmpl, err := template.New("email").Parse(templateString)
+	tmpl, err := template.New("email").Funcs(templateFuncs(variables)).Parse(templateString)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, variables); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// renderText renders the text variant of the template, or derives it from the HTML when there is none.
+func (s *SMTPService) renderText(ctx context.Context, templateName, renderedHTML string, variables map[string]interface{}) (string, error) {
+	textTemplate, err := s.loadTemplate(ctx, templateName, ".txt")
+	if errors.Is(err, ErrTemplateNotFound) {
+		return htmlToText(renderedHTML), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := texttemplate.New("email").Funcs(templateFuncs(variables)).Parse(textTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -87,8 +220,155 @@ func (s *SMTPService) parseTemplate(templateString string, variables map[string]
 	return builder.String(), nil
 }
 
-// buildMessage constructs the email message body
-func (s *SMTPService) buildMessage(from, to, subject, body string) string {
-	return fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=\"UTF-8\"\r\n\r\n%s",
-		from, to, subject, body)
+// buildMessage constructs the email message, the bodies are sent as multipart/alternative,
+// wrapped in multipart/related with the inline attachments and in multipart/mixed with the rest.
+func (s *SMTPService) buildMessage(from, to, subject, unsubscribeURL, htmlBody, textBody string, attachments ...Attachment) (string, error) {
+	message, err := buildAlternative(htmlBody, textBody)
+	if err != nil {
+		return "", err
+	}
+
+	var inline, attached []Attachment
+	for _, attachment := range attachments {
+		if attachment.ContentID != "" {
+			inline = append(inline, attachment)
+			continue
+		}
+		attached = append(attached, attachment)
+	}
+
+	if len(inline) > 0 {
+		if message, err = buildRelated(message, inline); err != nil {
+			return "", err
+		}
+	}
+
+	if len(attached) > 0 {
+		if message, err = buildMixed(message, attached); err != nil {
+			return "", err
+		}
+	}
+
+	var headers string
+	if unsubscribeURL != "" {
+		headers = fmt.Sprintf("List-Unsubscribe: <%s>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n", unsubscribeURL)
+	}
+
+	return fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n%sMIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n%s",
+		formatAddress(from), formatAddress(to), encodeHeader(subject), headers, message.contentType, message.body), nil
+}
+
+type mimeBody struct {
+	contentType string
+	body        []byte
+}
+
+func buildAlternative(htmlBody, textBody string) (*mimeBody, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	// the preferred version goes last
+	if err := writeQuotedPrintablePart(writer, `text/plain; charset="UTF-8"`, textBody); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(writer, `text/html; charset="UTF-8"`, htmlBody); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &mimeBody{
+		contentType: mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": writer.Boundary()}),
+		body:        body.Bytes(),
+	}, nil
+}
+
+// buildRelated the inline attachments follow the bodies that reference them, as required by RFC 2387.
+func buildRelated(content *mimeBody, attachments []Attachment) (*mimeBody, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writeContentPart(writer, content); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + attachment.ContentID + ">"},
+			"Content-Disposition":       {mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &mimeBody{
+		contentType: mime.FormatMediaType("multipart/related", map[string]string{"boundary": writer.Boundary(), "type": "multipart/alternative"}),
+		body:        body.Bytes(),
+	}, nil
+}
+
+func buildMixed(content *mimeBody, attachments []Attachment) (*mimeBody, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writeContentPart(writer, content); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &mimeBody{
+		contentType: mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": writer.Boundary()}),
+		body:        body.Bytes(),
+	}, nil
+}
+
+// writeContentPart nests the multipart content in the writer.
+func writeContentPart(writer *multipart.Writer, content *mimeBody) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {content.contentType}})
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content.body)
+	return err
+}
+
+// writeBase64 writes the content in lines of 76 characters as required by RFC 2045.
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
 }
@@ -1,15 +1,23 @@
 package notifications
 
 import (
+	"context"
 	_ "embed"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSMTPService_Parse(t *testing.T) {
-	s := NewSMTPService(&SMTPConfig{}, nil)
-	template := s.templates["summary"]
+	s := newTestSMTPService(t, &SMTPConfig{})
+	template, err := s.loadTemplate(context.Background(), "summary", ".html")
+	assert.NoError(t, err)
 	variables := map[string]interface{}{
 		"TotalBalance":    1000,
 		"AvrDebitAmount":  100,
@@ -19,6 +27,13 @@ func TestSMTPService_Parse(t *testing.T) {
 			{"Month": "Feb", "Count": 100},
 			{"Month": "Mar", "Count": -100},
 		},
+		"Sparkline": "▁▄█",
+		"DailyBalances": []map[string]interface{}{
+			{"Day": "2024-03-14", "Credits": "5.00", "Debits": "-2.00", "Balance": "13.00"},
+		},
+		"Categories": []map[string]interface{}{
+			{"Category": "groceries", "Amount": "12.50", "Percentage": "75.0%"},
+		},
 	}
 
 	rendered, err := s.parseTemplate(template, variables)
@@ -31,5 +46,258 @@ func TestSMTPService_Parse(t *testing.T) {
 	assert.Contains(t, rendered, "Jan")
 	assert.Contains(t, rendered, "Feb")
 	assert.Contains(t, rendered, "Mar")
+	assert.Contains(t, rendered, "groceries")
+	assert.Contains(t, rendered, "75.0%")
+	assert.Contains(t, rendered, "▁▄█")
+	assert.Contains(t, rendered, "2024-03-14")
+	assert.NotContains(t, rendered, "cid:")
+
+	variables["Inline"] = map[string]string{
+		"monthly-balance.png": "monthly-balance.png@storid",
+		"credits-debits.png":  "credits-debits.png@storid",
+	}
+	rendered, err = s.parseTemplate(template, variables)
+	assert.NoError(t, err)
+	assert.Contains(t, rendered, `src="cid:monthly-balance.png@storid"`)
+	assert.Contains(t, rendered, `src="cid:credits-debits.png@storid"`)
+	assert.Contains(t, rendered, "<td>Jan</td><td>Feb</td><td>Mar</td>")
+}
+
+func TestSMTPService_ParseStatement(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+	variables := map[string]interface{}{
+		"Statement": map[string]interface{}{
+			"Sequence":       3,
+			"PeriodStart":    "2024-02-15",
+			"PeriodEnd":      "2024-03-14",
+			"OpeningBalance": "100.00",
+			"ClosingBalance": "120.00",
+			"TotalCredits":   "50.00",
+			"TotalDebits":    "-30.00",
+			"CreditCount":    1,
+			"DebitCount":     2,
+			"Transactions": []map[string]interface{}{
+				{"Date": "2024-02-16", "Type": "credit", "Category": "salary", "Amount": "50.00"},
+			},
+		},
+	}
+
+	template, err := s.loadTemplate(context.Background(), "statement", ".html")
+	assert.NoError(t, err)
+
+	rendered, err := s.parseTemplate(template, variables)
+	assert.NoError(t, err)
+
+	assert.Contains(t, rendered, "Statement #3 (2024-02-15 - 2024-03-14)")
+	assert.Contains(t, rendered, "120.00")
+	assert.Contains(t, rendered, "salary")
+}
+
+func TestSMTPService_BuildMessage(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+
+	message, err := s.buildMessage("from@storid.com", "José Pérez <to@storid.com>", "Resumen de cuenta ñandú", "", "<p>Hola José, tu saldo es de 1.000 €</p>", "Hola José")
+	assert.NoError(t, err)
+
+	assert.Contains(t, message, "To: =?utf-8?q?Jos=C3=A9_P=C3=A9rez?= <to@storid.com>\r\n")
+	assert.Contains(t, message, "Subject: =?UTF-8?q?Resumen_de_cuenta_=C3=B1and=C3=BA?=\r\n")
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Resumen de cuenta ñandú", subject)
 
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	// multipart.Reader decodes the quoted-printable parts
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	text, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, `text/plain; charset="UTF-8"`, text.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(text)
+	assert.Equal(t, "Hola José", string(body))
+
+	html, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, `text/html; charset="UTF-8"`, html.Header.Get("Content-Type"))
+	body, _ = io.ReadAll(html)
+	assert.Equal(t, "<p>Hola José, tu saldo es de 1.000 €</p>", string(body))
+
+	assert.NotContains(t, message, "José, tu saldo")
+	assert.Contains(t, message, "Jos=C3=A9, tu saldo")
+}
+
+func TestSMTPService_BuildMessageWithUnsubscribe(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+
+	message, err := s.buildMessage("from@storid.com", "to@storid.com", "Summary Balance", "https://api.storid.com/prod/unsubscribe?token=abc", "<p>hello</p>", "hello")
+	assert.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	assert.NoError(t, err)
+	assert.Equal(t, "<https://api.storid.com/prod/unsubscribe?token=abc>", parsed.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", parsed.Header.Get("List-Unsubscribe-Post"))
+
+	message, err = s.buildMessage("from@storid.com", "to@storid.com", "Summary Balance", "", "<p>hello</p>", "hello")
+	assert.NoError(t, err)
+	assert.NotContains(t, message, "List-Unsubscribe")
+}
+
+func TestSMTPService_BuildMessageWithAttachments(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+	content := []byte(strings.Repeat("%PDF-1.4 statement ", 10))
+
+	message, err := s.buildMessage("from@storid.com", "to@storid.com", "Summary Balance", "", "<p>hello</p>", "hello", Attachment{
+		Filename:    "statement.pdf",
+		ContentType: "application/pdf",
+		Content:     content,
+	})
+	assert.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	assert.NoError(t, err)
+	assert.Equal(t, "Summary Balance", parsed.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	alternative, err := reader.NextPart()
+	assert.NoError(t, err)
+	mediaType, _, err = mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	attachment, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "statement.pdf", attachment.FileName())
+	encoded, _ := io.ReadAll(attachment)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, content, decoded)
+
+	_, err = reader.NextPart()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSMTPService_BuildMessageWithInlineAttachments(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+	chart := []byte("\x89PNG chart")
+
+	message, err := s.buildMessage("from@storid.com", "to@storid.com", "Summary Balance", "", `<img src="cid:chart@storid">`, "hello",
+		Attachment{Filename: "chart.png", ContentType: "image/png", Content: chart, ContentID: "chart@storid"},
+		Attachment{Filename: "statement.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")},
+	)
+	assert.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	assert.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+	mixed := multipart.NewReader(parsed.Body, params["boundary"])
+
+	// the bodies and the images they reference are related, the rest of the attachments are mixed
+	related, err := mixed.NextPart()
+	assert.NoError(t, err)
+	mediaType, params, err = mime.ParseMediaType(related.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/related", mediaType)
+	assert.Equal(t, "multipart/alternative", params["type"])
+	reader := multipart.NewReader(related, params["boundary"])
+
+	alternative, err := reader.NextPart()
+	assert.NoError(t, err)
+	mediaType, _, err = mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	image, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", image.Header.Get("Content-Type"))
+	assert.Equal(t, "<chart@storid>", image.Header.Get("Content-ID"))
+	assert.True(t, strings.HasPrefix(image.Header.Get("Content-Disposition"), "inline"))
+	encoded, _ := io.ReadAll(image)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, chart, decoded)
+
+	_, err = reader.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	attachment, err := mixed.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "statement.pdf", attachment.FileName())
+
+	_, err = mixed.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// without other attachments the related part is the message
+	message, err = s.buildMessage("from@storid.com", "to@storid.com", "Summary Balance", "", `<img src="cid:chart@storid">`, "hello",
+		Attachment{Filename: "chart.png", ContentType: "image/png", Content: chart, ContentID: "chart@storid"},
+	)
+	assert.NoError(t, err)
+	parsed, err = mail.ReadMessage(strings.NewReader(message))
+	assert.NoError(t, err)
+	mediaType, _, err = mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/related", mediaType)
+}
+
+func TestSMTPService_RenderText(t *testing.T) {
+	s := newTestSMTPService(t, &SMTPConfig{})
+	variables := map[string]interface{}{
+		"TotalBalance":    "10.00",
+		"AvrDebitAmount":  "-1.00",
+		"AvrCreditAmount": "2.00",
+		"MonthlyBalance": []map[string]interface{}{
+			{"Month": "Jan", "Count": 3},
+		},
+	}
+
+	text, err := s.renderText(context.Background(), "summary", "", variables)
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Total Balance: 10.00")
+	assert.Contains(t, text, "  Jan: 3")
+	assert.NotContains(t, text, "<")
+
+	// the alert template has no text variant
+	html := `<html><head><style>body {}</style></head><body><table><caption>Unusual Activity</caption>` +
+		`<tr><th>Date</th><th>Amount</th></tr><tr><td>2024-03-01</td><td>-10.00 &amp; more</td></tr></table>` +
+		`<p>See <a href="https://storid.com">details</a></p></body></html>`
+	text, err = s.renderText(context.Background(), "alert", html, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unusual Activity\nDate Amount\n2024-03-01 -10.00 & more\n\nSee details (https://storid.com)\n", text)
+}
+
+func TestNewMessageID(t *testing.T) {
+	first, err := newMessageID("Storid <no-reply@storid.com>")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^<[0-9a-f]{32}@storid\.com>$`, first)
+
+	second, err := newMessageID("no-reply@storid.com")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	invalid, err := newMessageID("")
+	assert.NoError(t, err)
+	assert.Regexp(t, `@localhost>$`, invalid)
+}
+
+func newTestSMTPService(t *testing.T, config *SMTPConfig, options ...SMTPOption) *SMTPService {
+	t.Helper()
+	s, err := NewSMTPService(config, nil, options...)
+	assert.NoError(t, err)
+	return s
 }
@@ -0,0 +1,80 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileEmailService writes the emails to Dir instead of sending them, every email gets a directory with
+// the message as message.eml, its bodies as index.html and body.txt and its attachments. The inline
+// attachments are referenced by their filename in index.html so it can be opened in a browser.
+type FileEmailService struct {
+	SMTPService *SMTPService
+	Dir         string
+
+	mu    sync.Mutex
+	count int
+}
+
+func NewFileEmailService(service *SMTPService, dir string) *FileEmailService {
+	return &FileEmailService{
+		SMTPService: service,
+		Dir:         dir,
+	}
+}
+
+// Send returns the Message-ID of the email written.
+func (f *FileEmailService) Send(to, subject, templateName string, variables map[string]interface{}, attachments ...Attachment) (string, error) {
+	htmlBody, textBody, err := f.SMTPService.Render(context.Background(), templateName, variables)
+	if err != nil {
+		return "", err
+	}
+
+	messageID, message, err := f.SMTPService.compose(to, subject, htmlBody, textBody, variables, attachments)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(f.Dir, fmt.Sprintf("%04d-%s-%s", f.next(), templateName, fileSafe(addressOnly(to))))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating email directory: %w", err)
+	}
+
+	files := map[string][]byte{
+		"message.eml": []byte(message),
+		"body.txt":    []byte(textBody),
+	}
+	for _, attachment := range attachments {
+		if attachment.ContentID != "" {
+			htmlBody = strings.ReplaceAll(htmlBody, "cid:"+attachment.ContentID, fileSafe(attachment.Filename))
+		}
+		if attachment.URL == "" {
+			files[fileSafe(attachment.Filename)] = attachment.Content
+		}
+	}
+	files["index.html"] = []byte(htmlBody)
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			return "", fmt.Errorf("error writing %s: %w", name, err)
+		}
+	}
+
+	return messageID, nil
+}
+
+func (f *FileEmailService) next() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count++
+	return f.count
+}
+
+// fileSafe keeps the name in the directory it is written to.
+func fileSafe(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(name)
+}
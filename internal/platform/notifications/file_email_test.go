@@ -0,0 +1,62 @@
+package notifications
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileEmailService_Send(t *testing.T) {
+	dir := t.TempDir()
+	service := NewFileEmailService(newTestSMTPService(t, &SMTPConfig{Username: "no-reply@storid.com"}), dir)
+
+	variables := map[string]interface{}{
+		"TotalBalance": "$10.00",
+		"Inline":       map[string]string{"monthly-balance.png": "monthly-balance.png@storid"},
+		"MonthlyBalance": []map[string]interface{}{
+			{"Month": "January", "Count": 3},
+		},
+	}
+
+	messageID, err := service.Send("Zoë Müller <test@storid.com>", "Summary Balance", "summary", variables,
+		Attachment{Filename: "monthly-balance.png", ContentType: "image/png", Content: []byte("png"), ContentID: "monthly-balance.png@storid"},
+		Attachment{Filename: "transactions.csv", ContentType: "text/csv", URL: "https://exports.storid.com/transactions.csv"},
+	)
+	assert.NoError(t, err)
+	assert.Regexp(t, `@storid\.com>$`, messageID)
+
+	emailDir := filepath.Join(dir, "0001-summary-test@storid.com")
+
+	eml, err := os.ReadFile(filepath.Join(emailDir, "message.eml"))
+	assert.NoError(t, err)
+	parsed, err := mail.ReadMessage(strings.NewReader(string(eml)))
+	assert.NoError(t, err)
+	assert.Equal(t, messageID, parsed.Header.Get("Message-ID"))
+
+	// the html references the inline files written next to it
+	html, err := os.ReadFile(filepath.Join(emailDir, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), `src="monthly-balance.png"`)
+	assert.NotContains(t, string(html), "cid:")
+
+	png, err := os.ReadFile(filepath.Join(emailDir, "monthly-balance.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(png))
+
+	text, err := os.ReadFile(filepath.Join(emailDir, "body.txt"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(text), "$10.00")
+
+	// the linked files are not written
+	_, err = os.Stat(filepath.Join(emailDir, "transactions.csv"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = service.Send("test@storid.com", "Summary Balance", "summary", variables)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "0002-summary-test@storid.com", "index.html"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+var (
+	hiddenElements = regexp.MustCompile(`(?is)<(head|style|script)[^>]*>.*?</(head|style|script)>`)
+	lineBreaks     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|h[1-6]|table|caption|ul)>`)
+	cellBreaks     = regexp.MustCompile(`(?i)</t[dh]>`)
+	links          = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	tags           = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaces         = regexp.MustCompile(`[ \t]+`)
+	blankLines     = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// htmlToText derives a plain text body from the HTML one, used for the templates without a text variant.
+func htmlToText(body string) string {
+	text := hiddenElements.ReplaceAllString(body, "")
+	text = links.ReplaceAllString(text, "$2 ($1)")
+	text = lineBreaks.ReplaceAllString(text, "\n")
+	text = cellBreaks.ReplaceAllString(text, " ")
+	text = tags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spaces.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n")) + "\n"
+}
+
+// formatAddress encodes the display name as RFC 2047 when it is not ASCII,
+// invalid addresses are returned as they are.
+func formatAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+	return parsed.String()
+}
+
+// addressOnly returns the address without the display name, as required by the SMTP envelope.
+func addressOnly(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+	return parsed.Address
+}
+
+// newMessageID returns a random Message-ID in the domain of the sender.
+func newMessageID(from string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if _, host, found := strings.Cut(addressOnly(from), "@"); found && host != "" {
+		domain = host
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain), nil
+}
+
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("UTF-8", value)
+}
+
+// writeQuotedPrintablePart adds a part encoded as quoted-printable to the multipart writer.
+func writeQuotedPrintablePart(writer *multipart.Writer, contentType, body string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeQuotedPrintable(part, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	encoder := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(encoder, body); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
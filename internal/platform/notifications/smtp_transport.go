@@ -0,0 +1,366 @@
+package notifications
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS modes of the SMTP connection, none is only meant for a local server because the credentials
+// are sent in clear text.
+const (
+	TLSModeNone     = "none"
+	TLSModeStartTLS = "starttls"
+	TLSModeImplicit = "implicit"
+)
+
+// SMTP authentication mechanisms.
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+)
+
+const (
+	DefaultSMTPDialTimeout = 10 * time.Second
+	DefaultSMTPTimeout     = 30 * time.Second
+	DefaultSMTPPoolSize    = 10
+	DefaultSMTPIdleTimeout = time.Minute
+)
+
+// SMTPTransport sends the messages over pooled connections. A connection is reset with RSET before it
+// is reused and closed when a message fails or when it stayed idle longer than IdleTimeout, the
+// servers close the idle connections on their side.
+type SMTPTransport struct {
+	Host          string
+	Port          string
+	Username      string
+	Password      string
+	HeloHostname  string
+	TLSMode       string
+	TLSConfig     *tls.Config
+	AuthMechanism string
+	DialTimeout   time.Duration
+	// Timeout of every command and of the whole DATA of a message
+	Timeout time.Duration
+	// PoolSize amount of idle connections kept open
+	PoolSize    int
+	IdleTimeout time.Duration
+
+	mu   sync.Mutex
+	idle []*smtpConn
+	now  func() time.Time
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// NewSMTPTransport the TLS mode and the auth mechanism are case-insensitive, an unknown one is an error
+// so a typo fails at startup instead of at the first email.
+func NewSMTPTransport(config *SMTPConfig) (*SMTPTransport, error) {
+	t := &SMTPTransport{
+		Host:          config.Host,
+		Port:          config.Port,
+		Username:      config.Username,
+		Password:      config.Password,
+		HeloHostname:  config.HeloHostname,
+		TLSMode:       strings.ToLower(config.TLSMode),
+		AuthMechanism: strings.ToLower(config.AuthMechanism),
+		DialTimeout:   config.DialTimeout,
+		Timeout:       config.Timeout,
+		PoolSize:      config.PoolSize,
+		IdleTimeout:   config.IdleTimeout,
+		TLSConfig: &tls.Config{
+			ServerName:         config.Host,
+			InsecureSkipVerify: config.TLSSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		},
+		now: time.Now,
+	}
+
+	if t.TLSMode == "" {
+		t.TLSMode = TLSModeStartTLS
+	}
+	if t.AuthMechanism == "" {
+		t.AuthMechanism = AuthPlain
+	}
+
+	switch t.TLSMode {
+	case TLSModeNone, TLSModeStartTLS, TLSModeImplicit:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", config.TLSMode)
+	}
+
+	switch t.AuthMechanism {
+	case AuthPlain, AuthLogin, AuthCRAMMD5:
+	default:
+		return nil, fmt.Errorf("unknown smtp auth mechanism %q", config.AuthMechanism)
+	}
+	if t.DialTimeout <= 0 {
+		t.DialTimeout = DefaultSMTPDialTimeout
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultSMTPTimeout
+	}
+	if t.PoolSize < 0 {
+		t.PoolSize = 0
+	}
+	if t.IdleTimeout <= 0 {
+		t.IdleTimeout = DefaultSMTPIdleTimeout
+	}
+
+	return t, nil
+}
+
+// Send the errors keep the *textproto.Error of the SMTP replies and the net.Error of the connection
+// so the callers can tell the transient failures apart.
+func (t *SMTPTransport) Send(from string, to []string, message []byte) error {
+	c, err := t.get()
+	if err != nil {
+		return err
+	}
+
+	if err := t.send(c, from, to, message); err != nil {
+		c.close()
+		return err
+	}
+
+	t.put(c)
+	return nil
+}
+
+// Close closes the idle connections, the connections in use are closed when their message is sent.
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.mu.Unlock()
+
+	for _, c := range idle {
+		c.quit(t.now().Add(t.Timeout))
+	}
+	return nil
+}
+
+func (t *SMTPTransport) send(c *smtpConn, from string, to []string, message []byte) error {
+	if err := c.conn.SetDeadline(t.now().Add(t.Timeout)); err != nil {
+		return fmt.Errorf("error setting smtp deadline: %w", err)
+	}
+
+	if err := c.client.Mail(from); err != nil {
+		return fmt.Errorf("error sending MAIL FROM: %w", err)
+	}
+	for _, recipient := range to {
+		if err := c.client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("error sending RCPT TO %s: %w", recipient, err)
+		}
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending DATA: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error ending DATA: %w", err)
+	}
+
+	return nil
+}
+
+// get returns an idle connection that answers the RSET or a new one.
+func (t *SMTPTransport) get() (*smtpConn, error) {
+	for {
+		c := t.pop()
+		if c == nil {
+			return t.dial()
+		}
+
+		if err := c.conn.SetDeadline(t.now().Add(t.Timeout)); err == nil {
+			if err := c.client.Reset(); err == nil {
+				return c, nil
+			}
+		}
+		c.close()
+	}
+}
+
+// pop the expired connections are closed on the way.
+func (t *SMTPTransport) pop() *smtpConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.idle) > 0 {
+		c := t.idle[len(t.idle)-1]
+		t.idle = t.idle[:len(t.idle)-1]
+		if t.now().Sub(c.lastUsed) < t.IdleTimeout {
+			return c
+		}
+		c.close()
+	}
+	return nil
+}
+
+func (t *SMTPTransport) put(c *smtpConn) {
+	c.lastUsed = t.now()
+
+	t.mu.Lock()
+	if len(t.idle) < t.PoolSize {
+		t.idle = append(t.idle, c)
+		c = nil
+	}
+	t.mu.Unlock()
+
+	if c != nil {
+		c.quit(t.now().Add(t.Timeout))
+	}
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(t.Host, t.Port)
+	dialer := &net.Dialer{Timeout: t.DialTimeout}
+
+	var conn net.Conn
+	var err error
+	switch t.TLSMode {
+	case TLSModeImplicit:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, t.TLSConfig)
+	case TLSModeStartTLS, TLSModeNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", t.TLSMode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+
+	c := &smtpConn{conn: conn}
+	if err := t.handshake(c); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// handshake greets the server, upgrades the connection with STARTTLS and authenticates.
+func (t *SMTPTransport) handshake(c *smtpConn) error {
+	if err := c.conn.SetDeadline(t.now().Add(t.Timeout)); err != nil {
+		return fmt.Errorf("error setting smtp deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(c.conn, t.Host)
+	if err != nil {
+		return fmt.Errorf("error reading smtp greeting: %w", err)
+	}
+	c.client = client
+
+	if t.HeloHostname != "" {
+		if err := client.Hello(t.HeloHostname); err != nil {
+			return fmt.Errorf("error sending EHLO: %w", err)
+		}
+	}
+
+	if t.TLSMode == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", t.Host)
+		}
+		if err := client.StartTLS(t.TLSConfig); err != nil {
+			return fmt.Errorf("error starting TLS: %w", err)
+		}
+	}
+
+	if t.Username == "" {
+		return nil
+	}
+
+	auth, err := t.auth()
+	if err != nil {
+		return err
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return fmt.Errorf("smtp server %s does not support AUTH", t.Host)
+	}
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+
+	return nil
+}
+
+func (t *SMTPTransport) auth() (smtp.Auth, error) {
+	switch t.AuthMechanism {
+	case AuthPlain:
+		return &plainAuth{username: t.Username, password: t.Password}, nil
+	case AuthLogin:
+		return &loginAuth{username: t.Username, password: t.Password}, nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(t.Username, t.Password), nil
+	default:
+		return nil, fmt.Errorf("unknown smtp auth mechanism %q", t.AuthMechanism)
+	}
+}
+
+func (c *smtpConn) quit(deadline time.Time) {
+	_ = c.conn.SetDeadline(deadline)
+	if err := c.client.Quit(); err != nil {
+		c.close()
+	}
+}
+
+func (c *smtpConn) close() {
+	_ = c.conn.Close()
+}
+
+// plainAuth unlike smtp.PlainAuth it does not check the connection is encrypted, the transport
+// already requires TLS unless TLSMode is none.
+type plainAuth struct {
+	username string
+	password string
+}
+
+func (a *plainAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a *plainAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, errors.New("unexpected server challenge")
+	}
+	return nil, nil
+}
+
+// loginAuth the LOGIN mechanism of the servers that do not support PLAIN e.g. Office 365.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
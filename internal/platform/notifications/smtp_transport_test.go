@@ -0,0 +1,321 @@
+package notifications
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts the user "user" with the password "pass", it advertises STARTTLS when it has
+// a certificate and speaks TLS from the start when implicit is set.
+type fakeSMTPServer struct {
+	listener    net.Listener
+	certificate *tls.Certificate
+	silent      bool
+
+	mu          sync.Mutex
+	connections int
+	commands    []string
+	messages    []string
+	// handlers the connections being served
+	handlers sync.WaitGroup
+}
+
+func newFakeSMTPServer(t *testing.T, certificate *tls.Certificate, implicit bool) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	if implicit {
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{*certificate}})
+	}
+
+	s := &fakeSMTPServer{listener: listener, certificate: certificate}
+	if implicit {
+		s.certificate = nil
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) config() *SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &SMTPConfig{Host: host, Port: port, Username: "user", Password: "pass", TLSMode: TLSModeNone, PoolSize: 1}
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+		s.handlers.Add(1)
+		go func() {
+			defer s.handlers.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// waitClosed blocks until the server closed every connection it accepted.
+func (s *fakeSMTPServer) waitClosed() {
+	s.handlers.Wait()
+}
+
+func (s *fakeSMTPServer) record(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, command)
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	silent := s.silent
+	s.mu.Unlock()
+	if silent {
+		time.Sleep(time.Second)
+		return
+	}
+
+	text := textproto.NewConn(conn)
+	reply := func(lines ...string) {
+		for _, line := range lines {
+			_ = text.PrintfLine("%s", line)
+		}
+	}
+	reply("220 fake ESMTP")
+
+	secure := s.certificate == nil
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		s.record(verb)
+
+		switch verb {
+		case "EHLO":
+			if !secure {
+				reply("250-fake", "250-STARTTLS", "250 AUTH PLAIN LOGIN")
+				continue
+			}
+			reply("250-fake", "250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.certificate}})
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, text, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			var username, password string
+			switch mechanism {
+			case "PLAIN":
+				decoded, _ := base64.StdEncoding.DecodeString(initial)
+				parts := strings.Split(string(decoded), "\x00")
+				if len(parts) == 3 {
+					username, password = parts[1], parts[2]
+				}
+			case "LOGIN":
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				line, _ := text.ReadLine()
+				decoded, _ := base64.StdEncoding.DecodeString(line)
+				username = string(decoded)
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				line, _ = text.ReadLine()
+				decoded, _ = base64.StdEncoding.DecodeString(line)
+				password = string(decoded)
+			}
+			if username != "user" || password != "pass" {
+				reply("535 authentication failed")
+				continue
+			}
+			reply("235 authenticated")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			if verb == "RCPT" && strings.Contains(arg, "busy@") {
+				reply("451 try again later")
+				continue
+			}
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			message, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(message))
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) stats() (int, []string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, append([]string(nil), s.commands...), append([]string(nil), s.messages...)
+}
+
+func selfSignedCertificate(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestSMTPTransport_ReusesConnection(t *testing.T) {
+	server := newFakeSMTPServer(t, nil, false)
+	transport := newTestSMTPTransport(t, server.config())
+
+	assert.NoError(t, transport.Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n")))
+	assert.NoError(t, transport.Send("from@storid.com", []string{"b@storid.com"}, []byte("Subject: 2\r\n\r\ntwo\r\n")))
+
+	connections, commands, messages := server.stats()
+	assert.Equal(t, 1, connections)
+	assert.Equal(t, []string{"EHLO", "AUTH", "MAIL", "RCPT", "DATA", "RSET", "MAIL", "RCPT", "DATA"}, commands)
+	assert.Len(t, messages, 2)
+
+	assert.NoError(t, transport.Close())
+	server.waitClosed()
+	_, commands, _ = server.stats()
+	assert.Equal(t, "QUIT", commands[len(commands)-1])
+}
+
+func TestSMTPTransport_ClosesFailedAndExpiredConnections(t *testing.T) {
+	server := newFakeSMTPServer(t, nil, false)
+	transport := newTestSMTPTransport(t, server.config())
+
+	// the 4xx reply is kept so the sender retries it, the connection is not reused
+	err := transport.Send("from@storid.com", []string{"busy@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n"))
+	var reply *textproto.Error
+	assert.ErrorAs(t, err, &reply)
+	assert.Equal(t, 451, reply.Code)
+
+	assert.NoError(t, transport.Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 2\r\n\r\ntwo\r\n")))
+
+	now := time.Now()
+	transport.now = func() time.Time { return now.Add(2 * DefaultSMTPIdleTimeout) }
+	assert.NoError(t, transport.Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 3\r\n\r\nthree\r\n")))
+
+	connections, _, messages := server.stats()
+	assert.Equal(t, 3, connections)
+	assert.Len(t, messages, 2)
+}
+
+func TestSMTPTransport_Auth(t *testing.T) {
+	server := newFakeSMTPServer(t, nil, false)
+
+	config := server.config()
+	config.AuthMechanism = AuthLogin
+	assert.NoError(t, newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n")))
+
+	config.Password = "wrong"
+	err := newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n"))
+	assert.ErrorContains(t, err, "535")
+
+	config.AuthMechanism = "ntlm"
+	_, err = NewSMTPTransport(config)
+	assert.ErrorContains(t, err, "unknown smtp auth mechanism")
+}
+
+func TestNewSMTPTransport_Modes(t *testing.T) {
+	transport, err := NewSMTPTransport(&SMTPConfig{TLSMode: "STARTTLS", AuthMechanism: "CRAM-MD5"})
+	assert.NoError(t, err)
+	assert.Equal(t, TLSModeStartTLS, transport.TLSMode)
+	assert.Equal(t, AuthCRAMMD5, transport.AuthMechanism)
+
+	transport, err = NewSMTPTransport(&SMTPConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, TLSModeStartTLS, transport.TLSMode)
+	assert.Equal(t, AuthPlain, transport.AuthMechanism)
+
+	_, err = NewSMTPTransport(&SMTPConfig{TLSMode: "ssl"})
+	assert.ErrorContains(t, err, `unknown smtp tls mode "ssl"`)
+}
+
+func TestSMTPTransport_TLS(t *testing.T) {
+	certificate := selfSignedCertificate(t)
+
+	// with STARTTLS the email is never sent in clear text
+	config := newFakeSMTPServer(t, nil, false).config()
+	config.TLSMode = TLSModeStartTLS
+	assert.ErrorContains(t, newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, nil), "does not support STARTTLS")
+
+	server := newFakeSMTPServer(t, certificate, false)
+	config = server.config()
+	config.TLSMode = TLSModeStartTLS
+	err := newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n"))
+	assert.ErrorContains(t, err, "certificate")
+
+	config.TLSSkipVerify = true
+	assert.NoError(t, newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n")))
+	_, commands, _ := server.stats()
+	assert.Contains(t, commands, "STARTTLS")
+
+	server = newFakeSMTPServer(t, certificate, true)
+	config = server.config()
+	config.TLSMode = TLSModeImplicit
+	config.TLSSkipVerify = true
+	assert.NoError(t, newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, []byte("Subject: 1\r\n\r\none\r\n")))
+	_, _, messages := server.stats()
+	assert.Len(t, messages, 1)
+}
+
+func TestSMTPTransport_Timeout(t *testing.T) {
+	server := newFakeSMTPServer(t, nil, false)
+	server.mu.Lock()
+	server.silent = true
+	server.mu.Unlock()
+
+	config := server.config()
+	config.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := newTestSMTPTransport(t, config).Send("from@storid.com", []string{"a@storid.com"}, nil)
+	var netErr net.Error
+	assert.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+}
+
+func newTestSMTPTransport(t *testing.T, config *SMTPConfig) *SMTPTransport {
+	t.Helper()
+	transport, err := NewSMTPTransport(config)
+	assert.NoError(t, err)
+	return transport
+}
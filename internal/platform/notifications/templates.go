@@ -0,0 +1,311 @@
+package notifications
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.uber.org/zap"
+)
+
+const (
+	TemplateSourceEmbedded = "embedded"
+	TemplateSourceDir      = "dir"
+	TemplateSourceS3       = "s3"
+)
+
+// ErrTemplateNotFound is returned by the stores when the template does not exist.
+var ErrTemplateNotFound = errors.New("template not found")
+
+//go:embed template
+var embeddedTemplates embed.FS
+
+// RequiredVariables the variables every version of a template must use, a template
+// without them is rejected and the next store is used instead.
+var RequiredVariables = map[string][]string{
+	"summary":   {"TotalBalance", "AvrDebitAmount", "AvrCreditAmount", "MonthlyBalance"},
+	"alert":     {"Alerts"},
+	"statement": {"Statement"},
+}
+
+// TemplateConfig Source is embedded (default), dir or s3, Versions pins a template to a version,
+// e.g. {"summary": "v2"} loads summary@v2.html and falls back to summary.html when it does not exist.
+type TemplateConfig struct {
+	Source   string
+	Dir      string
+	Bucket   string
+	Prefix   string
+	CacheTTL time.Duration
+	Versions map[string]string
+}
+
+// TemplateStore returns the source of a template by file name, e.g. summary.html or summary@v2.txt.
+type TemplateStore interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// NewTemplateStore builds the store for the configured source, the embedded templates are always the fallback.
+func NewTemplateStore(config *TemplateConfig, client *s3.Client, logger *zap.Logger) TemplateStore {
+	embedded := NewEmbeddedTemplateStore()
+
+	var store TemplateStore
+	switch config.Source {
+	case TemplateSourceDir:
+		store = NewDirTemplateStore(config.Dir)
+	case TemplateSourceS3:
+		store = NewS3TemplateStore(client, config.Bucket, config.Prefix)
+	default:
+		return embedded
+	}
+
+	if config.CacheTTL > 0 {
+		store = NewCachedTemplateStore(store, config.CacheTTL)
+	}
+
+	return NewFallbackTemplateStore(logger, store, embedded)
+}
+
+type EmbeddedTemplateStore struct {
+	FS fs.FS
+}
+
+func NewEmbeddedTemplateStore() *EmbeddedTemplateStore {
+	return &EmbeddedTemplateStore{FS: embeddedTemplates}
+}
+
+func (s *EmbeddedTemplateStore) Get(_ context.Context, name string) (string, error) {
+	return readTemplate(s.FS, path.Join("template", name))
+}
+
+// DirTemplateStore reads the templates from a local directory, meant for development and previews.
+type DirTemplateStore struct {
+	Dir string
+}
+
+func NewDirTemplateStore(dir string) *DirTemplateStore {
+	return &DirTemplateStore{Dir: dir}
+}
+
+func (s *DirTemplateStore) Get(_ context.Context, name string) (string, error) {
+	return readTemplate(os.DirFS(filepath.Clean(s.Dir)), name)
+}
+
+func readTemplate(fsys fs.FS, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid template name %s", name)
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading template %s: %w", name, err)
+	}
+
+	return string(content), nil
+}
+
+type S3TemplateStore struct {
+	S3Client *s3.Client
+	Bucket   string
+	Prefix   string
+}
+
+func NewS3TemplateStore(client *s3.Client, bucket, prefix string) *S3TemplateStore {
+	return &S3TemplateStore{
+		S3Client: client,
+		Bucket:   bucket,
+		Prefix:   prefix,
+	}
+}
+
+func (s *S3TemplateStore) Get(ctx context.Context, name string) (string, error) {
+	output, err := s.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, name)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting template %s: %w", name, err)
+	}
+	defer output.Body.Close()
+
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading template %s: %w", name, err)
+	}
+
+	return string(content), nil
+}
+
+type cachedTemplate struct {
+	source    string
+	err       error
+	expiresAt time.Time
+}
+
+// CachedTemplateStore keeps the templates, and the missing ones, for TTL.
+type CachedTemplateStore struct {
+	Store TemplateStore
+	TTL   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedTemplate
+}
+
+func NewCachedTemplateStore(store TemplateStore, ttl time.Duration) *CachedTemplateStore {
+	return &CachedTemplateStore{
+		Store:   store,
+		TTL:     ttl,
+		now:     time.Now,
+		entries: map[string]cachedTemplate{},
+	}
+}
+
+func (s *CachedTemplateStore) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[name]
+	s.mu.Unlock()
+	if ok && s.now().Before(entry.expiresAt) {
+		return entry.source, entry.err
+	}
+
+	source, err := s.Store.Get(ctx, name)
+	if err != nil && !errors.Is(err, ErrTemplateNotFound) {
+		// other errors are not cached so the next call retries
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.entries[name] = cachedTemplate{source: source, err: err, expiresAt: s.now().Add(s.TTL)}
+	s.mu.Unlock()
+
+	return source, err
+}
+
+// FallbackTemplateStore returns the first valid template found in Stores,
+// a missing, unreachable or invalid template moves on to the next store.
+type FallbackTemplateStore struct {
+	Stores []TemplateStore
+	Log    *zap.Logger
+}
+
+func NewFallbackTemplateStore(logger *zap.Logger, stores ...TemplateStore) *FallbackTemplateStore {
+	return &FallbackTemplateStore{
+		Stores: stores,
+		Log:    logger,
+	}
+}
+
+func (s *FallbackTemplateStore) Get(ctx context.Context, name string) (string, error) {
+	for _, store := range s.Stores {
+		source, err := store.Get(ctx, name)
+		if errors.Is(err, ErrTemplateNotFound) {
+			continue
+		}
+		if err == nil {
+			err = ValidateTemplate(name, source)
+		}
+		if err != nil {
+			s.Log.Warn("template rejected, using the next store", zap.String("template", name), zap.Error(err))
+			continue
+		}
+		return source, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// TemplateName returns the template of a file name, e.g. summary for summary@v2.html.
+func TemplateName(fileName string) string {
+	name := strings.TrimSuffix(fileName, path.Ext(fileName))
+	name, _, _ = strings.Cut(name, "@")
+	return name
+}
+
+// ValidateTemplate checks the template parses, with the builtin functions and templateFuncs, and uses its RequiredVariables.
+func ValidateTemplate(fileName, source string) error {
+	tmpl, err := texttemplate.New(fileName).Funcs(templateFuncs(nil)).Parse(source)
+	if err != nil {
+		return fmt.Errorf("error parsing template %s: %w", fileName, err)
+	}
+
+	used := map[string]bool{}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectFields(t.Tree.Root, used)
+		}
+	}
+
+	var missing []string
+	for _, variable := range RequiredVariables[TemplateName(fileName)] {
+		if !used[variable] {
+			missing = append(missing, variable)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("template %s does not use the required variables %s", fileName, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func collectFields(node parse.Node, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, used)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, used)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, used)
+		}
+	case *parse.FieldNode:
+		used[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, used)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, used map[string]bool) {
+	collectFields(n.Pipe, used)
+	collectFields(n.List, used)
+	collectFields(n.ElseList, used)
+}
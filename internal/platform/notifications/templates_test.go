@@ -0,0 +1,164 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type MockTemplateStore struct {
+	templates map[string]string
+	err       error
+	calls     int
+}
+
+func (m *MockTemplateStore) Get(ctx context.Context, name string) (string, error) {
+	m.calls++
+	if m.err != nil {
+		return "", m.err
+	}
+	source, ok := m.templates[name]
+	if !ok {
+		return "", ErrTemplateNotFound
+	}
+	return source, nil
+}
+
+func TestEmbeddedTemplates_AreValid(t *testing.T) {
+	entries, err := fs.ReadDir(embeddedTemplates, "template")
+	assert.NoError(t, err)
+
+	store := NewEmbeddedTemplateStore()
+	for _, entry := range entries {
+		source, err := store.Get(context.Background(), entry.Name())
+		assert.NoError(t, err)
+		assert.NoError(t, ValidateTemplate(entry.Name(), source), entry.Name())
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	assert.NoError(t, ValidateTemplate("alert@v2.html", "{{range .Alerts}}{{.Detail}}{{end}}"))
+	assert.NoError(t, ValidateTemplate("unknown.html", "hello"))
+	assert.NoError(t, ValidateTemplate("unknown.html", `{{with index .Inline "chart.png"}}{{len .}}{{end}}`))
+	assert.ErrorContains(t, ValidateTemplate("alert.html", "{{.Detail}}"), "Alerts")
+	assert.ErrorContains(t, ValidateTemplate("alert.html", "{{range .Alerts}}"), "error parsing")
+	assert.ErrorContains(t, ValidateTemplate("unknown.html", "{{money .Amount}}"), "error parsing")
+}
+
+// the builtin functions and the ones of templateFuncs can be used by the templates.
+func TestValidateTemplate_Functions(t *testing.T) {
+	sources := []string{
+		`{{if eq .Locale "es"}}hola{{else}}hello{{end}}`,
+		`{{if and (ne .Locale "") (gt (len .Alerts) 0)}}{{index .Alerts 0}}{{end}}`,
+		`{{printf "%d" .Count}} {{html .Name}} {{js .Name}} {{urlquery .Name}}`,
+		`{{t "summary.subject" .Name}}`,
+	}
+	for _, source := range sources {
+		assert.NoError(t, ValidateTemplate("unknown.html", source), source)
+	}
+}
+
+func TestFallbackTemplateStore_Get(t *testing.T) {
+	primary := &MockTemplateStore{templates: map[string]string{
+		"alert.html":     "{{.Detail}}",
+		"statement.html": "custom {{.Statement}}",
+	}}
+	embedded := &EmbeddedTemplateStore{FS: fstest.MapFS{
+		"template/alert.html":     {Data: []byte("default {{.Alerts}}")},
+		"template/statement.html": {Data: []byte("default {{.Statement}}")},
+	}}
+	store := NewFallbackTemplateStore(zap.NewNop(), primary, embedded)
+
+	source, err := store.Get(context.Background(), "statement.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom {{.Statement}}", source)
+
+	// invalid template
+	source, err = store.Get(context.Background(), "alert.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "default {{.Alerts}}", source)
+
+	_, err = store.Get(context.Background(), "missing.html")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// unreachable store
+	primary.err = errors.New("timeout")
+	source, err = store.Get(context.Background(), "statement.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "default {{.Statement}}", source)
+}
+
+func TestCachedTemplateStore_Get(t *testing.T) {
+	now := time.Now()
+	mock := &MockTemplateStore{templates: map[string]string{"summary.html": "v1"}}
+	store := NewCachedTemplateStore(mock, time.Minute)
+	store.now = func() time.Time { return now }
+
+	source, _ := store.Get(context.Background(), "summary.html")
+	assert.Equal(t, "v1", source)
+	_, err := store.Get(context.Background(), "summary.txt")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	mock.templates["summary.html"] = "v2"
+	source, _ = store.Get(context.Background(), "summary.html")
+	assert.Equal(t, "v1", source)
+	store.Get(context.Background(), "summary.txt")
+	assert.Equal(t, 2, mock.calls)
+
+	now = now.Add(time.Minute)
+	source, _ = store.Get(context.Background(), "summary.html")
+	assert.Equal(t, "v2", source)
+
+	// errors are not cached
+	mock.err = errors.New("timeout")
+	_, err = store.Get(context.Background(), "alert.html")
+	assert.Error(t, err)
+	_, err = store.Get(context.Background(), "alert.html")
+	assert.Error(t, err)
+	assert.Equal(t, 5, mock.calls)
+}
+
+func TestDirTemplateStore_Get(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "summary@v2.html"), []byte("v2"), 0o600))
+	store := NewDirTemplateStore(dir)
+
+	source, err := store.Get(context.Background(), "summary@v2.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", source)
+
+	_, err = store.Get(context.Background(), "summary.html")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	_, err = store.Get(context.Background(), "../summary.html")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestSMTPService_LoadTemplateVersion(t *testing.T) {
+	store := &MockTemplateStore{templates: map[string]string{
+		"summary@v2.html": "v2",
+		"summary.html":    "v1",
+		"alert.html":      "alert",
+	}}
+	s := newTestSMTPService(t, &SMTPConfig{}, WithTemplateStore(store), WithTemplateVersions(map[string]string{
+		"summary": "v2",
+		"alert":   "v3",
+	}))
+
+	source, err := s.loadTemplate(context.Background(), "summary", ".html")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", source)
+
+	source, err = s.loadTemplate(context.Background(), "alert", ".html")
+	assert.NoError(t, err)
+	assert.Equal(t, "alert", source)
+}
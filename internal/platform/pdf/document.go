@@ -0,0 +1,158 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margins in points.
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+	Margin     = 50.0
+
+	TextSize    = 10.0
+	HeadingSize = 16.0
+
+	lineSpacing = 1.4
+	// charWidth is an approximation of the Helvetica average glyph width used to truncate cells.
+	charWidth = 0.5
+)
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+// Document is a minimal text-only PDF writer using the standard Helvetica fonts,
+// the fonts are not embedded so the output only depends on the standard library.
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func New() *Document {
+	d := &Document{}
+	d.addPage()
+	return d
+}
+
+// Heading writes a bold line using HeadingSize.
+func (d *Document) Heading(text string) {
+	d.write(fontBold, HeadingSize, Margin, text)
+}
+
+// Text writes a regular line.
+func (d *Document) Text(text string) {
+	d.write(fontRegular, TextSize, Margin, text)
+}
+
+// Row writes one line split in columns, cells longer than their column are truncated.
+func (d *Document) Row(bold bool, widths []float64, cells ...string) {
+	font := fontRegular
+	if bold {
+		font = fontBold
+	}
+
+	d.ensureSpace(TextSize * lineSpacing)
+	x := Margin
+	for i, cell := range cells {
+		if i >= len(widths) {
+			break
+		}
+		limit := int(widths[i] / (TextSize * charWidth))
+		if runes := []rune(cell); len(runes) > limit {
+			cell = string(runes[:limit])
+		}
+		d.text(font, TextSize, x, cell)
+		x += widths[i]
+	}
+	d.y -= TextSize * lineSpacing
+}
+
+// Space moves the cursor down.
+func (d *Document) Space(height float64) {
+	d.y -= height
+}
+
+func (d *Document) write(font string, size, x float64, text string) {
+	d.ensureSpace(size * lineSpacing)
+	d.text(font, size, x, text)
+	d.y -= size * lineSpacing
+}
+
+func (d *Document) text(font string, size, x float64, text string) {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y-size, escape(text))
+}
+
+func (d *Document) ensureSpace(height float64) {
+	if d.y-height < Margin {
+		d.addPage()
+	}
+}
+
+func (d *Document) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = PageHeight - Margin
+}
+
+// Bytes returns the PDF file.
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// 1 catalog, 2 pages, 3 and 4 fonts, then a page and its content for each page
+	kids := make([]string, 0, len(d.pages))
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, fontRegular, fontBold, 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// escape encodes the text as WinAnsi, the characters outside of it are replaced by '?'.
+func escape(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(byte(r))
+		case r == '€':
+			builder.WriteString(`\200`)
+		case r >= 0x20 && r < 0x7f:
+			builder.WriteByte(byte(r))
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&builder, `\%03o`, r)
+		default:
+			builder.WriteByte('?')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,32 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument_Bytes(t *testing.T) {
+	d := New()
+	d.Heading("Statement (2024)")
+	for i := 0; i < 100; i++ {
+		d.Row(false, []float64{100, 50}, fmt.Sprintf("row %d", i), "a very long cell that does not fit")
+	}
+
+	out := d.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), `(Statement \(2024\)) Tj`)
+	assert.Contains(t, string(out), "/Count 2")
+	assert.Contains(t, string(out), "(a very lon) Tj")
+	assert.Contains(t, string(out), "(row 99) Tj")
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\\b`, escape(`a\b`))
+	assert.Equal(t, `Mu\361oz \200`, escape("Muñoz €"))
+	assert.Equal(t, "?", escape("日"))
+}
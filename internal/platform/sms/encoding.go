@@ -0,0 +1,111 @@
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// GSM7 messages use the 7 bit alphabet, 160 characters fit in a single segment
+	GSM7 = "gsm7"
+	// UCS2 is the fallback for the messages with characters outside the GSM 7 bit alphabet, 70 fit in a segment
+	UCS2 = "ucs2"
+)
+
+// characters per segment, the messages with more than one segment lose some to the concatenation header
+const (
+	gsm7Single = 160
+	gsm7Multi  = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// the extension characters are escaped, they take two characters
+const gsm7Extension = "^{}\\[~]|€\f"
+
+// Encoding returns GSM7 when every character is in the GSM 7 bit alphabet, UCS2 otherwise.
+func Encoding(message string) string {
+	for _, r := range message {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extension, r) {
+			return UCS2
+		}
+	}
+	return GSM7
+}
+
+// Length counts the characters the message takes in its encoding.
+func Length(message string) int {
+	if Encoding(message) == UCS2 {
+		return len(utf16.Encode([]rune(message)))
+	}
+
+	length := 0
+	for _, r := range message {
+		length += runeLength(r)
+	}
+	return length
+}
+
+// Segments returns the amount of SMS the message is split into.
+func Segments(message string) int {
+	length := Length(message)
+
+	single, multi := gsm7Single, gsm7Multi
+	if Encoding(message) == UCS2 {
+		single, multi = ucs2Single, ucs2Multi
+	}
+
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
+
+// Fit truncates the message to the given amount of segments, the truncated messages end with an ellipsis.
+func Fit(message string, segments int) string {
+	if segments <= 0 || Segments(message) <= segments {
+		return message
+	}
+
+	ellipsis := "..."
+	limit := gsm7Single
+	if segments > 1 {
+		limit = gsm7Multi * segments
+	}
+	if Encoding(message) == UCS2 {
+		ellipsis = "…"
+		limit = ucs2Single
+		if segments > 1 {
+			limit = ucs2Multi * segments
+		}
+	}
+
+	limit -= Length(ellipsis)
+	encoding := Encoding(message)
+
+	var fitted strings.Builder
+	length := 0
+	for _, r := range message {
+		size := runeLength(r)
+		if encoding == UCS2 {
+			size = len(utf16.Encode([]rune{r}))
+		}
+		if length+size > limit {
+			break
+		}
+		length += size
+		fitted.WriteRune(r)
+	}
+
+	return strings.TrimRight(fitted.String(), " ") + ellipsis
+}
+
+func runeLength(r rune) int {
+	if strings.ContainsRune(gsm7Extension, r) {
+		return 2
+	}
+	return 1
+}
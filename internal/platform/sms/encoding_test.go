@@ -0,0 +1,43 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoding(t *testing.T) {
+	assert.Equal(t, GSM7, Encoding("Storid: your balance is $1,500.00"))
+	assert.Equal(t, GSM7, Encoding("Saldo: €1.500,00 {ñ}"))
+	assert.Equal(t, UCS2, Encoding("Saldo en cuenta: 15 transacciones en marzo, último día"))
+	assert.Equal(t, UCS2, Encoding("balance 👍"))
+}
+
+func TestSegments(t *testing.T) {
+	assert.Equal(t, 1, Segments(strings.Repeat("a", 160)))
+	assert.Equal(t, 2, Segments(strings.Repeat("a", 161)))
+	assert.Equal(t, 3, Segments(strings.Repeat("a", 307)))
+
+	// the extension characters take two
+	assert.Equal(t, 160, Length(strings.Repeat("€", 80)))
+	assert.Equal(t, 2, Segments(strings.Repeat("€", 81)))
+
+	assert.Equal(t, 1, Segments(strings.Repeat("ó", 70)))
+	assert.Equal(t, 2, Segments(strings.Repeat("ó", 71)))
+	// emojis take two UTF-16 units
+	assert.Equal(t, 2, Length("👍"))
+}
+
+func TestFit(t *testing.T) {
+	assert.Equal(t, "short", Fit("short", 1))
+
+	fitted := Fit(strings.Repeat("word ", 40), 1)
+	assert.Equal(t, 1, Segments(fitted))
+	assert.True(t, strings.HasSuffix(fitted, "..."))
+
+	fitted = Fit(strings.Repeat("ó", 100), 1)
+	assert.Equal(t, 1, Segments(fitted))
+	assert.Equal(t, 70, Length(fitted))
+	assert.True(t, strings.HasSuffix(fitted, "…"))
+}
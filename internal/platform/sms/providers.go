@@ -0,0 +1,147 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ProviderHTTP = "http"
+	ProviderFake = "fake"
+)
+
+// ProviderError is returned when the provider does not accept the message.
+type ProviderError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("sms provider responded %d: %s", e.StatusCode, e.Body)
+}
+
+// Transient the throttled messages and the provider failures can be sent again.
+func (e *ProviderError) Transient() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// HTTPProvider posts the messages as JSON to an SMS gateway authenticated with a bearer API key,
+// the gateway answers with the ID of the message.
+type HTTPProvider struct {
+	URL    string
+	APIKey string
+	From   string
+	Client *http.Client
+}
+
+// ValidateURL the gateway URL must be an absolute http or https URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid sms gateway URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid sms gateway URL %q", rawURL)
+	}
+	return nil
+}
+
+func NewHTTPProvider(url, apiKey, from string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		URL:    url,
+		APIKey: apiKey,
+		From:   from,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+type httpMessage struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Body     string `json:"body"`
+	Encoding string `json:"encoding"`
+}
+
+// Send returns the ID the gateway gave to the message.
+func (p *HTTPProvider) Send(ctx context.Context, to, body string) (string, error) {
+	payload, err := json.Marshal(&httpMessage{From: p.From, To: to, Body: body, Encoding: Encoding(body)})
+	if err != nil {
+		return "", fmt.Errorf("error encoding sms: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("error creating sms request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending sms: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return "", fmt.Errorf("error reading sms response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &ProviderError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &sent); err != nil {
+		return "", fmt.Errorf("error decoding sms response: %w", err)
+	}
+
+	return sent.ID, nil
+}
+
+type Message struct {
+	To   string
+	Body string
+}
+
+// FakeProvider keeps the messages in memory instead of sending them, it is used locally and in the tests.
+type FakeProvider struct {
+	messages []Message
+	mu       sync.Mutex
+	log      *zap.Logger
+}
+
+func NewFakeProvider(log *zap.Logger) *FakeProvider {
+	return &FakeProvider{log: log}
+}
+
+func (p *FakeProvider) Send(ctx context.Context, to, body string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, Message{To: to, Body: body})
+	if p.log != nil {
+		p.log.Info("sms sent", zap.String("to", to), zap.String("body", body), zap.Int("segments", Segments(body)))
+	}
+
+	return "fake-" + strconv.Itoa(len(p.messages)), nil
+}
+
+// Sent returns a copy of the messages sent so far.
+func (p *FakeProvider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
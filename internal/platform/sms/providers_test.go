@@ -0,0 +1,69 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestHTTPProvider_Send(t *testing.T) {
+	var received httpMessage
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_, _ = w.Write([]byte(`{"id": "SM123"}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, "api-key", "Storid", time.Second)
+
+	id, err := provider.Send(context.Background(), "+5491122334455", "Storid: balance $10.00")
+	assert.NoError(t, err)
+	assert.Equal(t, "SM123", id)
+	assert.Equal(t, "Bearer api-key", authorization)
+	assert.Equal(t, httpMessage{From: "Storid", To: "+5491122334455", Body: "Storid: balance $10.00", Encoding: GSM7}, received)
+}
+
+func TestHTTPProvider_SendErrors(t *testing.T) {
+	status := http.StatusTooManyRequests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, "api-key", "Storid", time.Second)
+
+	_, err := provider.Send(context.Background(), "+5491122334455", "hi")
+	var providerErr *ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.True(t, providerErr.Transient())
+
+	status = http.StatusBadRequest
+	_, err = provider.Send(context.Background(), "invalid", "hi")
+	assert.ErrorAs(t, err, &providerErr)
+	assert.False(t, providerErr.Transient())
+}
+
+func TestFakeProvider_Send(t *testing.T) {
+	provider := NewFakeProvider(zap.NewNop())
+
+	id, err := provider.Send(context.Background(), "+5491122334455", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-1", id)
+	assert.Equal(t, []Message{{To: "+5491122334455", Body: "hi"}}, provider.Sent())
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://sms.example.com/messages"))
+
+	for _, rawURL := range []string{"", "sms.example.com/messages", "ftp://sms.example.com", "https://", "%zz"} {
+		assert.Error(t, ValidateURL(rawURL), rawURL)
+	}
+}